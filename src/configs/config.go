@@ -5,6 +5,105 @@ var resetPassExpTime int16 = 300 //s
 var PBKDF2Iterations int = 15000
 var clientURL string = "http://localhost:3000"
 
+// password policy
+var passwordMinLength int = 8
+var passwordMaxLength int = 64
+var passwordRequireLower bool = true
+var passwordRequireUpper bool = false
+var passwordRequireDigit bool = true
+var passwordRequireSymbol bool = false
+var passwordHistorySize int = 5 // jumlah hash terakhir yang tidak boleh dipakai ulang
+
+// session
+var sessionMaxAge int32 = 604800         //s, sesi login wajib login ulang setelah 7 hari
+var knownDeviceRetention int32 = 7776000 //s, perangkat yang tidak login selama 90 hari dianggap baru lagi
+
+// login lockout (faktor kedua salah berulang kali)
+var loginMaxFailures int64 = 10
+var loginFailureWindow int16 = 900 //s
+var loginLockoutTime int16 = 900   //s
+
+// account enumeration protection, default mati karena mengubah response Reset_Password / Register / Magic_Link_Request
+// (akun tidak dikenal dan duplikat username / email tidak lagi dibalas error, lihat resetPassword.go dan register.go)
+var enumerationProtection bool = false
+var notifyUnknownResetAttempt bool = true
+var uniformResponseTime int16 = 1500 //ms
+
+// two-factor authentication
+var totpIssuer string = "SIMPEL"
+var totpSkew int = 1                 // toleransi ±1 time-step (30 detik)
+var mfaPendingExpireTime int16 = 300 //s
+var mfaMaxAttempts int64 = 5
+var recoveryCodeCount int = 10
+var recoveryCodeLowThreshold int64 = 3 // email peringatan bila sisa kode <= nilai ini
+
+// magic link login
+var magicLinkExpireTime int16 = 600 //s
+
+// oauth 2.0 authorization server
+var oauthAuthorizeRequestExpireTime int16 = 600 //s, waktu user untuk login + consent
+var oauthCodeExpireTime int16 = 60              //s
+var oauthAccessTokenExpireTime int32 = 3600     //s
+var oauthRefreshTokenExpireTime int32 = 2592000 //s, 30 hari
+var oauthConsentPath string = "/oauth/consent"  // halaman consent di frontend (clientURL + path)
+
+// oauth client_credentials (service-to-service)
+var oauthClientRateLimit int64 = 600         // request per window per client, bisa di-override kolom rate_limit
+var oauthClientRateWindow int16 = 60         //s
+var oauthSecretRotationOverlap int32 = 86400 //s, secret lama tetap berlaku setelah rotasi
+
+// openid connect
+var oidcIDTokenExpireTime int32 = 3600              //s
+var oidcLogoutHintMaxAge int32 = 86400              //s, id_token_hint yang kedaluwarsa lebih lama dari ini ditolak
+var oidcLogoutRequestExpireTime int16 = 600         //s, waktu user mengonfirmasi logout
+var oidcLogoutPath string = "/oauth/logout/confirm" // halaman konfirmasi logout di frontend (clientURL + path)
+
+// login lewat identity provider eksternal
+var idpStateExpireTime int16 = 600        //s, waktu user login di provider
+var idpTicketExpireTime int16 = 60        //s, ticket ditukar frontend menjadi sesi
+var idpCallbackPath string = "/login/idp" // halaman frontend penerima ticket / error (clientURL + path)
+
+// personal api key
+var apiKeyMaxPerUser int = 20         // key aktif per user
+var apiKeyLastUsedInterval int16 = 60 //s, last_used hanya ditulis ulang setelah interval ini
+var trustProxyHeaders bool = false    // pakai X-Forwarded-For / X-Real-IP untuk IP client (di belakang reverse proxy)
+
+// device (device.unit)
+var deviceTokenExpireTime int16 = 100       //s, waktu device mengirim token setelah Device_Login
+var deviceSessionExpireTime int32 = 86400   //s, device login ulang setelah sesi kedaluwarsa
+var deviceIngestMaxBatch int = 1000         // reading per request
+var deviceIngestMaxBodySize int64 = 1 << 20 //byte
+var deviceIngestRateLimit int64 = 6000      // reading per window per unit
+var deviceIngestRateWindow int16 = 60       //s
+var deviceIngestMaxAge int32 = 604800       //s, reading lebih lama dari ini ditolak (buffer offline 7 hari)
+var deviceIngestMaxClockSkew int16 = 300    //s, toleransi tstamp di masa depan
+var telemetryQueryDefaultLimit int = 1000   // baris per halaman query device.data
+var telemetryQueryMaxLimit int = 10000
+var pairingCodeExpireTime int32 = 604800 //s, pairing code untuk claim unit
+var deviceClaimRateLimit int64 = 10      // percobaan claim per window per user
+var deviceClaimRateWindow int16 = 900    //s
+var deviceShareRateLimit int64 = 20      // share per window per owner, membatasi probing email
+var deviceShareRateWindow int16 = 900    //s
+var mqttAuthRateLimit int64 = 20         // percobaan MQTT_Auth_User per window per username / IP client
+var mqttAuthRateWindow int16 = 300       //s
+
+// webauthn / passkey
+var webauthnChallengeExpireTime int16 = 120 //s
+
+// outbound mail queue
+var mailQueueWorkers int = 2
+var mailQueueMaxAttempts int = 6
+var mailQueueBaseBackoff int16 = 5          //s, digandakan setiap percobaan gagal
+var mailQueueMaxBackoff int16 = 600         //s
+var mailQueueStatusRetention int32 = 604800 //s, status pesan disimpan 7 hari
+var mailQueueDeadRetention int32 = 86400    //s, job dead-letter (beserta isinya) disimpan 1 hari
+
+var phoneQueueWorkers int = 2
+var phoneQueueMaxAttempts int = 4
+var phoneQueueBaseBackoff int16 = 5 //s, digandakan setiap percobaan gagal
+var phoneQueueMaxBackoff int16 = 60 //s
+var phoneQueueJobTTL int32 = 900    //s, OTP / link sudah tidak berguna setelah ini, job dibuang
+
 func GetOTPExpireTime() int16 {
 	return otpExpireTime
 
@@ -21,3 +120,279 @@ func GetPBKDF2Iterations() int {
 func GetClientURL() string {
 	return clientURL
 }
+
+func GetPasswordMinLength() int {
+	return passwordMinLength
+}
+
+func GetPasswordMaxLength() int {
+	return passwordMaxLength
+}
+
+func GetPasswordRequireLower() bool {
+	return passwordRequireLower
+}
+
+func GetPasswordRequireUpper() bool {
+	return passwordRequireUpper
+}
+
+func GetPasswordRequireDigit() bool {
+	return passwordRequireDigit
+}
+
+func GetPasswordRequireSymbol() bool {
+	return passwordRequireSymbol
+}
+
+func GetPasswordHistorySize() int {
+	return passwordHistorySize
+}
+
+func GetSessionMaxAge() int32 {
+	return sessionMaxAge
+}
+
+func GetKnownDeviceRetention() int32 {
+	return knownDeviceRetention
+}
+
+func GetLoginMaxFailures() int64 {
+	return loginMaxFailures
+}
+
+func GetLoginFailureWindow() int16 {
+	return loginFailureWindow
+}
+
+func GetLoginLockoutTime() int16 {
+	return loginLockoutTime
+}
+
+func GetEnumerationProtection() bool {
+	return enumerationProtection
+}
+
+func GetNotifyUnknownResetAttempt() bool {
+	return notifyUnknownResetAttempt
+}
+
+func GetUniformResponseTime() int16 {
+	return uniformResponseTime
+}
+
+func GetMailQueueWorkers() int {
+	return mailQueueWorkers
+}
+
+func GetMailQueueMaxAttempts() int {
+	return mailQueueMaxAttempts
+}
+
+func GetMailQueueBaseBackoff() int16 {
+	return mailQueueBaseBackoff
+}
+
+func GetMailQueueMaxBackoff() int16 {
+	return mailQueueMaxBackoff
+}
+
+func GetMailQueueStatusRetention() int32 {
+	return mailQueueStatusRetention
+}
+
+func GetMailQueueDeadRetention() int32 {
+	return mailQueueDeadRetention
+}
+
+func GetPhoneQueueWorkers() int {
+	return phoneQueueWorkers
+}
+
+func GetPhoneQueueMaxAttempts() int {
+	return phoneQueueMaxAttempts
+}
+
+func GetPhoneQueueBaseBackoff() int16 {
+	return phoneQueueBaseBackoff
+}
+
+func GetPhoneQueueMaxBackoff() int16 {
+	return phoneQueueMaxBackoff
+}
+
+func GetPhoneQueueJobTTL() int32 {
+	return phoneQueueJobTTL
+}
+
+func GetTOTPIssuer() string {
+	return totpIssuer
+}
+
+func GetTOTPSkew() int {
+	return totpSkew
+}
+
+func GetMFAPendingExpireTime() int16 {
+	return mfaPendingExpireTime
+}
+
+func GetMFAMaxAttempts() int64 {
+	return mfaMaxAttempts
+}
+
+func GetRecoveryCodeCount() int {
+	return recoveryCodeCount
+}
+
+func GetRecoveryCodeLowThreshold() int64 {
+	return recoveryCodeLowThreshold
+}
+
+func GetWebAuthnChallengeExpireTime() int16 {
+	return webauthnChallengeExpireTime
+}
+
+func GetMagicLinkExpireTime() int16 {
+	return magicLinkExpireTime
+}
+
+func GetOAuthAuthorizeRequestExpireTime() int16 {
+	return oauthAuthorizeRequestExpireTime
+}
+
+func GetOAuthCodeExpireTime() int16 {
+	return oauthCodeExpireTime
+}
+
+func GetOAuthAccessTokenExpireTime() int32 {
+	return oauthAccessTokenExpireTime
+}
+
+func GetOAuthRefreshTokenExpireTime() int32 {
+	return oauthRefreshTokenExpireTime
+}
+
+func GetOAuthConsentPath() string {
+	return oauthConsentPath
+}
+
+func GetOAuthClientRateLimit() int64 {
+	return oauthClientRateLimit
+}
+
+func GetOAuthClientRateWindow() int16 {
+	return oauthClientRateWindow
+}
+
+func GetOAuthSecretRotationOverlap() int32 {
+	return oauthSecretRotationOverlap
+}
+
+func GetIdPStateExpireTime() int16 {
+	return idpStateExpireTime
+}
+
+func GetIdPTicketExpireTime() int16 {
+	return idpTicketExpireTime
+}
+
+func GetIdPCallbackPath() string {
+	return idpCallbackPath
+}
+
+func GetOIDCIDTokenExpireTime() int32 {
+	return oidcIDTokenExpireTime
+}
+
+func GetOIDCLogoutHintMaxAge() int32 {
+	return oidcLogoutHintMaxAge
+}
+
+func GetOIDCLogoutRequestExpireTime() int16 {
+	return oidcLogoutRequestExpireTime
+}
+
+func GetOIDCLogoutPath() string {
+	return oidcLogoutPath
+}
+
+func GetAPIKeyMaxPerUser() int {
+	return apiKeyMaxPerUser
+}
+
+func GetAPIKeyLastUsedInterval() int16 {
+	return apiKeyLastUsedInterval
+}
+
+func GetTrustProxyHeaders() bool {
+	return trustProxyHeaders
+}
+
+func GetDeviceTokenExpireTime() int16 {
+	return deviceTokenExpireTime
+}
+
+func GetDeviceSessionExpireTime() int32 {
+	return deviceSessionExpireTime
+}
+
+func GetDeviceIngestMaxBatch() int {
+	return deviceIngestMaxBatch
+}
+
+func GetDeviceIngestMaxBodySize() int64 {
+	return deviceIngestMaxBodySize
+}
+
+func GetDeviceIngestRateLimit() int64 {
+	return deviceIngestRateLimit
+}
+
+func GetDeviceIngestRateWindow() int16 {
+	return deviceIngestRateWindow
+}
+
+func GetDeviceIngestMaxAge() int32 {
+	return deviceIngestMaxAge
+}
+
+func GetDeviceIngestMaxClockSkew() int16 {
+	return deviceIngestMaxClockSkew
+}
+
+func GetTelemetryQueryDefaultLimit() int {
+	return telemetryQueryDefaultLimit
+}
+
+func GetTelemetryQueryMaxLimit() int {
+	return telemetryQueryMaxLimit
+}
+
+func GetPairingCodeExpireTime() int32 {
+	return pairingCodeExpireTime
+}
+
+func GetDeviceClaimRateLimit() int64 {
+	return deviceClaimRateLimit
+}
+
+func GetDeviceClaimRateWindow() int16 {
+	return deviceClaimRateWindow
+}
+
+func GetDeviceShareRateLimit() int64 {
+	return deviceShareRateLimit
+}
+
+func GetDeviceShareRateWindow() int16 {
+	return deviceShareRateWindow
+}
+
+func GetMQTTAuthRateLimit() int64 {
+	return mqttAuthRateLimit
+}
+
+func GetMQTTAuthRateWindow() int16 {
+	return mqttAuthRateWindow
+}
@@ -55,3 +55,10 @@ func XorEncode(input string, key byte) (string, error) {
 	}
 	return encoded.String(), nil
 }
+
+// HashSHA256 mengembalikan SHA-256 dari teks dalam format hex,
+// dipakai untuk menyimpan token acak tanpa menyimpan nilai aslinya
+func HashSHA256(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
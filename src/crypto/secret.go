@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// Kunci AES-256 untuk mengenkripsi secret yang harus bisa dibaca ulang (misal secret TOTP),
+// di-set sekali dari environment di main
+var (
+	secretKey   []byte
+	secretKeyMu sync.RWMutex
+)
+
+// InitSecretKey menerima kunci 32 byte dalam format hex (64 karakter)
+func InitSecretKey(hexKey string) error {
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return errors.New("secret key must be hex encoded")
+	}
+	if len(key) != 32 {
+		return errors.New("secret key must be 32 bytes (64 hex characters)")
+	}
+
+	secretKeyMu.Lock()
+	defer secretKeyMu.Unlock()
+	secretKey = key
+	return nil
+}
+
+func secretAEAD() (cipher.AEAD, error) {
+	secretKeyMu.RLock()
+	key := secretKey
+	secretKeyMu.RUnlock()
+
+	if key == nil {
+		return nil, errors.New("secret key is not initialized")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptSecret mengenkripsi plaintext dengan AES-256-GCM, hasil base64(nonce || ciphertext)
+func EncryptSecret(plaintext string) (string, error) {
+	aead, err := secretAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret membuka hasil EncryptSecret
+func DecryptSecret(encoded string) (string, error) {
+	aead, err := secretAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted secret is too short")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const testSecretKey = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func setSecretKey(t *testing.T, hexKey string) {
+	t.Helper()
+	if err := InitSecretKey(hexKey); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		secretKeyMu.Lock()
+		secretKey = nil
+		secretKeyMu.Unlock()
+	})
+}
+
+func TestEncryptSecretRoundTrip(t *testing.T) {
+	setSecretKey(t, testSecretKey)
+
+	for _, plaintext := range []string{rfc6238Secret, "", "ünïcødé secret"} {
+		encrypted, err := EncryptSecret(plaintext)
+		if err != nil {
+			t.Fatalf("EncryptSecret(%q): %v", plaintext, err)
+		}
+		if plaintext != "" && strings.Contains(encrypted, plaintext) {
+			t.Errorf("EncryptSecret(%q) leaks plaintext: %s", plaintext, encrypted)
+		}
+
+		decrypted, err := DecryptSecret(encrypted)
+		if err != nil {
+			t.Fatalf("DecryptSecret(%q): %v", plaintext, err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("round trip = %q, want %q", decrypted, plaintext)
+		}
+	}
+
+	// nonce acak: plaintext yang sama menghasilkan ciphertext berbeda
+	first, _ := EncryptSecret(rfc6238Secret)
+	second, _ := EncryptSecret(rfc6238Secret)
+	if first == second {
+		t.Error("EncryptSecret produced identical ciphertext twice")
+	}
+}
+
+func TestDecryptSecretTampered(t *testing.T) {
+	setSecretKey(t, testSecretKey)
+
+	encrypted, err := EncryptSecret(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(encrypted)
+
+	flip := func(i int) string {
+		tampered := append([]byte(nil), sealed...)
+		tampered[i] ^= 0x01
+		return base64.StdEncoding.EncodeToString(tampered)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"nonce bit flipped", flip(0)},
+		{"ciphertext bit flipped", flip(len(sealed) / 2)},
+		{"tag bit flipped", flip(len(sealed) - 1)},
+		{"truncated tag", base64.StdEncoding.EncodeToString(sealed[:len(sealed)-1])},
+		{"shorter than nonce", base64.StdEncoding.EncodeToString(sealed[:4])},
+		{"not base64", "not base64!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if plaintext, err := DecryptSecret(tt.encoded); err == nil {
+				t.Errorf("DecryptSecret succeeded with %q, want error", plaintext)
+			}
+		})
+	}
+}
+
+func TestDecryptSecretWrongKey(t *testing.T) {
+	setSecretKey(t, testSecretKey)
+	encrypted, err := EncryptSecret(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setSecretKey(t, strings.Repeat("ff", 32))
+	if _, err := DecryptSecret(encrypted); err == nil {
+		t.Error("DecryptSecret with other key expected error")
+	}
+}
+
+func TestInitSecretKey(t *testing.T) {
+	t.Cleanup(func() {
+		secretKeyMu.Lock()
+		secretKey = nil
+		secretKeyMu.Unlock()
+	})
+
+	tests := []struct {
+		name    string
+		hexKey  string
+		wantErr bool
+	}{
+		{"32 byte key", testSecretKey, false},
+		{"not hex", strings.Repeat("zz", 32), true},
+		{"16 byte key", testSecretKey[:32], true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := InitSecretKey(tt.hexKey); (err != nil) != tt.wantErr {
+				t.Errorf("InitSecretKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretKeyNotInitialized(t *testing.T) {
+	secretKeyMu.Lock()
+	secretKey = nil
+	secretKeyMu.Unlock()
+
+	if _, err := EncryptSecret("x"); err == nil {
+		t.Error("EncryptSecret without key expected error")
+	}
+	if _, err := DecryptSecret("x"); err == nil {
+		t.Error("DecryptSecret without key expected error")
+	}
+}
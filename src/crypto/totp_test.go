@@ -0,0 +1,109 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret RFC 6238 Appendix B untuk SHA-1: ASCII "12345678901234567890" dalam base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPRFC6238(t *testing.T) {
+	// Vektor RFC 6238 berupa kode 8 digit; kode 6 digit adalah 6 digit terakhirnya
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},          // 94287082
+		{1111111109, "081804"},  // 07081804
+		{1111111111, "050471"},  // 14050471
+		{1234567890, "005924"},  // 89005924
+		{2000000000, "279037"},  // 69279037
+		{20000000000, "353130"}, // 65353130
+	}
+
+	for _, tt := range tests {
+		step := TOTPStep(time.Unix(tt.unix, 0))
+		got, err := GenerateTOTP(rfc6238Secret, step)
+		if err != nil {
+			t.Fatalf("GenerateTOTP(t=%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateTOTP(t=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateTOTPSecretFormat(t *testing.T) {
+	step := TOTPStep(time.Unix(59, 0))
+	want, _ := GenerateTOTP(rfc6238Secret, step)
+
+	// secret yang diketik user bisa huruf kecil, berspasi, atau ber-padding
+	for _, secret := range []string{
+		strings.ToLower(rfc6238Secret),
+		"GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ",
+		rfc6238Secret + "====",
+	} {
+		got, err := GenerateTOTP(secret, step)
+		if err != nil || got != want {
+			t.Errorf("GenerateTOTP(%q) = %s (%v), want %s", secret, got, err, want)
+		}
+	}
+
+	if _, err := GenerateTOTP("not base32!", step); err == nil {
+		t.Error("GenerateTOTP with invalid secret expected error")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+	codeAt := func(step int64) string {
+		code, err := GenerateTOTP(rfc6238Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", codeAt(current), 1, current, true},
+		{"previous step within skew", codeAt(current - 1), 1, current - 1, true},
+		{"next step within skew", codeAt(current + 1), 1, current + 1, true},
+		{"previous step without skew", codeAt(current - 1), 0, 0, false},
+		{"outside skew", codeAt(current - 2), 1, 0, false},
+		{"surrounding whitespace", " " + codeAt(current) + "\n", 0, current, true},
+		{"wrong length", "12345", 1, 0, false},
+		{"empty", "", 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q) = (%d, %v), want (%d, %v)", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := base32NoPad.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not unpadded base32: %v", secret, err)
+	}
+	if len(key) != totpSecretSize {
+		t.Errorf("secret decodes to %d bytes, want %d", len(key), totpSecretSize)
+	}
+}
@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE sysuser.api_key (
+	id bigserial NOT NULL,
+	user_id int8 NOT NULL,
+	name character varying(64) NOT NULL,
+	prefix character varying(16) NOT NULL,  -- bagian publik key, dipakai untuk lookup
+	key_hash character varying(64) NOT NULL, -- sha256 hex dari key lengkap
+	scopes character varying(255) NOT NULL,  -- dipisah spasi
+	expire_tstamp int8 NULL,                 -- NULL = tidak kedaluwarsa
+	create_tstamp int8 NOT NULL,
+	last_used_tstamp int8 NULL,
+	last_used_ip character varying(45) NULL,
+	revoked_tstamp int8 NULL,
+	CONSTRAINT api_key_pkey PRIMARY KEY (id),
+	CONSTRAINT api_key_prefix_unique UNIQUE (prefix),
+	CONSTRAINT api_key_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX api_key_user_idx ON sysuser.api_key (user_id);
+*/
+
+// !NOTE : personal API key
+/*	Format key: ak_<prefix 16 hex>_<secret base64url>, hanya ditampilkan sekali saat dibuat.
+	Key lama dengan prefix 8 hex tetap diterima ParseAPIKey.
+	Dipakai lewat header "Authorization: Bearer ak_..." di endpoint yang dibungkus middlewares.AllowAPIKey.
+	Key tidak bisa dipakai untuk mengelola key lain, password, 2FA maupun sesi (butuh sesi login).
+	Semua key user dicabut saat Change_Password.
+*/
+
+const (
+	apiKeyTag         = "ak_"
+	apiKeyPrefixBytes = 8
+	apiKeySecretBytes = 32
+
+	apiKeyCreateAttempts = 3 // percobaan ulang bila prefix acak bentrok (api_key_prefix_unique)
+)
+
+// apiKeyScopes adalah scope yang bisa dipilih user, endpoint menentukan scope yang dibutuhkan di main.go
+var apiKeyScopes = map[string]bool{
+	"account:read":   true, // data akun sendiri (identities, passkey, status recovery code)
+	"admin":          true, // endpoint admin, tetap dicek role lewat RequireRole
+	"device:read":    true, // daftar unit dan query data device yang bisa diakses user
+	"device:control": true, // publish command MQTT ke unit dengan role operator / owner
+}
+
+type apiKey struct {
+	ID             int64          `db:"id"`
+	UserID         int64          `db:"user_id"`
+	Name           string         `db:"name"`
+	Prefix         string         `db:"prefix"`
+	KeyHash        string         `db:"key_hash"`
+	Scopes         string         `db:"scopes"`
+	ExpireTstamp   sql.NullInt64  `db:"expire_tstamp"`
+	CreateTstamp   int64          `db:"create_tstamp"`
+	LastUsedTstamp sql.NullInt64  `db:"last_used_tstamp"`
+	LastUsedIP     sql.NullString `db:"last_used_ip"`
+	RevokedTstamp  sql.NullInt64  `db:"revoked_tstamp"`
+}
+
+// ParseAPIKey mengambil prefix publik dari key, ok false bila format bukan API key
+func ParseAPIKey(key string) (prefix string, ok bool) {
+	if !strings.HasPrefix(key, apiKeyTag) {
+		return "", false
+	}
+	// prefix hanya berisi hex, jadi '_' pertama adalah pemisah meskipun secret base64url juga bisa berisi '_'
+	rest := key[len(apiKeyTag):]
+	prefixLength := strings.IndexByte(rest, '_')
+	if prefixLength != apiKeyPrefixBytes*2 {
+		return "", false
+	}
+	if len(rest) == prefixLength+1 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(rest[:prefixLength]); err != nil {
+		return "", false
+	}
+	return rest[:prefixLength], true
+}
+
+func generateAPIKey() (key, prefix string, err error) {
+	// prefix hex supaya tidak mengandung '_' / '-' dari base64url
+	prefixBytes := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	secret, err := utils.SecureTokenGenerator(apiKeySecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(prefixBytes)
+	return apiKeyTag + prefix + "_" + secret, prefix, nil
+}
+
+// parseAPIKeyScopes memvalidasi daftar scope dari request, mengembalikan string dipisah spasi
+func parseAPIKeyScopes(list []any) (string, bool) {
+	var scopes []string
+	for _, item := range list {
+		scope, _ := item.(string)
+		if !apiKeyScopes[scope] {
+			return "", false
+		}
+		if !hasScope(strings.Join(scopes, " "), scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return strings.Join(scopes, " "), len(scopes) > 0
+}
+
+func (k *apiKey) status(now int64) string {
+	switch {
+	case k.RevokedTstamp.Valid:
+		return "revoked"
+	case k.ExpireTstamp.Valid && k.ExpireTstamp.Int64 <= now:
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+func (k *apiKey) payload(now int64) map[string]any {
+	item := map[string]any{
+		"id":            k.ID,
+		"name":          k.Name,
+		"prefix":        apiKeyTag + k.Prefix,
+		"scopes":        strings.Fields(k.Scopes),
+		"status":        k.status(now),
+		"create_tstamp": k.CreateTstamp,
+	}
+	if k.ExpireTstamp.Valid {
+		item["expire_tstamp"] = k.ExpireTstamp.Int64
+	}
+	if k.LastUsedTstamp.Valid {
+		item["last_used_tstamp"] = k.LastUsedTstamp.Int64
+		item["last_used_ip"] = k.LastUsedIP.String
+	}
+	if k.RevokedTstamp.Valid {
+		item["revoked_tstamp"] = k.RevokedTstamp.Int64
+	}
+	return item
+}
+
+// apiKeySession mengambil userID dari context dan memastikan request memakai sesi login, bukan API key
+func apiKeySession(r *http.Request) (int64, bool) {
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	sessionID, _ := r.Context().Value(HTTPContextKey("sessionID")).(string)
+	return userID, userID != 0 && sessionID != ""
+}
+
+/*
+{
+	"name" : "deploy script",
+	"scopes" : ["account:read"],
+	"expires_in_days" : 90
+}
+*/
+
+func API_Key_Create(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - API_Key_Create - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, ok := apiKeySession(r)
+	if !ok {
+		logger.Error(referenceID, "ERROR - API_Key_Create - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	name, _ := param["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		logger.Error(referenceID, "ERROR - API_Key_Create - Invalid name")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	list, _ := param["scopes"].([]any)
+	scopes, ok := parseAPIKeyScopes(list)
+	if !ok {
+		logger.Error(referenceID, "ERROR - API_Key_Create - Invalid scopes: ", list)
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid scopes"
+		utils.Response(w, result)
+		return
+	}
+
+	now := time.Now().Unix()
+	var expireTstamp sql.NullInt64
+	if days, ok := param["expires_in_days"].(float64); ok {
+		if days < 1 || days > 3650 {
+			logger.Error(referenceID, "ERROR - API_Key_Create - Invalid expires_in_days: ", days)
+			result.ErrorCode = "400003"
+			result.ErrorMessage = "Invalid expires_in_days"
+			utils.Response(w, result)
+			return
+		}
+		expireTstamp = sql.NullInt64{Int64: now + int64(days)*86400, Valid: true}
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_Create - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var activeKeys int
+	queryCount := `SELECT COUNT(*) FROM sysuser.api_key WHERE user_id = $1 AND revoked_tstamp IS NULL AND (expire_tstamp IS NULL OR expire_tstamp > $2)`
+	if err := conn.Get(&activeKeys, queryCount, userID, now); err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_Create - Failed to count keys: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if activeKeys >= configs.GetAPIKeyMaxPerUser() {
+		logger.Error(referenceID, "ERROR - API_Key_Create - Key limit reached: ", activeKeys)
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "API key limit reached"
+		utils.Response(w, result)
+		return
+	}
+
+	// key hanya ditampilkan sekali, yang disimpan hash-nya
+	var key, prefix string
+	var keyID int64
+	queryInsert := `
+		INSERT INTO sysuser.api_key (user_id, name, prefix, key_hash, scopes, expire_tstamp, create_tstamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	for attempt := 1; ; attempt++ {
+		key, prefix, err = generateAPIKey()
+		if err != nil {
+			logger.Error(referenceID, "ERROR - API_Key_Create - Failed to generate key: ", err)
+			result.ErrorCode = "500003"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		err = conn.Get(&keyID, queryInsert, userID, name, prefix, crypto.HashSHA256(key), scopes, expireTstamp, now)
+		var pqErr *pq.Error
+		if err != nil && errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "api_key_prefix_unique" && attempt < apiKeyCreateAttempts {
+			logger.Warning(referenceID, "WARNING - API_Key_Create - Prefix collision, retrying: ", prefix)
+			continue
+		}
+		break
+	}
+	if err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_Create - Failed to store key: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - API_Key_Create - Key created: ", keyID, " (", apiKeyTag+prefix, ") for user: ", userID)
+	created := apiKey{ID: keyID, Name: name, Prefix: prefix, Scopes: scopes, ExpireTstamp: expireTstamp, CreateTstamp: now}
+	result.Payload = created.payload(now)
+	result.Payload["key"] = key
+	utils.Response(w, result)
+}
+
+func API_Key_List(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - API_Key_List - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, ok := apiKeySession(r)
+	if !ok {
+		logger.Error(referenceID, "ERROR - API_Key_List - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_List - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var keys []apiKey
+	if err := conn.Select(&keys, `SELECT * FROM sysuser.api_key WHERE user_id = $1 ORDER BY create_tstamp DESC`, userID); err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_List - Failed to load keys: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	now := time.Now().Unix()
+	list := make([]map[string]any, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, key.payload(now))
+	}
+
+	result.Payload["keys"] = list
+	utils.Response(w, result)
+}
+
+/*
+{
+	"id" : 12,
+	"name" : "ci pipeline"
+}
+*/
+
+func API_Key_Rename(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - API_Key_Rename - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, ok := apiKeySession(r)
+	if !ok {
+		logger.Error(referenceID, "ERROR - API_Key_Rename - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	id, ok := param["id"].(float64)
+	if !ok || id <= 0 {
+		logger.Error(referenceID, "ERROR - API_Key_Rename - Missing id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	name, _ := param["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		logger.Error(referenceID, "ERROR - API_Key_Rename - Invalid name")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_Rename - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	res, err := conn.Exec(`UPDATE sysuser.api_key SET name = $1 WHERE id = $2 AND user_id = $3`, name, int64(id), userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_Rename - Failed to rename key: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		logger.Error(referenceID, "ERROR - API_Key_Rename - Key not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
+
+/*
+{
+	"id" : 12
+}
+*/
+
+func API_Key_Revoke(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - API_Key_Revoke - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, ok := apiKeySession(r)
+	if !ok {
+		logger.Error(referenceID, "ERROR - API_Key_Revoke - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	id, ok := param["id"].(float64)
+	if !ok || id <= 0 {
+		logger.Error(referenceID, "ERROR - API_Key_Revoke - Missing id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_Revoke - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// baris tetap disimpan agar riwayat pemakaian key masih bisa dilihat
+	queryRevoke := `UPDATE sysuser.api_key SET revoked_tstamp = $1 WHERE id = $2 AND user_id = $3 AND revoked_tstamp IS NULL`
+	res, err := conn.Exec(queryRevoke, time.Now().Unix(), int64(id), userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - API_Key_Revoke - Failed to revoke key: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if revoked, _ := res.RowsAffected(); revoked == 0 {
+		logger.Error(referenceID, "ERROR - API_Key_Revoke - Key not found or already revoked")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - API_Key_Revoke - Key revoked: ", int64(id), " by user: ", userID)
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAPIKey(t *testing.T) {
+	const prefix = "0123456789abcdef"
+	secret := strings.Repeat("x", 43)
+
+	tests := []struct {
+		name       string
+		key        string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{"valid", apiKeyTag + prefix + "_" + secret, prefix, true},
+		// secret base64url boleh berisi '_' dan '-'
+		{"secret with separators", apiKeyTag + prefix + "_ab_cd-ef", prefix, true},
+		{"uppercase hex prefix", apiKeyTag + strings.ToUpper(prefix) + "_" + secret, strings.ToUpper(prefix), true},
+		{"8 character prefix", apiKeyTag + prefix[:8] + "_" + secret, "", false},
+		{"prefix too long", apiKeyTag + prefix + "00_" + secret, "", false},
+		{"non hex prefix", apiKeyTag + "0123456789abcdeg_" + secret, "", false},
+		{"empty secret", apiKeyTag + prefix + "_", "", false},
+		{"missing separator", apiKeyTag + prefix + secret, "", false},
+		{"missing tag", prefix + "_" + secret, "", false},
+		{"other tag", "sk_" + prefix + "_" + secret, "", false},
+		{"session token", secret, "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, ok := ParseAPIKey(tt.key)
+			if ok != tt.wantOK || prefix != tt.wantPrefix {
+				t.Errorf("ParseAPIKey(%q) = (%q, %v), want (%q, %v)", tt.key, prefix, ok, tt.wantPrefix, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateAPIKeyParses(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		key, prefix, err := generateAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, ok := ParseAPIKey(key)
+		if !ok || parsed != prefix {
+			t.Fatalf("ParseAPIKey(%q) = (%q, %v), want (%q, true)", key, parsed, ok, prefix)
+		}
+	}
+}
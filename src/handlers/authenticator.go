@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/ldap"
+	"auth_service/logger"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/*
+ALTER TABLE sysuser."user" ADD COLUMN auth_source character varying(16) NOT NULL DEFAULT 'local';  -- local | ldap
+*/
+
+const (
+	authSourceLocal = "local"
+	authSourceLDAP  = "ldap"
+)
+
+// errAuthFailed dipakai untuk semua kegagalan login agar client tidak bisa membedakan penyebabnya
+var errAuthFailed = errors.New("authentication failed")
+
+// authenticator memverifikasi kredensial login dan mengembalikan kredensial lokal (salt + saltedpassword)
+// yang dipakai untuk challenge Verify_Token
+type authenticator interface {
+	authenticate(referenceID string, conn *sqlx.DB, userData, password string, existing *UserCred) (*UserCred, error)
+}
+
+// localAuthenticator: password dibuktikan client lewat HMAC saltedpassword di Verify_Token
+type localAuthenticator struct{}
+
+func (localAuthenticator) authenticate(referenceID string, conn *sqlx.DB, userData, password string, existing *UserCred) (*UserCred, error) {
+	if existing == nil {
+		return nil, errAuthFailed
+	}
+	return existing, nil
+}
+
+// ldapAuthenticator: bind ke directory, lalu saltedpassword shadow user disinkronkan dengan password directory
+// sehingga alur Verify_Token di client tidak berubah
+type ldapAuthenticator struct{}
+
+func (ldapAuthenticator) authenticate(referenceID string, conn *sqlx.DB, userData, password string, existing *UserCred) (*UserCred, error) {
+	if !ldap.Enabled() {
+		logger.Error(referenceID, "ERROR - Login - LDAP account but LDAP is not configured")
+		return nil, errAuthFailed
+	}
+
+	directoryUser, err := ldap.Authenticate(userData, password)
+	if errors.Is(err, ldap.ErrInvalidCredentials) || errors.Is(err, ldap.ErrNotFound) || errors.Is(err, ldap.ErrNotAllowed) {
+		logger.Error(referenceID, "ERROR - Login - LDAP authentication rejected: ", err)
+		return nil, errAuthFailed
+	} else if err != nil {
+		return nil, err
+	}
+
+	fullName := strings.TrimSpace(directoryUser.FullName)
+	if fullName == "" {
+		fullName = directoryUser.Username
+	}
+
+	if existing == nil {
+		return provisionLDAPUser(referenceID, conn, directoryUser, fullName, password)
+	}
+
+	salt := existing.Salt
+	saltedPassword, err := crypto.GeneratePBKDF2(password, salt, 32, configs.GetPBKDF2Iterations())
+	if err != nil {
+		return nil, err
+	}
+
+	// profil dan role selalu mengikuti directory, email hanya diganti bila directory mengisinya
+	queryUpdate := `
+		UPDATE sysuser."user"
+		SET saltedpassword = $1, full_name = $2, email = COALESCE(NULLIF($3, ''), email), role = $4
+		WHERE id = $5`
+	if _, err := conn.Exec(queryUpdate, saltedPassword, fullName, directoryUser.Email, directoryUser.Role, existing.ID); err != nil {
+		return nil, err
+	}
+
+	existing.SaltedPassword = saltedPassword
+	return existing, nil
+}
+
+// provisionLDAPUser membuat shadow user saat login LDAP pertama kali
+func provisionLDAPUser(referenceID string, conn *sqlx.DB, directoryUser *ldap.User, fullName, password string) (*UserCred, error) {
+	if directoryUser.Email == "" {
+		logger.Error(referenceID, "ERROR - Login - Directory entry has no email: ", directoryUser.DN)
+		return nil, errAuthFailed
+	}
+
+	// akun lokal dengan username / email yang sama tidak diambil alih
+	if field := existingUserField(conn, directoryUser.Username, directoryUser.Email, nil); field != "" {
+		logger.Error(referenceID, "ERROR - Login - LDAP user conflicts with existing account on ", field)
+		return nil, errAuthFailed
+	}
+
+	userID, err := createUser(referenceID, "Login", conn, newUser{
+		Username:   directoryUser.Username,
+		FullName:   fullName,
+		Email:      directoryUser.Email,
+		Password:   password,
+		Role:       directoryUser.Role,
+		AuthSource: authSourceLDAP,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(referenceID, "INFO - Login - Shadow user created from LDAP: ", userID, " (", directoryUser.DN, ")")
+
+	var userCred UserCred
+	queryGetUser := `SELECT id, salt, saltedpassword, auth_source FROM sysuser."user" WHERE id = $1`
+	if err := conn.Get(&userCred, queryGetUser, userID); err != nil {
+		return nil, err
+	}
+	return &userCred, nil
+}
+
+// authenticatorFor memilih backend berdasarkan auth_source akun, akun yang belum ada dicoba ke LDAP
+func authenticatorFor(existing *UserCred) authenticator {
+	if existing == nil {
+		if ldap.Enabled() {
+			return ldapAuthenticator{}
+		}
+		return localAuthenticator{}
+	}
+	if existing.AuthSource == authSourceLDAP {
+		return ldapAuthenticator{}
+	}
+	return localAuthenticator{}
+}
+
+// authenticateLogin mencari akun lalu mendelegasikan verifikasi ke backend yang sesuai
+func authenticateLogin(referenceID string, conn *sqlx.DB, userData, password string) (*UserCred, error) {
+	var existing *UserCred
+	var userCred UserCred
+	queryGetUser := `SELECT id, salt, saltedpassword, auth_source FROM sysuser.user WHERE username = $1 OR email = $1`
+	err := conn.Get(&userCred, queryGetUser, userData)
+	if err == nil {
+		existing = &userCred
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return authenticatorFor(existing).authenticate(referenceID, conn, userData, password, existing)
+}
+
+// passwordManagedLocally mengecek apakah password akun boleh diubah di service ini
+func passwordManagedLocally(authSource string) bool {
+	return authSource == "" || authSource == authSourceLocal
+}
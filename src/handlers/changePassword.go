@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/mail"
+	"auth_service/policy"
+	"auth_service/utils"
+	"crypto/subtle"
+	"net/http"
+	"time"
+)
+
+/*
+{
+	"current_password" : "master123",
+	"new_password" : "master456"
+}
+
+Header wajib (dari payload Verify_Token):
+	X-Session-Id   : <session_id>
+	X-Session-Hash : <session_hash>
+*/
+
+type userPasswordData struct {
+	Username       string `db:"username"`
+	Email          string `db:"email"`
+	FullName       string `db:"full_name"`
+	Salt           string `db:"salt"`
+	SaltedPassword string `db:"saltedpassword"`
+	AuthSource     string `db:"auth_source"`
+}
+
+// passwordMatches membandingkan password dengan salt + saltedpassword tersimpan secara constant-time
+func passwordMatches(password, salt, saltedPassword string) bool {
+	hashed, err := crypto.GeneratePBKDF2(password, salt, 32, configs.GetPBKDF2Iterations())
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashed), []byte(saltedPassword)) == 1
+}
+
+func Change_Password(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Change_Password - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	sessionID, _ := r.Context().Value(HTTPContextKey("sessionID")).(string)
+	if userID == 0 || sessionID == "" {
+		logger.Error(referenceID, "ERROR - Change_Password - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	currentPassword, ok := param["current_password"].(string)
+	if !ok || currentPassword == "" {
+		logger.Error(referenceID, "ERROR - Change_Password - Missing current_password")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	newPassword, ok := param["new_password"].(string)
+	if !ok || newPassword == "" {
+		logger.Error(referenceID, "ERROR - Change_Password - Missing new_password")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Change_Password - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var userData userPasswordData
+	queryGetUser := `SELECT username, email, full_name, salt, saltedpassword, auth_source FROM sysuser."user" WHERE id = $1`
+	if err := conn.Get(&userData, queryGetUser, userID); err != nil {
+		logger.Error(referenceID, "ERROR - Change_Password - User not found: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	// password akun directory dikelola di LDAP / Active Directory
+	if !passwordManagedLocally(userData.AuthSource) {
+		logger.Error(referenceID, "ERROR - Change_Password - Password is managed by ", userData.AuthSource)
+		result.ErrorCode = "403001"
+		result.ErrorMessage = "Forbidden"
+		utils.Response(w, result)
+		return
+	}
+
+	// tebakan current_password dihitung ke counter lockout login yang sama
+	remaining, err := loginLockRemaining(userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Change_Password - Failed to read lockout status: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if remaining > 0 {
+		logger.Error(referenceID, "ERROR - Change_Password - Account is locked")
+		result.ErrorCode = "429001"
+		result.ErrorMessage = "Too many attempts"
+		result.Payload["remaining_time"] = int(remaining.Seconds())
+		utils.Response(w, result)
+		return
+	}
+
+	if !passwordMatches(currentPassword, userData.Salt, userData.SaltedPassword) {
+		logger.Error(referenceID, "ERROR - Change_Password - Current password mismatch")
+		if locked, err := recordLoginFailure(referenceID, conn, userID); err != nil {
+			logger.Warning(referenceID, "WARNING - Change_Password - Failed to record failed attempt: ", err)
+		} else if locked {
+			result.ErrorCode = "429001"
+			result.ErrorMessage = "Too many attempts"
+			result.Payload["remaining_time"] = configs.GetLoginLockoutTime()
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+	clearLoginFailures(userID)
+
+	violations := policy.Validate(referenceID, newPassword, policy.Subject{Username: userData.Username, Email: userData.Email, FullName: userData.FullName})
+	reused, err := policy.IsReused(conn, userID, newPassword)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Change_Password - Password history check failed: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if reused {
+		violations = append(violations, policy.Reused)
+	}
+
+	if len(violations) > 0 {
+		logger.Error(referenceID, "ERROR - Change_Password - Password policy violations: ", violations)
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid request"
+		result.Payload["violations"] = violations
+		utils.Response(w, result)
+		return
+	}
+
+	salt, _ := utils.RandomStringGenerator(16)
+	hashedPassword, _ := crypto.GeneratePBKDF2(newPassword, salt, 32, configs.GetPBKDF2Iterations())
+	_, err = conn.Exec(`UPDATE sysuser."user" SET saltedpassword = $1, salt = $2, password_set = true WHERE id = $3`, hashedPassword, salt, userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Change_Password - Failed to update password: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := policy.RecordHistory(conn, userID, salt, hashedPassword); err != nil {
+		logger.Warning(referenceID, "WARNING - Change_Password - Failed to record password history: ", err)
+	}
+
+	if _, err := invalidatePasswordResetTokens(conn, userID); err != nil {
+		logger.Warning(referenceID, "WARNING - Change_Password - Failed to invalidate reset tokens: ", err)
+	}
+
+	// sysuser.session hanya satu baris per user (login baru menggantikan sesi lama), jadi sesi lain yang
+	// masih hidup adalah token OAuth dari login sebelumnya. Token tersebut dicabut, token dari sesi
+	// yang dipakai untuk request ini tetap berlaku.
+	queryRevokeTokens := `
+		UPDATE sysuser.oauth_token SET revoked = true
+		WHERE user_id = $1 AND session_id IS DISTINCT FROM $2 AND NOT revoked`
+	res, err := conn.Exec(queryRevokeTokens, userID, sessionID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Change_Password - Failed to revoke other sessions: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	revokedTokens, _ := res.RowsAffected()
+	logger.Info(referenceID, "INFO - Change_Password - Revoked tokens from other sessions: ", revokedTokens)
+
+	// API key pribadi bisa dibuat oleh siapa pun yang sempat memegang password lama, jadi ikut dicabut
+	res, err = conn.Exec(`UPDATE sysuser.api_key SET revoked_tstamp = $1 WHERE user_id = $2 AND revoked_tstamp IS NULL`, time.Now().Unix(), userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Change_Password - Failed to revoke API keys: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	revokedKeys, _ := res.RowsAffected()
+	logger.Info(referenceID, "INFO - Change_Password - Revoked API keys: ", revokedKeys)
+
+	// password sudah terganti, kegagalan kirim notifikasi tidak membatalkan request
+	_, err = mail.EnqueueTemplate(userData.Email, userLocale(conn, userID), mail.TemplatePasswordChanged, map[string]any{
+		"FullName": userData.FullName,
+		"Username": userData.Username,
+		"Time":     time.Now().Format(time.RFC1123),
+	})
+	if err != nil {
+		logger.Warning(referenceID, "WARNING - Change_Password - Failed to queue notification email: ", err)
+	}
+
+	result.Payload["status"] = "success"
+	result.Payload["revoked_tokens"] = revokedTokens
+	result.Payload["revoked_api_keys"] = revokedKeys
+	utils.Response(w, result)
+}
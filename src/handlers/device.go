@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"net/http"
+	"time"
+)
+
+/*
+\d device.unit;
+                                       Table "device.unit"
+     Column      |          Type          | Collation | Nullable |                  Default
+-----------------+------------------------+-----------+----------+-------------------------------------------
+ id              | bigint                 |           | not null | nextval('public.device_id_sq'::regclass)
+ name            | character varying(255) |           | not null |
+ status          | integer                |           | not null |
+ salt            | character varying(64)  |           | not null |
+ salted_password | character varying(128) |           | not null |
+ data            | jsonb                  |           | not null |
+ create_tstamp   | bigint                 |           |          | (EXTRACT(epoch FROM now()))::bigint
+Indexes:
+    "unit_pkey" PRIMARY KEY, btree (id)
+
+CREATE TABLE device.token (
+	unit_id int8 NOT NULL,
+	token character varying(64) NOT NULL,
+	tstamp int8 NOT NULL,
+	CONSTRAINT device_token_pkey PRIMARY KEY (unit_id),
+	CONSTRAINT device_token_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+);
+CREATE INDEX device_token_token_idx ON device.token (token);
+
+CREATE TABLE device.session (
+	session_id character varying(16) NOT NULL,
+	unit_id int8 NOT NULL,
+	session_hash character varying(128) NOT NULL,
+	tstamp int8 NOT NULL,
+	st int4 NOT NULL,
+	CONSTRAINT device_session_pkey PRIMARY KEY (session_id),
+	CONSTRAINT device_session_unit_key UNIQUE (unit_id),
+	CONSTRAINT device_session_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+);
+*/
+
+// status device.unit
+const (
+	deviceStatusDisabled = 0
+	deviceStatusActive   = 1
+)
+
+// !NOTE : alur login device, sama dengan Login / Verify_Token user
+/*	1. /device/login        -> kirim unit_id dan half_nonce, dapat full_nonce dan salt
+	2. device menghitung    -> salted_password = pbkdf2-sha256(secret, salt), token = hmac-sha256(salted_password, full_nonce)
+	3. /device/verify-token -> kirim token, dapat session_id dan session_hash
+	4. request berikutnya   -> header X-Session-Id dan X-Session-Hash (middlewares.DeviceAuthMiddleware)
+
+	Secret device dibuat lewat /admin/devices/provision atau /admin/devices/rotate-secret.
+*/
+
+type deviceCred struct {
+	ID             int64  `db:"id"`
+	Salt           string `db:"salt"`
+	SaltedPassword string `db:"salted_password"`
+}
+
+/*
+{
+	"unit_id" : 1,
+	"half_nonce" : "a1b2c3d4"
+}
+*/
+
+func Device_Login(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, ok := r.Context().Value(ctxKey).(string)
+	if !ok {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Login - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	unitID, ok := param["unit_id"].(float64)
+	if !ok || unitID <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Login - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	halfNonce, ok := param["half_nonce"].(string)
+	if !ok || len(halfNonce) < 8 {
+		logger.Error(referenceID, "ERROR - Device_Login - Missing half_nonce")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Login - DB connection failed: ", err)
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var cred deviceCred
+	queryGetUnit := `SELECT id, salt, salted_password FROM device.unit WHERE id = $1 AND status = $2`
+	if err := conn.Get(&cred, queryGetUnit, int64(unitID), deviceStatusActive); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Login - Unit not found or disabled: ", err)
+		result.ErrorCode = "401000"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	halfNonce2, errHNC2 := GenerateNonce()
+
+	fullNonce := halfNonce + halfNonce2
+	token, errTkn := crypto.GenerateHMAC(cred.SaltedPassword, fullNonce)
+
+	if errHNC2 != nil || errTkn != nil {
+		logger.Error(referenceID, "ERROR - Device_Login - GenerateNonce or GenerateHMAC failed", errTkn, errHNC2)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	queryUpsertToken := `
+		INSERT INTO device.token (unit_id, token, tstamp)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (unit_id)
+		DO UPDATE SET token = EXCLUDED.token, tstamp = EXCLUDED.tstamp`
+	if _, err := conn.Exec(queryUpsertToken, cred.ID, token, time.Now().Unix()); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Login - Token upsert failed", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["full_nonce"] = fullNonce
+	result.Payload["salt"] = cred.Salt
+	utils.Response(w, result)
+}
+
+/*
+{
+	"token" : "<hmac-sha256(salted_password, full_nonce)>"
+}
+*/
+
+func Device_Verify_Token(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, ok := r.Context().Value(ctxKey).(string)
+	if !ok {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Verify_Token - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+	tokenClient, ok := param["token"].(string)
+	if !ok || tokenClient == "" {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - Missing token")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - DB connection failed: ", err)
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// token sekali pakai, langsung dihapus saat dibaca
+	var unitID, tokenCreatedStamp int64
+	queryTakeToken := `DELETE FROM device.token WHERE token = $1 RETURNING unit_id, tstamp`
+	if err := conn.QueryRow(queryTakeToken, tokenClient).Scan(&unitID, &tokenCreatedStamp); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - Invalid token: ", err)
+		result.ErrorCode = "401000"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if time.Now().Unix()-tokenCreatedStamp > int64(configs.GetDeviceTokenExpireTime()) {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - Token expired")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	var unit struct {
+		Name   string `db:"name"`
+		Status int    `db:"status"`
+	}
+	if err := conn.Get(&unit, `SELECT name, status FROM device.unit WHERE id = $1`, unitID); err != nil || unit.Status != deviceStatusActive {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - Unit not found or disabled: ", unitID, " ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	sessionID, err := utils.RandomStringGenerator(16)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - Session ID generation failed")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	sessionHash, err := crypto.GenerateHMAC(tokenClient, sessionID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - HMAC computation failed")
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// satu sesi per device, login ulang menggantikan sesi lama
+	now := time.Now().Unix()
+	queryUpsertSession := `
+		INSERT INTO device.session (session_id, unit_id, session_hash, tstamp, st)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (unit_id) DO UPDATE SET
+			session_id = EXCLUDED.session_id,
+			session_hash = EXCLUDED.session_hash,
+			tstamp = EXCLUDED.tstamp,
+			st = EXCLUDED.st`
+	if _, err := conn.Exec(queryUpsertSession, sessionID, unitID, sessionHash, now, 1); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Verify_Token - Session upsert failed: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Verify_Token - Device session created for unit: ", unitID)
+	result.Payload["session_id"] = sessionID
+	result.Payload["session_hash"] = sessionHash
+	result.Payload["unit_id"] = unitID
+	result.Payload["name"] = unit.Name
+	result.Payload["expire_tstamp"] = now + int64(configs.GetDeviceSessionExpireTime())
+	utils.Response(w, result)
+}
+
+// Device_Logout mengakhiri sesi device yang sedang dipakai (butuh DeviceAuthMiddleware)
+func Device_Logout(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, ok := r.Context().Value(ctxKey).(string)
+	if !ok {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Logout - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	unitID, _ := r.Context().Value(HTTPContextKey("unitID")).(int64)
+	sessionID, _ := r.Context().Value(HTTPContextKey("deviceSessionID")).(string)
+	if unitID == 0 || sessionID == "" {
+		logger.Error(referenceID, "ERROR - Device_Logout - Missing device session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Logout - DB connection failed: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if _, err := conn.Exec(`DELETE FROM device.session WHERE session_id = $1 AND unit_id = $2`, sessionID, unitID); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Logout - Failed to delete session: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
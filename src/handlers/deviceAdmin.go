@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Pengelolaan device.unit, hanya untuk role admin (lihat main.go)
+
+const deviceSecretBytes = 24
+
+// generateDeviceCredentials membuat secret baru beserta salt dan salted_password untuk device.unit.
+// Secret hanya dikembalikan ke admin, yang disimpan hanya hasil PBKDF2-nya.
+func generateDeviceCredentials() (secret, salt, saltedPassword string, err error) {
+	secret, err = utils.SecureTokenGenerator(deviceSecretBytes)
+	if err != nil {
+		return "", "", "", err
+	}
+	salt, err = utils.RandomStringGenerator(16)
+	if err != nil {
+		return "", "", "", err
+	}
+	saltedPassword, err = crypto.GeneratePBKDF2(secret, salt, 32, configs.GetPBKDF2Iterations())
+	if err != nil {
+		return "", "", "", err
+	}
+	return secret, salt, saltedPassword, nil
+}
+
+// endDeviceSessions menghapus challenge dan sesi device, dipakai saat secret dirotasi atau unit dinonaktifkan
+func endDeviceSessions(tx *sql.Tx, unitID int64) error {
+	if _, err := tx.Exec(`DELETE FROM device.token WHERE unit_id = $1`, unitID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM device.session WHERE unit_id = $1`, unitID)
+	return err
+}
+
+/*
+{
+	"name" : "meter-lantai-2",
+	"data" : {"location" : "panel B"}
+}
+*/
+
+func Device_Provision(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Provision - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	name, _ := param["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 255 {
+		logger.Error(referenceID, "ERROR - Device_Provision - Invalid name")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	data := map[string]any{}
+	if value, exists := param["data"]; exists && value != nil {
+		object, ok := value.(map[string]any)
+		if !ok {
+			logger.Error(referenceID, "ERROR - Device_Provision - data must be an object")
+			result.ErrorCode = "400002"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		data = object
+	}
+	dataJSON, _ := json.Marshal(data)
+
+	secret, salt, saltedPassword, err := generateDeviceCredentials()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Provision - Failed to generate credentials: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Provision - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var unitID, createTstamp int64
+	queryInsert := `
+		INSERT INTO device.unit (name, status, salt, salted_password, data, create_tstamp)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, create_tstamp`
+	err = conn.QueryRow(queryInsert, name, deviceStatusActive, salt, saltedPassword, string(dataJSON), time.Now().Unix()).Scan(&unitID, &createTstamp)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Provision - Failed to store unit: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Provision - Unit provisioned: ", unitID, " (", name, ")")
+	result.Payload["unit_id"] = unitID
+	result.Payload["name"] = name
+	result.Payload["secret"] = secret
+	result.Payload["status"] = deviceStatusActive
+	result.Payload["data"] = data
+	result.Payload["create_tstamp"] = createTstamp
+	utils.Response(w, result)
+}
+
+func Device_List(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_List - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_List - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var units []struct {
+		ID              int64           `db:"id"`
+		Name            string          `db:"name"`
+		Status          int             `db:"status"`
+		Data            json.RawMessage `db:"data"`
+		CreateTstamp    sql.NullInt64   `db:"create_tstamp"`
+		LastLoginTstamp sql.NullInt64   `db:"last_login_tstamp"`
+		OwnerUserID     sql.NullInt64   `db:"owner_user_id"`
+	}
+	queryUnits := `
+		SELECT u.id, u.name, u.status, u.data, u.create_tstamp, s.tstamp AS last_login_tstamp, g.user_id AS owner_user_id
+		FROM device.unit u
+		LEFT JOIN device.session s ON s.unit_id = u.id
+		LEFT JOIN device.unit_grant g ON g.unit_id = u.id AND g.role = 'owner'
+		ORDER BY u.id`
+	if err := conn.Select(&units, queryUnits); err != nil {
+		logger.Error(referenceID, "ERROR - Device_List - Failed to load units: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	list := make([]map[string]any, 0, len(units))
+	for _, unit := range units {
+		item := map[string]any{
+			"unit_id": unit.ID,
+			"name":    unit.Name,
+			"status":  unit.Status,
+			"data":    unit.Data,
+		}
+		if unit.CreateTstamp.Valid {
+			item["create_tstamp"] = unit.CreateTstamp.Int64
+		}
+		if unit.LastLoginTstamp.Valid {
+			item["last_login_tstamp"] = unit.LastLoginTstamp.Int64
+		}
+		if unit.OwnerUserID.Valid {
+			item["owner_user_id"] = unit.OwnerUserID.Int64
+		}
+		list = append(list, item)
+	}
+
+	result.Payload["units"] = list
+	utils.Response(w, result)
+}
+
+/*
+{
+	"unit_id" : 1
+}
+*/
+
+func Device_Rotate_Secret(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Rotate_Secret - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	unitID, ok := param["unit_id"].(float64)
+	if !ok || unitID <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	secret, salt, saltedPassword, err := generateDeviceCredentials()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Failed to generate credentials: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Failed to begin transaction: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE device.unit SET salt = $1, salted_password = $2 WHERE id = $3`, salt, saltedPassword, int64(unitID))
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Failed to update unit: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Unit not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	// sesi lama dibuat dengan secret lama, device harus login ulang
+	if err := endDeviceSessions(tx, int64(unitID)); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Failed to end sessions: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Rotate_Secret - Failed to commit: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Rotate_Secret - Secret rotated for unit: ", int64(unitID))
+	result.Payload["unit_id"] = int64(unitID)
+	result.Payload["secret"] = secret
+	utils.Response(w, result)
+}
+
+/*
+{
+	"unit_id" : 1,
+	"status" : 0
+}
+*/
+
+func Device_Set_Status(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Set_Status - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	unitID, ok := param["unit_id"].(float64)
+	if !ok || unitID <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Set_Status - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	status, ok := param["status"].(float64)
+	if !ok || (int(status) != deviceStatusActive && int(status) != deviceStatusDisabled) {
+		logger.Error(referenceID, "ERROR - Device_Set_Status - Invalid status: ", param["status"])
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid status"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Set_Status - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Set_Status - Failed to begin transaction: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE device.unit SET status = $1 WHERE id = $2`, int(status), int64(unitID))
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Set_Status - Failed to update unit: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		logger.Error(referenceID, "ERROR - Device_Set_Status - Unit not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	if int(status) == deviceStatusDisabled {
+		if err := endDeviceSessions(tx, int64(unitID)); err != nil {
+			logger.Error(referenceID, "ERROR - Device_Set_Status - Failed to end sessions: ", err)
+			result.ErrorCode = "500004"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Set_Status - Failed to commit: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Set_Status - Unit ", int64(unitID), " status set to ", int(status))
+	result.Payload["unit_id"] = int64(unitID)
+	result.Payload["status"] = int(status)
+	utils.Response(w, result)
+}
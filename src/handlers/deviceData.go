@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/rds"
+	"auth_service/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/*
+\d device.data;
+                                    Table "device.data"
+    Column    |       Type       | Collation | Nullable |                 Default
+--------------+------------------+-----------+----------+-----------------------------------------
+ id           | bigint           |           | not null | nextval('device.data_id_seq'::regclass)
+ unit_id      | bigint           |           | not null |
+ tstamp       | bigint           |           | not null | (EXTRACT(epoch FROM now()))::bigint
+ voltage      | double precision |           | not null |
+ current      | double precision |           | not null |
+ power        | double precision |           | not null |
+ energy       | double precision |           | not null |
+ frequency    | double precision |           | not null |
+ power_factor | double precision |           | not null |
+Indexes:
+    "data_new_pkey1" PRIMARY KEY, btree (id)
+Foreign-key constraints:
+    "fk_unit" FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+
+CREATE INDEX data_unit_tstamp_idx ON device.data (unit_id, tstamp);
+*/
+
+// !NOTE : format ingestion /device/data (butuh sesi device)
+/*	JSON (Content-Type: application/json), satu reading atau batch:
+	{"voltage": 221.4, "current": 1.25, "power": 262.1, "energy": 12.53, "frequency": 50.01, "power_factor": 0.95, "tstamp": 1739370518}
+	{"readings": [{...}, {...}]}
+
+	Line protocol (Content-Type: text/plain), satu reading per baris, tstamp opsional di akhir:
+	v=221.4,i=1.25,p=262.1,e=12.53,f=50.01,pf=0.95 1739370518
+	Nama panjang (voltage, current, ...) juga diterima. Baris kosong dan baris diawali '#' diabaikan.
+
+	tstamp kosong berarti waktu server. Satu reading tidak valid membuat seluruh batch ditolak.
+*/
+
+type telemetryReading struct {
+	Tstamp      *int64   `json:"tstamp"`
+	Voltage     *float64 `json:"voltage"`
+	Current     *float64 `json:"current"`
+	Power       *float64 `json:"power"`
+	Energy      *float64 `json:"energy"`
+	Frequency   *float64 `json:"frequency"`
+	PowerFactor *float64 `json:"power_factor"`
+}
+
+// telemetryRange adalah batas nilai yang masuk akal per kolom
+type telemetryRange struct {
+	Min, Max float64
+}
+
+var telemetryRanges = map[string]telemetryRange{
+	"voltage":      {0, 1000},
+	"current":      {0, 1000},
+	"power":        {-1e6, 1e6}, // negatif untuk ekspor (panel surya)
+	"energy":       {0, 1e12},
+	"frequency":    {40, 70},
+	"power_factor": {-1, 1},
+}
+
+// telemetryAliases dipakai line protocol
+var telemetryAliases = map[string]string{
+	"v":  "voltage",
+	"i":  "current",
+	"p":  "power",
+	"e":  "energy",
+	"f":  "frequency",
+	"pf": "power_factor",
+}
+
+const telemetryMaxErrors = 20
+
+// field mengembalikan pointer kolom berdasarkan nama
+func (t *telemetryReading) field(name string) **float64 {
+	switch name {
+	case "voltage":
+		return &t.Voltage
+	case "current":
+		return &t.Current
+	case "power":
+		return &t.Power
+	case "energy":
+		return &t.Energy
+	case "frequency":
+		return &t.Frequency
+	case "power_factor":
+		return &t.PowerFactor
+	}
+	return nil
+}
+
+// validate memeriksa kelengkapan dan rentang nilai, tstamp kosong diisi now
+func (t *telemetryReading) validate(now int64) error {
+	for _, name := range []string{"voltage", "current", "power", "energy", "frequency", "power_factor"} {
+		value := *t.field(name)
+		if value == nil {
+			return fmt.Errorf("missing %s", name)
+		}
+		limit := telemetryRanges[name]
+		if math.IsNaN(*value) || *value < limit.Min || *value > limit.Max {
+			return fmt.Errorf("%s out of range [%g, %g]", name, limit.Min, limit.Max)
+		}
+	}
+
+	if t.Tstamp == nil {
+		t.Tstamp = &now
+	}
+	if *t.Tstamp > now+int64(configs.GetDeviceIngestMaxClockSkew()) {
+		return errors.New("tstamp is in the future")
+	}
+	if *t.Tstamp < now-int64(configs.GetDeviceIngestMaxAge()) {
+		return errors.New("tstamp is too old")
+	}
+	return nil
+}
+
+func parseTelemetryJSON(body []byte) ([]telemetryReading, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, errors.New("invalid JSON")
+	}
+
+	if raw, ok := envelope["readings"]; ok {
+		var readings []telemetryReading
+		if err := json.Unmarshal(raw, &readings); err != nil {
+			return nil, errors.New("readings must be an array of objects with numeric fields")
+		}
+		return readings, nil
+	}
+
+	var reading telemetryReading
+	if err := json.Unmarshal(body, &reading); err != nil {
+		return nil, errors.New("reading fields must be numeric")
+	}
+	return []telemetryReading{reading}, nil
+}
+
+// parseTelemetryLine mem-parse satu baris line protocol
+func parseTelemetryLine(line string) (telemetryReading, error) {
+	var reading telemetryReading
+
+	fieldSet, tstampValue, hasTstamp := strings.Cut(line, " ")
+	if hasTstamp {
+		tstamp, err := strconv.ParseInt(strings.TrimSpace(tstampValue), 10, 64)
+		if err != nil {
+			return reading, errors.New("invalid tstamp")
+		}
+		reading.Tstamp = &tstamp
+	}
+
+	for _, pair := range strings.Split(fieldSet, ",") {
+		key, rawValue, found := strings.Cut(pair, "=")
+		if !found {
+			return reading, fmt.Errorf("invalid field %q", pair)
+		}
+		name := key
+		if alias, ok := telemetryAliases[key]; ok {
+			name = alias
+		}
+		target := reading.field(name)
+		if target == nil {
+			return reading, fmt.Errorf("unknown field %q", key)
+		}
+		if *target != nil {
+			return reading, fmt.Errorf("duplicate field %q", key)
+		}
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			return reading, fmt.Errorf("invalid value for %s", name)
+		}
+		*target = &value
+	}
+	return reading, nil
+}
+
+// telemetryError adalah kesalahan per reading, index dimulai dari 0 (JSON) atau nomor baris (line protocol)
+type telemetryError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// insertTelemetry menyimpan reading dengan multi-row insert dalam satu transaksi
+func insertTelemetry(conn *sqlx.DB, unitID int64, readings []telemetryReading) error {
+	// 8 parameter per baris, batas parameter postgres 65535
+	const chunkSize = 1000
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(readings); start += chunkSize {
+		end := min(start+chunkSize, len(readings))
+
+		var query strings.Builder
+		query.WriteString(`INSERT INTO device.data (unit_id, tstamp, voltage, current, power, energy, frequency, power_factor) VALUES `)
+		args := make([]any, 0, (end-start)*8)
+		for i, reading := range readings[start:end] {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			n := len(args)
+			fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+			args = append(args, unitID, *reading.Tstamp, *reading.Voltage, *reading.Current, *reading.Power, *reading.Energy, *reading.Frequency, *reading.PowerFactor)
+		}
+
+		if _, err := tx.Exec(query.String(), args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func Device_Data_Ingest(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, ok := r.Context().Value(ctxKey).(string)
+	if !ok {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Data_Ingest - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	unitID, _ := r.Context().Value(HTTPContextKey("unitID")).(int64)
+	if unitID == 0 {
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - Missing device session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		result.ErrorCode = "405001"
+		result.ErrorMessage = "Method not allowed"
+		utils.Response(w, result)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, configs.GetDeviceIngestMaxBodySize()))
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - Failed to read body: ", err)
+		result.ErrorCode = "413001"
+		result.ErrorMessage = "Payload too large"
+		utils.Response(w, result)
+		return
+	}
+
+	now := time.Now().Unix()
+	var readings []telemetryReading
+	var readingErrors []telemetryError
+	// positions[i] adalah index reading di request: urutan array (JSON) atau nomor baris (line protocol)
+	var positions []int
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json", "":
+		readings, err = parseTelemetryJSON(body)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Device_Data_Ingest - Invalid JSON body: ", err)
+			result.ErrorCode = "400001"
+			result.ErrorMessage = "Invalid request"
+			result.Payload["error"] = err.Error()
+			utils.Response(w, result)
+			return
+		}
+		for i := range readings {
+			positions = append(positions, i)
+		}
+	case "text/plain":
+		for lineNumber, line := range strings.Split(string(body), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			reading, err := parseTelemetryLine(line)
+			if err != nil {
+				readingErrors = append(readingErrors, telemetryError{Index: lineNumber + 1, Error: err.Error()})
+				continue
+			}
+			readings = append(readings, reading)
+			positions = append(positions, lineNumber+1)
+		}
+	default:
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - Unsupported content type: ", mediaType)
+		result.ErrorCode = "415001"
+		result.ErrorMessage = "Unsupported media type"
+		utils.Response(w, result)
+		return
+	}
+
+	if len(readings)+len(readingErrors) == 0 {
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - Empty batch")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+	if len(readings)+len(readingErrors) > configs.GetDeviceIngestMaxBatch() {
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - Batch too large: ", len(readings)+len(readingErrors))
+		result.ErrorCode = "413002"
+		result.ErrorMessage = "Batch too large"
+		result.Payload["max_batch"] = configs.GetDeviceIngestMaxBatch()
+		utils.Response(w, result)
+		return
+	}
+
+	for i := range readings {
+		if err := readings[i].validate(now); err != nil {
+			readingErrors = append(readingErrors, telemetryError{Index: positions[i], Error: err.Error()})
+		}
+	}
+
+	if len(readingErrors) > 0 {
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - Invalid readings: ", len(readingErrors))
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid readings"
+		result.Payload["invalid_count"] = len(readingErrors)
+		result.Payload["errors"] = readingErrors[:min(len(readingErrors), telemetryMaxErrors)]
+		utils.Response(w, result)
+		return
+	}
+
+	window := time.Duration(configs.GetDeviceIngestRateWindow()) * time.Second
+	if ttl, err := utils.UnitRateLimiter(rds.GetRedisClient(), referenceID, unitID, int64(len(readings)), configs.GetDeviceIngestRateLimit(), window); err != nil {
+		if ttl > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(ttl.Seconds())+1))
+			result.ErrorCode = "429001"
+			result.ErrorMessage = "Too many readings"
+			result.Payload["remaining_time"] = int(ttl.Seconds())
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - DB connection failed: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := insertTelemetry(conn, unitID, readings); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Data_Ingest - Failed to store readings: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Data_Ingest - Stored ", len(readings), " readings for unit: ", unitID)
+	result.Payload["accepted"] = len(readings)
+	utils.Response(w, result)
+}
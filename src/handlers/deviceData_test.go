@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseTelemetryLine(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		wantErr    string
+		wantTstamp int64 // 0 berarti tstamp kosong
+	}{
+		{"aliases with tstamp", "v=221.4,i=1.25,p=262.1,e=12.53,f=50.01,pf=0.95 1739370518", "", 1739370518},
+		{"long names without tstamp", "voltage=221.4,current=1.25,power=262.1,energy=12.53,frequency=50.01,power_factor=0.95", "", 0},
+		{"trailing space before tstamp", "v=221.4 1739370518 ", "", 1739370518},
+		{"partial fields", "v=221.4,i=1.25", "", 0},
+		{"duplicate alias", "v=221.4,v=222", "duplicate field", 0},
+		{"duplicate alias and long name", "v=221.4,voltage=222", "duplicate field", 0},
+		{"unknown field", "v=221.4,temp=30", "unknown field", 0},
+		{"tstamp is not a column", "tstamp=1739370518", "unknown field", 0},
+		{"missing value separator", "v221.4", "invalid field", 0},
+		{"empty field", "v=221.4,,i=1", "invalid field", 0},
+		{"non numeric value", "v=abc", "invalid value", 0},
+		{"invalid tstamp", "v=221.4 yesterday", "invalid tstamp", 0},
+		{"fractional tstamp", "v=221.4 1739370518.5", "invalid tstamp", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading, err := parseTelemetryLine(tt.line)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseTelemetryLine(%q) error = %v, want %q", tt.line, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTelemetryLine(%q): %v", tt.line, err)
+			}
+			if tt.wantTstamp == 0 && reading.Tstamp != nil {
+				t.Errorf("tstamp = %d, want empty", *reading.Tstamp)
+			}
+			if tt.wantTstamp != 0 && (reading.Tstamp == nil || *reading.Tstamp != tt.wantTstamp) {
+				t.Errorf("tstamp = %v, want %d", reading.Tstamp, tt.wantTstamp)
+			}
+			if reading.Voltage == nil || *reading.Voltage != 221.4 {
+				t.Errorf("voltage = %v, want 221.4", reading.Voltage)
+			}
+		})
+	}
+}
+
+func TestParseTelemetryLineSpecialValues(t *testing.T) {
+	// NaN dan ±Inf lolos parser (strconv.ParseFloat), validate yang menolaknya
+	for _, raw := range []string{"NaN", "Inf", "+Inf", "-Inf", "infinity"} {
+		reading, err := parseTelemetryLine("v=" + raw)
+		if err != nil {
+			t.Fatalf("parseTelemetryLine(v=%s): %v", raw, err)
+		}
+		value := *reading.Voltage
+		if !math.IsNaN(value) && !math.IsInf(value, 0) {
+			t.Errorf("parseTelemetryLine(v=%s) voltage = %g, want NaN or Inf", raw, value)
+		}
+	}
+}
+
+func TestTelemetryReadingValidate(t *testing.T) {
+	const now = int64(1739370518)
+	float := func(v float64) *float64 { return &v }
+	tstamp := func(v int64) *int64 { return &v }
+	valid := func() telemetryReading {
+		return telemetryReading{
+			Voltage:     float(221.4),
+			Current:     float(1.25),
+			Power:       float(262.1),
+			Energy:      float(12.53),
+			Frequency:   float(50.01),
+			PowerFactor: float(0.95),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		modify     func(*telemetryReading)
+		wantErr    string
+		wantTstamp int64
+	}{
+		{"valid uses server time", func(r *telemetryReading) {}, "", now},
+		{"valid with tstamp", func(r *telemetryReading) { r.Tstamp = tstamp(now - 60) }, "", now - 60},
+		{"negative power for export", func(r *telemetryReading) { r.Power = float(-500) }, "", now},
+		{"range bounds inclusive", func(r *telemetryReading) { r.PowerFactor = float(-1); r.Frequency = float(70) }, "", now},
+		{"missing voltage", func(r *telemetryReading) { r.Voltage = nil }, "missing voltage", 0},
+		{"missing power_factor", func(r *telemetryReading) { r.PowerFactor = nil }, "missing power_factor", 0},
+		{"negative current", func(r *telemetryReading) { r.Current = float(-0.1) }, "current out of range", 0},
+		{"frequency too high", func(r *telemetryReading) { r.Frequency = float(70.1) }, "frequency out of range", 0},
+		{"NaN", func(r *telemetryReading) { r.Voltage = float(math.NaN()) }, "voltage out of range", 0},
+		{"+Inf", func(r *telemetryReading) { r.Energy = float(math.Inf(1)) }, "energy out of range", 0},
+		{"-Inf", func(r *telemetryReading) { r.Power = float(math.Inf(-1)) }, "power out of range", 0},
+		{"tstamp within clock skew", func(r *telemetryReading) { r.Tstamp = tstamp(now + 300) }, "", now + 300},
+		{"tstamp in the future", func(r *telemetryReading) { r.Tstamp = tstamp(now + 301) }, "future", 0},
+		{"tstamp at max age", func(r *telemetryReading) { r.Tstamp = tstamp(now - 604800) }, "", now - 604800},
+		{"tstamp too old", func(r *telemetryReading) { r.Tstamp = tstamp(now - 604801) }, "too old", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading := valid()
+			tt.modify(&reading)
+			err := reading.validate(now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validate(): %v", err)
+			}
+			if *reading.Tstamp != tt.wantTstamp {
+				t.Errorf("tstamp = %d, want %d", *reading.Tstamp, tt.wantTstamp)
+			}
+		})
+	}
+}
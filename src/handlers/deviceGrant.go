@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE device.unit_grant (
+	unit_id int8 NOT NULL,
+	user_id int8 NOT NULL,
+	role character varying(16) NOT NULL DEFAULT 'viewer', -- owner | operator | viewer
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT unit_grant_pkey PRIMARY KEY (unit_id, user_id),
+	CONSTRAINT unit_grant_role_check CHECK (role IN ('owner', 'operator', 'viewer')),
+	CONSTRAINT unit_grant_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE,
+	CONSTRAINT unit_grant_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX unit_grant_user_idx ON device.unit_grant (user_id);
+CREATE UNIQUE INDEX unit_grant_owner_idx ON device.unit_grant (unit_id) WHERE role = 'owner';
+*/
+
+/*	Role per unit:
+	- owner    : satu per unit (hasil claim pairing code), boleh membagikan dan mencabut akses
+	- operator : membaca data dan mengendalikan unit
+	- viewer   : hanya membaca data
+*/
+
+const (
+	unitRoleOwner    = "owner"
+	unitRoleOperator = "operator"
+	unitRoleViewer   = "viewer"
+)
+
+func validUnitRole(role string) bool {
+	return role == unitRoleOwner || role == unitRoleOperator || role == unitRoleViewer
+}
+
+// unitRole mengembalikan role user pada unit, string kosong bila user tidak punya grant
+func unitRole(conn *sqlx.DB, userID, unitID int64) (string, error) {
+	var role string
+	err := conn.Get(&role, `SELECT role FROM device.unit_grant WHERE unit_id = $1 AND user_id = $2`, unitID, userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// userCanReadUnit mengecek apakah user boleh membaca data unit (punya grant dengan role apa pun, atau role admin)
+func userCanReadUnit(conn *sqlx.DB, userID, unitID int64) (bool, error) {
+	var allowed bool
+	queryAccess := `
+		SELECT EXISTS (SELECT 1 FROM device.unit_grant WHERE unit_id = $1 AND user_id = $2)
+			OR EXISTS (SELECT 1 FROM sysuser."user" WHERE id = $2 AND role = 'admin')`
+	err := conn.Get(&allowed, queryAccess, unitID, userID)
+	return allowed, err
+}
+
+// isUniqueViolation mengecek error unique constraint postgres, di sini hanya mungkin dari unit_grant_owner_idx
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+/*
+{
+	"unit_id" : 1,
+	"email" : "operator@example.com",
+	"role" : "operator"
+}
+*/
+
+func Device_Grant(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Grant - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	unitID, ok := param["unit_id"].(float64)
+	if !ok || unitID <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Grant - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	email, _ := param["email"].(string)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		logger.Error(referenceID, "ERROR - Device_Grant - Missing email")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	// default viewer, admin boleh menetapkan owner (misal unit tanpa pairing code)
+	role, _ := param["role"].(string)
+	if role == "" {
+		role = unitRoleViewer
+	}
+	if !validUnitRole(role) {
+		logger.Error(referenceID, "ERROR - Device_Grant - Invalid role: ", role)
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid role"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Grant - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var userID int64
+	if err := conn.Get(&userID, `SELECT id FROM sysuser."user" WHERE email = $1`, email); err == sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - Device_Grant - User not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "User not found"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Grant - User lookup failed: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// grant yang sudah ada diperbarui role-nya
+	queryGrant := `
+		INSERT INTO device.unit_grant (unit_id, user_id, role, create_tstamp)
+		SELECT id, $2, $3, $4 FROM device.unit WHERE id = $1
+		ON CONFLICT (unit_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	res, err := conn.Exec(queryGrant, int64(unitID), userID, role, time.Now().Unix())
+	if isUniqueViolation(err) {
+		logger.Error(referenceID, "ERROR - Device_Grant - Unit ", int64(unitID), " already has an owner")
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "Unit already has an owner"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Grant - Failed to store grant: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if granted, _ := res.RowsAffected(); granted == 0 {
+		logger.Error(referenceID, "ERROR - Device_Grant - Unit not found")
+		result.ErrorCode = "404002"
+		result.ErrorMessage = "Unit not found"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Grant - Unit ", int64(unitID), " granted to user: ", userID, " as ", role)
+	result.Payload["unit_id"] = int64(unitID)
+	result.Payload["user_id"] = userID
+	result.Payload["role"] = role
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
+
+/*
+{
+	"unit_id" : 1,
+	"user_id" : 42
+}
+*/
+
+func Device_Grant_Revoke(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Grant_Revoke - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	unitID, ok := param["unit_id"].(float64)
+	if !ok || unitID <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Grant_Revoke - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	userID, ok := param["user_id"].(float64)
+	if !ok || userID <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Grant_Revoke - Missing user_id")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Grant_Revoke - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	res, err := conn.Exec(`DELETE FROM device.unit_grant WHERE unit_id = $1 AND user_id = $2`, int64(unitID), int64(userID))
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Grant_Revoke - Failed to delete grant: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if deleted, _ := res.RowsAffected(); deleted == 0 {
+		logger.Error(referenceID, "ERROR - Device_Grant_Revoke - Grant not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Grant_Revoke - Grant revoked: unit ", int64(unitID), ", user ", int64(userID))
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/rds"
+	"auth_service/utils"
+	cryptorand "crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+CREATE TABLE device.pairing_code (
+	unit_id int8 NOT NULL,
+	code_hash character varying(64) NOT NULL, -- SHA-256 hex dari kode yang sudah dinormalisasi
+	expire_tstamp int8 NOT NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT pairing_code_pkey PRIMARY KEY (unit_id),
+	CONSTRAINT pairing_code_hash_key UNIQUE (code_hash),
+	CONSTRAINT pairing_code_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+);
+*/
+
+/*	Alur claim unit:
+	1. admin membuat pairing code (Device_Pairing_Code), biasanya dicetak di label unit
+	2. user memasukkan kode (Device_Claim) -> menjadi owner unit, kode langsung dihapus
+	Kode baru menggantikan kode lama, unit yang sudah punya owner tidak bisa di-claim.
+*/
+
+// charset sama dengan kode pemulihan, 12 karakter dalam tiga grup: xxxx-xxxx-xxxx
+const pairingCodeLength = 12
+
+func newPairingCode() (string, error) {
+	max := big.NewInt(int64(len(recoveryCodeCharset)))
+	buf := make([]byte, pairingCodeLength)
+	for i := range buf {
+		n, err := cryptorand.Int(cryptorand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = recoveryCodeCharset[n.Int64()]
+	}
+	return string(buf[:4]) + "-" + string(buf[4:8]) + "-" + string(buf[8:]), nil
+}
+
+/*
+{
+	"unit_id" : 1
+}
+*/
+
+func Device_Pairing_Code(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Pairing_Code - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	unitID, ok := param["unit_id"].(float64)
+	if !ok || unitID <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Pairing_Code - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	code, err := newPairingCode()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Pairing_Code - Failed to generate code: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Pairing_Code - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var owned bool
+	if err := conn.Get(&owned, `SELECT EXISTS (SELECT 1 FROM device.unit_grant WHERE unit_id = $1 AND role = $2)`, int64(unitID), unitRoleOwner); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Pairing_Code - Failed to check owner: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if owned {
+		logger.Error(referenceID, "ERROR - Device_Pairing_Code - Unit ", int64(unitID), " already has an owner")
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "Unit already has an owner"
+		utils.Response(w, result)
+		return
+	}
+
+	now := time.Now().Unix()
+	expireTstamp := now + int64(configs.GetPairingCodeExpireTime())
+	queryCode := `
+		INSERT INTO device.pairing_code (unit_id, code_hash, expire_tstamp, create_tstamp)
+		SELECT id, $2, $3, $4 FROM device.unit WHERE id = $1
+		ON CONFLICT (unit_id) DO UPDATE SET code_hash = EXCLUDED.code_hash, expire_tstamp = EXCLUDED.expire_tstamp, create_tstamp = EXCLUDED.create_tstamp`
+	res, err := conn.Exec(queryCode, int64(unitID), crypto.HashSHA256(normalizeRecoveryCode(code)), expireTstamp, now)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Pairing_Code - Failed to store code: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if stored, _ := res.RowsAffected(); stored == 0 {
+		logger.Error(referenceID, "ERROR - Device_Pairing_Code - Unit not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Pairing_Code - Pairing code created for unit: ", int64(unitID))
+	result.Payload["unit_id"] = int64(unitID)
+	result.Payload["pairing_code"] = code
+	result.Payload["expire_tstamp"] = expireTstamp
+	utils.Response(w, result)
+}
+
+/*
+{
+	"pairing_code" : "abcd-efgh-jkmn"
+}
+*/
+
+func Device_Claim(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Claim - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Device_Claim - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	code, _ := param["pairing_code"].(string)
+	code = normalizeRecoveryCode(code)
+	if len(code) != pairingCodeLength {
+		logger.Error(referenceID, "ERROR - Device_Claim - Invalid pairing code format")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	// batasi tebakan kode per user
+	window := time.Duration(configs.GetDeviceClaimRateWindow()) * time.Second
+	if ttl, err := utils.ClientRateLimiter(rds.GetRedisClient(), referenceID, fmt.Sprintf("device_claim:%d", userID), configs.GetDeviceClaimRateLimit(), window); err != nil {
+		if ttl > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(ttl.Seconds())+1))
+			result.ErrorCode = "429001"
+			result.ErrorMessage = "Too many attempts"
+			result.Payload["remaining_time"] = int(ttl.Seconds())
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Claim - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Claim - Failed to begin transaction: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	// kode sekali pakai, dihapus di transaksi yang sama dengan pembuatan grant owner
+	var unitID int64
+	queryCode := `DELETE FROM device.pairing_code WHERE code_hash = $1 AND expire_tstamp > $2 RETURNING unit_id`
+	if err := tx.QueryRow(queryCode, crypto.HashSHA256(code), time.Now().Unix()).Scan(&unitID); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Claim - Pairing code not found or expired: ", err)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Invalid pairing code"
+		utils.Response(w, result)
+		return
+	}
+
+	queryOwner := `
+		INSERT INTO device.unit_grant (unit_id, user_id, role, create_tstamp)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (unit_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	_, err = tx.Exec(queryOwner, unitID, userID, unitRoleOwner, time.Now().Unix())
+	if isUniqueViolation(err) {
+		logger.Error(referenceID, "ERROR - Device_Claim - Unit ", unitID, " already has an owner")
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "Unit already has an owner"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Claim - Failed to store grant: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Claim - Failed to commit: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Claim - Unit ", unitID, " claimed by user: ", userID)
+	result.Payload["unit_id"] = unitID
+	result.Payload["role"] = unitRoleOwner
+	utils.Response(w, result)
+}
@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telemetryBuckets adalah ukuran bucket downsampling yang didukung (detik)
+var telemetryBuckets = map[string]int64{
+	"1m": 60,
+	"5m": 300,
+	"1h": 3600,
+}
+
+type telemetryRow struct {
+	ID          int64   `db:"id"`
+	Tstamp      int64   `db:"tstamp"`
+	Voltage     float64 `db:"voltage"`
+	Current     float64 `db:"current"`
+	Power       float64 `db:"power"`
+	Energy      float64 `db:"energy"`
+	Frequency   float64 `db:"frequency"`
+	PowerFactor float64 `db:"power_factor"`
+}
+
+var telemetryRowColumns = []string{"tstamp", "voltage", "current", "power", "energy", "frequency", "power_factor"}
+
+func (t telemetryRow) values() []float64 {
+	return []float64{float64(t.Tstamp), t.Voltage, t.Current, t.Power, t.Energy, t.Frequency, t.PowerFactor}
+}
+
+// telemetryBucketRow adalah agregat satu bucket: rata-rata, min dan max per kolom serta selisih energy
+type telemetryBucketRow struct {
+	Bucket         int64   `db:"bucket"`
+	Samples        int64   `db:"samples"`
+	VoltageAvg     float64 `db:"voltage_avg"`
+	VoltageMin     float64 `db:"voltage_min"`
+	VoltageMax     float64 `db:"voltage_max"`
+	CurrentAvg     float64 `db:"current_avg"`
+	CurrentMin     float64 `db:"current_min"`
+	CurrentMax     float64 `db:"current_max"`
+	PowerAvg       float64 `db:"power_avg"`
+	PowerMin       float64 `db:"power_min"`
+	PowerMax       float64 `db:"power_max"`
+	FrequencyAvg   float64 `db:"frequency_avg"`
+	FrequencyMin   float64 `db:"frequency_min"`
+	FrequencyMax   float64 `db:"frequency_max"`
+	PowerFactorAvg float64 `db:"power_factor_avg"`
+	PowerFactorMin float64 `db:"power_factor_min"`
+	PowerFactorMax float64 `db:"power_factor_max"`
+	EnergyDelta    float64 `db:"energy_delta"` // energy kumulatif, pembacaan terakhir bucket - pembacaan terakhir bucket sebelumnya
+}
+
+var telemetryBucketColumns = []string{
+	"tstamp", "samples",
+	"voltage_avg", "voltage_min", "voltage_max",
+	"current_avg", "current_min", "current_max",
+	"power_avg", "power_min", "power_max",
+	"frequency_avg", "frequency_min", "frequency_max",
+	"power_factor_avg", "power_factor_min", "power_factor_max",
+	"energy_delta",
+}
+
+func (t telemetryBucketRow) values() []float64 {
+	return []float64{
+		float64(t.Bucket), float64(t.Samples),
+		t.VoltageAvg, t.VoltageMin, t.VoltageMax,
+		t.CurrentAvg, t.CurrentMin, t.CurrentMax,
+		t.PowerAvg, t.PowerMin, t.PowerMax,
+		t.FrequencyAvg, t.FrequencyMin, t.FrequencyMax,
+		t.PowerFactorAvg, t.PowerFactorMin, t.PowerFactorMax,
+		t.EnergyDelta,
+	}
+}
+
+// rowsPayload mengubah baris menjadi map kolom -> nilai untuk response JSON
+func rowsPayload(columns []string, rows [][]float64) []map[string]any {
+	list := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		item := make(map[string]any, len(columns))
+		for i, column := range columns {
+			if i == 0 || column == "samples" {
+				item[column] = int64(row[i])
+			} else {
+				item[column] = row[i]
+			}
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// writeTelemetryCSV menulis hasil query sebagai file CSV, cursor halaman berikutnya lewat header X-Next-Cursor
+func writeTelemetryCSV(w http.ResponseWriter, unitID int64, columns []string, rows [][]float64, nextCursor string) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="unit-%d.csv"`, unitID))
+	if nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, value := range row {
+			if i == 0 || columns[i] == "samples" {
+				record[i] = strconv.FormatInt(int64(value), 10)
+			} else {
+				record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseTelemetryCursor membaca cursor halaman: "<tstamp>" (bucket) atau "<tstamp>.<id>" (data mentah)
+func parseTelemetryCursor(cursor string) (tstamp, id int64, err error) {
+	tstampValue, idValue, hasID := strings.Cut(cursor, ".")
+	if tstamp, err = strconv.ParseInt(tstampValue, 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if hasID {
+		if id, err = strconv.ParseInt(idValue, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	return tstamp, id, nil
+}
+
+// telemetryPageEnd mengembalikan batas akhir (eksklusif) limit bucket yang dimulai dari bucket berisi from
+func telemetryPageEnd(from, bucketSize int64, limit int) int64 {
+	return (from/bucketSize)*bucketSize + int64(limit)*bucketSize
+}
+
+/*
+{
+	"unit_id" : 1,
+	"from" : 1739370000,
+	"to" : 1739456400,
+	"bucket" : "5m",
+	"limit" : 500,
+	"cursor" : "",
+	"format" : "json"
+}
+*/
+
+// Device_Data_Query membaca device.data satu unit dalam rentang [from, to).
+// bucket kosong mengembalikan data mentah, format "csv" mengembalikan file CSV.
+func Device_Data_Query(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Data_Query - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	unitIDValue, ok := param["unit_id"].(float64)
+	if !ok || unitIDValue <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+	unitID := int64(unitIDValue)
+
+	// default 24 jam terakhir
+	to := time.Now().Unix() + 1
+	if value, ok := param["to"].(float64); ok {
+		to = int64(value)
+	}
+	from := to - 86400
+	if value, ok := param["from"].(float64); ok {
+		from = int64(value)
+	}
+	if from >= to {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Invalid time range: ", from, " - ", to)
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid time range"
+		utils.Response(w, result)
+		return
+	}
+
+	bucketName, _ := param["bucket"].(string)
+	bucketSize, ok := telemetryBuckets[bucketName]
+	if bucketName != "" && !ok {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Unsupported bucket: ", bucketName)
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid bucket"
+		result.Payload["supported_buckets"] = []string{"1m", "5m", "1h"}
+		utils.Response(w, result)
+		return
+	}
+
+	limit := configs.GetTelemetryQueryDefaultLimit()
+	if value, ok := param["limit"].(float64); ok {
+		limit = int(value)
+	}
+	if limit < 1 || limit > configs.GetTelemetryQueryMaxLimit() {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Invalid limit: ", limit)
+		result.ErrorCode = "400004"
+		result.ErrorMessage = "Invalid limit"
+		result.Payload["max_limit"] = configs.GetTelemetryQueryMaxLimit()
+		utils.Response(w, result)
+		return
+	}
+
+	// halaman berikutnya dimulai setelah cursor (posisi terakhir yang sudah diterima client)
+	var afterTstamp, afterID int64
+	hasCursor := false
+	if cursor, _ := param["cursor"].(string); cursor != "" {
+		var err error
+		afterTstamp, afterID, err = parseTelemetryCursor(cursor)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Device_Data_Query - Invalid cursor: ", cursor)
+			result.ErrorCode = "400005"
+			result.ErrorMessage = "Invalid cursor"
+			utils.Response(w, result)
+			return
+		}
+		hasCursor = true
+	}
+
+	format, _ := param["format"].(string)
+	if format != "" && format != "json" && format != "csv" {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Unsupported format: ", format)
+		result.ErrorCode = "400006"
+		result.ErrorMessage = "Invalid format"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	allowed, err := userCanReadUnit(conn, userID, unitID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Data_Query - Access check failed: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if !allowed {
+		// unit yang tidak ada dan unit tanpa grant diperlakukan sama
+		logger.Error(referenceID, "ERROR - Device_Data_Query - User ", userID, " has no access to unit ", unitID)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	var columns []string
+	var rows [][]float64
+	var nextCursor string
+
+	if bucketSize == 0 {
+		var readings []telemetryRow
+		queryRaw := `
+			SELECT id, tstamp, voltage, current, power, energy, frequency, power_factor
+			FROM device.data
+			WHERE unit_id = $1 AND tstamp >= $2 AND tstamp < $3 AND (NOT $4 OR (tstamp, id) > ($5, $6))
+			ORDER BY tstamp, id
+			LIMIT $7`
+		if err := conn.Select(&readings, queryRaw, unitID, from, to, hasCursor, afterTstamp, afterID, limit+1); err != nil {
+			logger.Error(referenceID, "ERROR - Device_Data_Query - Failed to load readings: ", err)
+			result.ErrorCode = "500003"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+		if len(readings) > limit {
+			readings = readings[:limit]
+			last := readings[limit-1]
+			nextCursor = fmt.Sprintf("%d.%d", last.Tstamp, last.ID)
+		}
+		columns = telemetryRowColumns
+		for _, reading := range readings {
+			rows = append(rows, reading.values())
+		}
+	} else {
+		var buckets []telemetryBucketRow
+		// energy_delta = pembacaan terakhir bucket - pembacaan terakhir bucket sebelumnya (LAG), sehingga
+		// energi di antara dua bucket tidak hilang. Bucket pertama memakai pembacaan terakhir sebelum
+		// bucketFrom, atau pembacaan pertama bucket itu sendiri bila tidak ada data sebelumnya.
+		queryBuckets := `
+			WITH buckets AS (
+				SELECT (tstamp / $2) * $2 AS bucket, COUNT(*) AS samples,
+					AVG(voltage) AS voltage_avg, MIN(voltage) AS voltage_min, MAX(voltage) AS voltage_max,
+					AVG(current) AS current_avg, MIN(current) AS current_min, MAX(current) AS current_max,
+					AVG(power) AS power_avg, MIN(power) AS power_min, MAX(power) AS power_max,
+					AVG(frequency) AS frequency_avg, MIN(frequency) AS frequency_min, MAX(frequency) AS frequency_max,
+					AVG(power_factor) AS power_factor_avg, MIN(power_factor) AS power_factor_min, MAX(power_factor) AS power_factor_max,
+					(ARRAY_AGG(energy ORDER BY tstamp, id))[1] AS energy_first,
+					(ARRAY_AGG(energy ORDER BY tstamp DESC, id DESC))[1] AS energy_last
+				FROM device.data
+				WHERE unit_id = $1 AND tstamp >= $3 AND tstamp < $4
+				GROUP BY bucket
+				ORDER BY bucket
+				LIMIT $5
+			)
+			SELECT bucket, samples,
+				voltage_avg, voltage_min, voltage_max,
+				current_avg, current_min, current_max,
+				power_avg, power_min, power_max,
+				frequency_avg, frequency_min, frequency_max,
+				power_factor_avg, power_factor_min, power_factor_max,
+				energy_last - COALESCE(
+					LAG(energy_last) OVER (ORDER BY bucket),
+					(SELECT energy FROM device.data WHERE unit_id = $1 AND tstamp < $3 ORDER BY tstamp DESC, id DESC LIMIT 1),
+					energy_first
+				) AS energy_delta
+			FROM buckets
+			ORDER BY bucket`
+		// cursor bucket adalah awal bucket terakhir, halaman berikutnya mulai dari bucket sesudahnya
+		bucketFrom := from
+		if hasCursor {
+			bucketFrom = max(from, afterTstamp+bucketSize)
+		}
+		// GROUP BY mengagregasi seluruh rentang sebelum LIMIT, jadi satu halaman hanya memindai rentang limit bucket.
+		// Sisa rentang dilanjutkan lewat cursor walaupun halaman ini berisi kurang dari limit bucket (data jarang).
+		bucketTo := min(to, telemetryPageEnd(bucketFrom, bucketSize, limit))
+		if err := conn.Select(&buckets, queryBuckets, unitID, bucketSize, bucketFrom, bucketTo, limit); err != nil {
+			logger.Error(referenceID, "ERROR - Device_Data_Query - Failed to load buckets: ", err)
+			result.ErrorCode = "500004"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+		if bucketTo < to {
+			nextCursor = strconv.FormatInt(bucketTo-bucketSize, 10)
+		}
+		columns = telemetryBucketColumns
+		for _, bucket := range buckets {
+			rows = append(rows, bucket.values())
+		}
+	}
+
+	if format == "csv" {
+		if err := writeTelemetryCSV(w, unitID, columns, rows, nextCursor); err != nil {
+			logger.Error(referenceID, "ERROR - Device_Data_Query - Failed to write CSV: ", err)
+		}
+		return
+	}
+
+	result.Payload["unit_id"] = unitID
+	result.Payload["from"] = from
+	result.Payload["to"] = to
+	result.Payload["bucket"] = bucketName
+	result.Payload["rows"] = rowsPayload(columns, rows)
+	if nextCursor != "" {
+		result.Payload["next_cursor"] = nextCursor
+	}
+	utils.Response(w, result)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import "testing"
+
+func TestParseTelemetryCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     string
+		wantTstamp int64
+		wantID     int64
+		wantErr    bool
+	}{
+		{"bucket cursor", "1739370000", 1739370000, 0, false},
+		{"raw cursor with id", "1739370518.42", 1739370518, 42, false},
+		{"zero id", "1739370518.0", 1739370518, 0, false},
+		{"negative tstamp", "-60", -60, 0, false},
+		{"empty", "", 0, 0, true},
+		{"non numeric tstamp", "abc", 0, 0, true},
+		{"non numeric id", "1739370518.abc", 0, 0, true},
+		{"empty id", "1739370518.", 0, 0, true},
+		{"empty tstamp", ".42", 0, 0, true},
+		{"extra part", "1739370518.42.1", 0, 0, true},
+		{"float tstamp", "1739370518.5e3", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tstamp, id, err := parseTelemetryCursor(tt.cursor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTelemetryCursor(%q) error = %v, wantErr %v", tt.cursor, err, tt.wantErr)
+			}
+			if tstamp != tt.wantTstamp || id != tt.wantID {
+				t.Errorf("parseTelemetryCursor(%q) = (%d, %d), want (%d, %d)", tt.cursor, tstamp, id, tt.wantTstamp, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestTelemetryPageEnd(t *testing.T) {
+	tests := []struct {
+		name       string
+		from       int64
+		bucketSize int64
+		limit      int
+		want       int64
+	}{
+		{"aligned from", 3600, 300, 4, 3600 + 4*300},
+		// bucket pertama dimulai di awal bucket yang berisi from
+		{"unaligned from", 3650, 300, 4, 3600 + 4*300},
+		{"single bucket", 7199, 3600, 1, 7200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := telemetryPageEnd(tt.from, tt.bucketSize, tt.limit); got != tt.want {
+				t.Errorf("telemetryPageEnd(%d, %d, %d) = %d, want %d", tt.from, tt.bucketSize, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
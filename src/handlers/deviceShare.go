@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/rds"
+	"auth_service/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Berbagi akses unit antar user, dikelola oleh owner unit (lihat deviceGrant.go untuk role)
+
+// Device_Unit_List mengembalikan unit yang bisa diakses user beserta role-nya.
+// Admin tetap memakai /admin/devices untuk melihat semua unit.
+func Device_Unit_List(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Unit_List - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Device_Unit_List - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Unit_List - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var units []struct {
+		ID              int64           `db:"id"`
+		Name            string          `db:"name"`
+		Status          int             `db:"status"`
+		Data            json.RawMessage `db:"data"`
+		Role            string          `db:"role"`
+		GrantTstamp     int64           `db:"grant_tstamp"`
+		LastLoginTstamp sql.NullInt64   `db:"last_login_tstamp"`
+	}
+	queryUnits := `
+		SELECT u.id, u.name, u.status, u.data, g.role, g.create_tstamp AS grant_tstamp, s.tstamp AS last_login_tstamp
+		FROM device.unit_grant g
+		JOIN device.unit u ON u.id = g.unit_id
+		LEFT JOIN device.session s ON s.unit_id = u.id
+		WHERE g.user_id = $1
+		ORDER BY u.id`
+	if err := conn.Select(&units, queryUnits, userID); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Unit_List - Failed to load units: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	list := make([]map[string]any, 0, len(units))
+	for _, unit := range units {
+		item := map[string]any{
+			"unit_id":      unit.ID,
+			"name":         unit.Name,
+			"status":       unit.Status,
+			"data":         unit.Data,
+			"role":         unit.Role,
+			"grant_tstamp": unit.GrantTstamp,
+		}
+		if unit.LastLoginTstamp.Valid {
+			item["last_login_tstamp"] = unit.LastLoginTstamp.Int64
+		}
+		list = append(list, item)
+	}
+
+	result.Payload["units"] = list
+	utils.Response(w, result)
+}
+
+/*
+{
+	"unit_id" : 1,
+	"email" : "teman@example.com",
+	"role" : "viewer"
+}
+*/
+
+// Device_Share memberi akses unit ke user lain berdasarkan email.
+// Dengan enumeration protection, email yang tidak terdaftar mendapat response yang sama dengan email terdaftar.
+func Device_Share(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Share - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Device_Share - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	unitIDValue, ok := param["unit_id"].(float64)
+	if !ok || unitIDValue <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Share - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+	unitID := int64(unitIDValue)
+
+	email, _ := param["email"].(string)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		logger.Error(referenceID, "ERROR - Device_Share - Missing email")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	// owner tidak bisa dibagikan, hanya satu per unit
+	role, _ := param["role"].(string)
+	if role == "" {
+		role = unitRoleViewer
+	}
+	if role != unitRoleViewer && role != unitRoleOperator {
+		logger.Error(referenceID, "ERROR - Device_Share - Invalid role: ", role)
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid role"
+		utils.Response(w, result)
+		return
+	}
+
+	// batasi share per owner supaya email tidak bisa diuji satu per satu
+	window := time.Duration(configs.GetDeviceShareRateWindow()) * time.Second
+	if ttl, err := utils.ClientRateLimiter(rds.GetRedisClient(), referenceID, fmt.Sprintf("device_share:%d", userID), configs.GetDeviceShareRateLimit(), window); err != nil {
+		if ttl > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(ttl.Seconds())+1))
+			result.ErrorCode = "429001"
+			result.ErrorMessage = "Too many attempts"
+			result.Payload["remaining_time"] = int(ttl.Seconds())
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	callerRole, err := unitRole(conn, userID, unitID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share - Failed to load role: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if callerRole != unitRoleOwner {
+		// unit yang tidak ada dan unit milik orang lain diperlakukan sama
+		logger.Error(referenceID, "ERROR - Device_Share - User ", userID, " is not owner of unit ", unitID)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	var targetID int64
+	err = conn.Get(&targetID, `SELECT id FROM sysuser."user" WHERE email = $1`, email)
+	if err == sql.ErrNoRows && configs.GetEnumerationProtection() {
+		// response dibuat sama persis dengan email yang terdaftar
+		logger.Warning(referenceID, "WARNING - Device_Share - Share requested for unknown account")
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+		result.Payload["unit_id"] = unitID
+		result.Payload["email"] = email
+		result.Payload["role"] = role
+		utils.Response(w, result)
+		return
+	} else if err == sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - Device_Share - User not found")
+		result.ErrorCode = "404002"
+		result.ErrorMessage = "User not found"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share - User lookup failed: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if targetID == userID {
+		logger.Error(referenceID, "ERROR - Device_Share - Owner cannot share unit with themselves")
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "Already owner"
+		utils.Response(w, result)
+		return
+	}
+
+	// share ulang ke user yang sama hanya mengganti role
+	queryShare := `
+		INSERT INTO device.unit_grant (unit_id, user_id, role, create_tstamp)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (unit_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	if _, err := conn.Exec(queryShare, unitID, targetID, role, time.Now().Unix()); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share - Failed to store grant: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Share - Unit ", unitID, " shared by user ", userID, " with user ", targetID, " as ", role)
+	result.Payload["unit_id"] = unitID
+	result.Payload["email"] = email
+	result.Payload["role"] = role
+	if configs.GetEnumerationProtection() {
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+	} else {
+		result.Payload["user_id"] = targetID
+	}
+	utils.Response(w, result)
+}
+
+/*
+{
+	"unit_id" : 1
+}
+*/
+
+// Device_Share_List mengembalikan semua user yang punya akses ke unit, hanya untuk owner
+func Device_Share_List(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Share_List - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Device_Share_List - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	unitIDValue, ok := param["unit_id"].(float64)
+	if !ok || unitIDValue <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Share_List - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+	unitID := int64(unitIDValue)
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share_List - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	callerRole, err := unitRole(conn, userID, unitID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share_List - Failed to load role: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if callerRole != unitRoleOwner {
+		logger.Error(referenceID, "ERROR - Device_Share_List - User ", userID, " is not owner of unit ", unitID)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	var grants []struct {
+		UserID       int64  `db:"user_id"`
+		Email        string `db:"email"`
+		FullName     string `db:"full_name"`
+		Role         string `db:"role"`
+		CreateTstamp int64  `db:"create_tstamp"`
+	}
+	queryGrants := `
+		SELECT g.user_id, u.email, u.full_name, g.role, g.create_tstamp
+		FROM device.unit_grant g
+		JOIN sysuser."user" u ON u.id = g.user_id
+		WHERE g.unit_id = $1
+		ORDER BY g.create_tstamp`
+	if err := conn.Select(&grants, queryGrants, unitID); err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share_List - Failed to load grants: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	list := make([]map[string]any, 0, len(grants))
+	for _, grant := range grants {
+		list = append(list, map[string]any{
+			"user_id":       grant.UserID,
+			"email":         grant.Email,
+			"full_name":     grant.FullName,
+			"role":          grant.Role,
+			"create_tstamp": grant.CreateTstamp,
+		})
+	}
+
+	result.Payload["unit_id"] = unitID
+	result.Payload["grants"] = list
+	utils.Response(w, result)
+}
+
+/*
+{
+	"unit_id" : 1,
+	"user_id" : 42
+}
+*/
+
+// Device_Share_Revoke mencabut akses user lain (oleh owner) atau melepas akses sendiri.
+// Grant owner tidak bisa dicabut lewat endpoint ini.
+func Device_Share_Revoke(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Device_Share_Revoke - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	unitIDValue, ok := param["unit_id"].(float64)
+	if !ok || unitIDValue <= 0 {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - Missing unit_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+	unitID := int64(unitIDValue)
+
+	// tanpa user_id berarti melepas akses sendiri
+	targetID := userID
+	if value, exists := param["user_id"]; exists {
+		targetValue, ok := value.(float64)
+		if !ok || targetValue <= 0 {
+			logger.Error(referenceID, "ERROR - Device_Share_Revoke - Invalid user_id")
+			result.ErrorCode = "400002"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		targetID = int64(targetValue)
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	callerRole, err := unitRole(conn, userID, unitID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - Failed to load role: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if callerRole == "" || (targetID != userID && callerRole != unitRoleOwner) {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - User ", userID, " cannot revoke access to unit ", unitID)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+	if targetID == userID && callerRole == unitRoleOwner {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - Owner cannot revoke own access to unit ", unitID)
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "Owner cannot leave unit"
+		utils.Response(w, result)
+		return
+	}
+
+	res, err := conn.Exec(`DELETE FROM device.unit_grant WHERE unit_id = $1 AND user_id = $2 AND role <> $3`, unitID, targetID, unitRoleOwner)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - Failed to delete grant: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if deleted, _ := res.RowsAffected(); deleted == 0 {
+		logger.Error(referenceID, "ERROR - Device_Share_Revoke - Grant not found")
+		result.ErrorCode = "404002"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Device_Share_Revoke - Grant revoked: unit ", unitID, ", user ", targetID, " by user ", userID)
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
@@ -0,0 +1,764 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/idp"
+	"auth_service/logger"
+	"auth_service/oidc"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// !NOTE : login lewat identity provider eksternal (Google, GitHub, IdP OIDC lain, lihat package idp)
+/*	1. Frontend membuka /idp/login?provider=<name>, server redirect ke provider (authorization code + PKCE)
+   dan memasang cookie idp_state untuk mengikat alur ke browser tersebut
+2. Provider redirect ke <OIDCISSUER>/idp/callback, server menukar code dan membaca identity
+3. Identity dicari di sysuser.identity (provider + subject). Bila belum ada:
+   - provider link_by_email: dihubungkan ke akun dengan email terverifikasi yang sama
+   - provider allow_signup: akun baru dibuat (JIT) lewat createUser, sama seperti Register_Verify_OTP
+4. Server redirect ke clientURL + idpCallbackPath?ticket=.. (atau ?error=..), frontend menukar ticket
+   di /idp/exchange menjadi sesi (user dengan TOTP aktif tetap diminta Verify_TOTP)
+5. User yang sudah login menghubungkan provider lewat /account/identities/link (response redirect_to),
+   response tersebut juga memasang cookie idp_state sehingga frontend harus memanggilnya dengan credentials
+   (fetch credentials: "include") dari origin yang same-site dengan server
+6. /account/identities/unlink menolak melepas provider terakhir bila akun tidak punya cara login lain
+   (password yang diketahui user, akun LDAP, provider lain atau passkey)
+*/
+
+/*
+CREATE TABLE sysuser.identity (
+	id bigserial NOT NULL,
+	provider character varying(64) NOT NULL,
+	subject character varying(255) NOT NULL,
+	user_id int8 NOT NULL,
+	email character varying(255) NULL,      -- email dari provider saat terakhir login
+	create_tstamp int8 NOT NULL,
+	last_login_tstamp int8 NULL,
+	CONSTRAINT identity_pkey PRIMARY KEY (id),
+	CONSTRAINT identity_provider_subject_key UNIQUE (provider, subject),
+	CONSTRAINT identity_user_provider_key UNIQUE (user_id, provider),
+	CONSTRAINT identity_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+
+ALTER TABLE sysuser."user" ADD COLUMN password_set boolean NOT NULL DEFAULT true; -- false untuk akun JIT (password acak) sampai reset password
+*/
+
+const idpStateCookieName = "idp_state"
+
+type idpState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	LinkUserID   int64  `json:"link_user_id,omitempty"` // diisi bila alur menghubungkan akun yang sedang login
+}
+
+type idpTicket struct {
+	UserID int64 `json:"user_id"`
+}
+
+func idpStateKey(state string) string {
+	return "idp_state:" + crypto.HashSHA256(state)
+}
+
+func idpTicketKey(ticket string) string {
+	return "idp_ticket:" + crypto.HashSHA256(ticket)
+}
+
+// idpRedirectURI adalah callback yang harus didaftarkan di setiap provider
+func idpRedirectURI() string {
+	return oidc.Issuer() + "/idp/callback"
+}
+
+func idpFrontendRedirect(w http.ResponseWriter, r *http.Request, values map[string]string) {
+	http.Redirect(w, r, appendQuery(configs.GetClientURL()+configs.GetIdPCallbackPath(), values), http.StatusFound)
+}
+
+// setIdPStateCookie mengikat state alur ke browser yang memulainya, dicek lagi di IdP_Callback
+func setIdPStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     idpStateCookieName,
+		Value:    state,
+		Path:     "/idp",
+		MaxAge:   int(configs.GetIdPStateExpireTime()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(oidc.Issuer(), "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// startIdPFlow menyimpan state alur di Redis lalu membuat URL authorization provider
+func startIdPFlow(provider *idp.Provider, linkUserID int64) (string, string, error) {
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		return "", "", fmt.Errorf("redis client is not initialized")
+	}
+
+	state, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	authURL, err := provider.AuthCodeURL(ctx, idpRedirectURI(), state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	value := marshalOAuthState(idpState{Provider: provider.Name, Nonce: nonce, CodeVerifier: codeVerifier, LinkUserID: linkUserID})
+	expiry := time.Duration(configs.GetIdPStateExpireTime()) * time.Second
+	if err := redisClient.Set(ctx, idpStateKey(state), value, expiry).Err(); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// IdP_Providers mengembalikan daftar provider untuk tombol login di frontend
+func IdP_Providers(w http.ResponseWriter, r *http.Request) {
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	list := make([]map[string]any, 0)
+	for _, provider := range idp.List() {
+		list = append(list, map[string]any{
+			"name":         provider.Name,
+			"display_name": provider.DisplayName,
+		})
+	}
+
+	result.Payload["providers"] = list
+	utils.Response(w, result)
+}
+
+// IdP_Login memulai login lewat provider (dibuka langsung oleh browser, bukan fetch)
+func IdP_Login(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - IdP_Login - Execution completed in ", duration)
+	}()
+
+	provider, ok := idp.Get(r.URL.Query().Get("provider"))
+	if !ok {
+		logger.Error(referenceID, "ERROR - IdP_Login - Unknown provider: ", r.URL.Query().Get("provider"))
+		idpFrontendRedirect(w, r, map[string]string{"error": "unknown_provider"})
+		return
+	}
+
+	authURL, state, err := startIdPFlow(provider, 0)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Login - Failed to start login with ", provider.Name, ": ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+
+	setIdPStateCookie(w, state)
+
+	logger.Info(referenceID, "INFO - IdP_Login - Redirecting to provider: ", provider.Name)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+/*
+{
+	"provider" : "google"
+}
+*/
+
+// IdP_Link memulai penghubungan provider ke akun yang sedang login.
+// Frontend membuka redirect_to di browser, hasil dikirim ke idpCallbackPath?linked=<provider>
+func IdP_Link(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - IdP_Link - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+
+	param, _ := utils.Request(r)
+
+	providerName, _ := param["provider"].(string)
+	provider, ok := idp.Get(providerName)
+	if !ok {
+		logger.Error(referenceID, "ERROR - IdP_Link - Unknown provider: ", providerName)
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	authURL, state, err := startIdPFlow(provider, userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Link - Failed to start linking with ", provider.Name, ": ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// tanpa cookie ini, URL provider bisa diberikan ke korban sehingga identity korban terhubung ke akun penyerang
+	setIdPStateCookie(w, state)
+
+	result.Payload["redirect_to"] = authURL
+	utils.Response(w, result)
+}
+
+// IdP_Callback menerima redirect dari provider. Semua hasil dikirim ke frontend lewat redirect.
+func IdP_Callback(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - IdP_Callback - Execution completed in ", duration)
+	}()
+
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state == "" {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Missing state")
+		idpFrontendRedirect(w, r, map[string]string{"error": "invalid_state"})
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Redis client is not initialized")
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+
+	// state sekali pakai
+	value, err := redisClient.GetDel(context.Background(), idpStateKey(state)).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - State not found, used or expired: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "invalid_state"})
+		return
+	}
+
+	var flow idpState
+	if err := json.Unmarshal([]byte(value), &flow); err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Invalid state data: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+
+	// alur login maupun link harus selesai di browser yang memulainya (mencegah login / linking CSRF)
+	cookie, err := r.Cookie(idpStateCookieName)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Browser binding mismatch")
+		idpFrontendRedirect(w, r, map[string]string{"error": "invalid_state"})
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: idpStateCookieName, Value: "", Path: "/idp", MaxAge: -1})
+
+	if providerError := query.Get("error"); providerError != "" {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Provider returned error: ", providerError, " ", query.Get("error_description"))
+		idpFrontendRedirect(w, r, map[string]string{"error": "access_denied"})
+		return
+	}
+
+	provider, ok := idp.Get(flow.Provider)
+	if !ok {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Provider no longer configured: ", flow.Provider)
+		idpFrontendRedirect(w, r, map[string]string{"error": "unknown_provider"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	identity, err := provider.Authenticate(ctx, idpRedirectURI(), query.Get("code"), flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Authentication with ", provider.Name, " failed: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "provider_error"})
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to get DB connection: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+
+	var linkedUserID int64
+	err = conn.Get(&linkedUserID, `SELECT user_id FROM sysuser.identity WHERE provider = $1 AND subject = $2`, provider.Name, identity.Subject)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Identity lookup failed: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+
+	if flow.LinkUserID != 0 {
+		idpLinkIdentity(w, r, referenceID, conn, provider, identity, flow.LinkUserID, linkedUserID)
+		return
+	}
+
+	userID := linkedUserID
+	if userID != 0 {
+		conn.Exec(`UPDATE sysuser.identity SET email = $1, last_login_tstamp = $2 WHERE provider = $3 AND subject = $4`,
+			sql.NullString{String: identity.Email, Valid: identity.Email != ""}, time.Now().Unix(), provider.Name, identity.Subject)
+	} else {
+		var errorCode string
+		userID, errorCode = idpResolveNewIdentity(r, referenceID, conn, provider, identity)
+		if errorCode != "" {
+			idpFrontendRedirect(w, r, map[string]string{"error": errorCode})
+			return
+		}
+	}
+
+	ticket, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to generate ticket: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+	expiry := time.Duration(configs.GetIdPTicketExpireTime()) * time.Second
+	if err := redisClient.Set(context.Background(), idpTicketKey(ticket), marshalOAuthState(idpTicket{UserID: userID}), expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to store ticket: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+
+	logger.Info(referenceID, "INFO - IdP_Callback - Login ticket issued for user: ", userID, " via ", provider.Name)
+	idpFrontendRedirect(w, r, map[string]string{"ticket": ticket})
+}
+
+// idpLinkIdentity menghubungkan identity ke akun yang memulai alur IdP_Link
+func idpLinkIdentity(w http.ResponseWriter, r *http.Request, referenceID string, conn *sqlx.DB, provider *idp.Provider, identity *idp.Identity, userID, linkedUserID int64) {
+	if linkedUserID == userID {
+		idpFrontendRedirect(w, r, map[string]string{"linked": provider.Name})
+		return
+	}
+	if linkedUserID != 0 {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Identity already linked to another account")
+		idpFrontendRedirect(w, r, map[string]string{"error": "identity_in_use"})
+		return
+	}
+
+	if err := insertIdentity(conn, provider.Name, identity, userID); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			logger.Error(referenceID, "ERROR - IdP_Callback - Account already linked to ", provider.Name)
+			idpFrontendRedirect(w, r, map[string]string{"error": "already_linked"})
+			return
+		}
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to link identity: ", err)
+		idpFrontendRedirect(w, r, map[string]string{"error": "server_error"})
+		return
+	}
+
+	logger.Info(referenceID, "INFO - IdP_Callback - Identity ", provider.Name, " linked to user: ", userID)
+	idpFrontendRedirect(w, r, map[string]string{"linked": provider.Name})
+}
+
+// idpResolveNewIdentity menghubungkan identity baru ke akun yang ada (link_by_email) atau membuat akun (allow_signup).
+// Mengembalikan error code untuk frontend bila gagal.
+func idpResolveNewIdentity(r *http.Request, referenceID string, conn *sqlx.DB, provider *idp.Provider, identity *idp.Identity) (int64, string) {
+	if identity.Email == "" || !identity.EmailVerified {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Provider did not return a verified email")
+		if provider.AllowSignup || provider.LinkByEmail {
+			return 0, "email_required"
+		}
+		return 0, "account_not_found"
+	}
+
+	var existingUserID int64
+	err := conn.Get(&existingUserID, `SELECT id FROM sysuser."user" WHERE email = $1`, identity.Email)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - IdP_Callback - User lookup failed: ", err)
+		return 0, "server_error"
+	}
+
+	if existingUserID != 0 {
+		if !provider.LinkByEmail {
+			// akun lokal dengan email sama harus menghubungkan provider sendiri setelah login
+			logger.Error(referenceID, "ERROR - IdP_Callback - Email belongs to an existing account")
+			return 0, "email_in_use"
+		}
+		if err := insertIdentity(conn, provider.Name, identity, existingUserID); err != nil {
+			logger.Error(referenceID, "ERROR - IdP_Callback - Failed to link identity by email: ", err)
+			return 0, "server_error"
+		}
+		logger.Info(referenceID, "INFO - IdP_Callback - Identity ", provider.Name, " linked by email to user: ", existingUserID)
+		return existingUserID, ""
+	}
+
+	if !provider.AllowSignup {
+		logger.Error(referenceID, "ERROR - IdP_Callback - No account for identity and signup is disabled")
+		return 0, "account_not_found"
+	}
+
+	username, err := idpUsername(conn, identity)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to derive username: ", err)
+		return 0, "server_error"
+	}
+	fullName := strings.TrimSpace(identity.FullName)
+	if fullName == "" {
+		fullName = username
+	}
+	// password acak, user bisa mengatur password sendiri lewat reset password
+	password, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to generate password: ", err)
+		return 0, "server_error"
+	}
+
+	userID, err := createUser(referenceID, "IdP_Callback", conn, newUser{
+		Username:       username,
+		FullName:       fullName,
+		Email:          identity.Email,
+		Password:       password,
+		RandomPassword: true,
+		Locale:         requestLocale(r, nil),
+	})
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to provision account: ", err)
+		return 0, "server_error"
+	}
+
+	if err := insertIdentity(conn, provider.Name, identity, userID); err != nil {
+		// callback paralel untuk identity yang sama, akun yang baru dibuat dibatalkan
+		logger.Error(referenceID, "ERROR - IdP_Callback - Failed to link provisioned account: ", err)
+		conn.Exec(`DELETE FROM sysuser."user" WHERE id = $1`, userID)
+		return 0, "server_error"
+	}
+
+	logger.Info(referenceID, "INFO - IdP_Callback - Account provisioned from ", provider.Name, ": ", userID, " (", username, ")")
+	return userID, ""
+}
+
+func insertIdentity(conn *sqlx.DB, provider string, identity *idp.Identity, userID int64) error {
+	now := time.Now().Unix()
+	queryInsert := `
+		INSERT INTO sysuser.identity (provider, subject, user_id, email, create_tstamp, last_login_tstamp)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := conn.Exec(queryInsert, provider, identity.Subject, userID, sql.NullString{String: identity.Email, Valid: identity.Email != ""}, now, now)
+	return err
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// idpUsername membuat username unik dari claim username / email provider (minimal 6 karakter seperti Register)
+func idpUsername(conn *sqlx.DB, identity *idp.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = strings.Trim(usernameInvalidChars.ReplaceAllString(strings.ToLower(base), ""), "._-")
+	if len(base) > 32 {
+		base = base[:32]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		if len(candidate) >= 6 {
+			var exists bool
+			if err := conn.Get(&exists, `SELECT EXISTS (SELECT 1 FROM sysuser."user" WHERE username = $1)`, candidate); err != nil {
+				return "", err
+			}
+			if !exists {
+				return candidate, nil
+			}
+		}
+		suffix, err := utils.RandoNnumberGenerator(4)
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, suffix)
+	}
+	return "", fmt.Errorf("no free username for %s", base)
+}
+
+/*
+{
+	"ticket" : "<ticket>"
+}
+*/
+
+// IdP_Exchange menukar ticket dari IdP_Callback menjadi sesi
+func IdP_Exchange(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - IdP_Exchange - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	ticket, ok := param["ticket"].(string)
+	if !ok || ticket == "" {
+		logger.Error(referenceID, "ERROR - IdP_Exchange - Missing ticket")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - IdP_Exchange - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	value, err := redisClient.GetDel(context.Background(), idpTicketKey(ticket)).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Exchange - Ticket not found, used or expired: ", err)
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	var stored idpTicket
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Exchange - Invalid ticket data: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Exchange - Failed to get DB connection: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if !completeLogin(referenceID, "IdP_Exchange", r, conn, stored.UserID, ticket, &result) {
+		utils.Response(w, result)
+		return
+	}
+
+	utils.Response(w, result)
+}
+
+func IdP_Identities(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Identities - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var identities []struct {
+		Provider        string         `db:"provider"`
+		Email           sql.NullString `db:"email"`
+		CreateTstamp    int64          `db:"create_tstamp"`
+		LastLoginTstamp sql.NullInt64  `db:"last_login_tstamp"`
+	}
+	queryList := `SELECT provider, email, create_tstamp, last_login_tstamp FROM sysuser.identity WHERE user_id = $1 ORDER BY create_tstamp`
+	if err := conn.Select(&identities, queryList, userID); err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Identities - Failed to load identities: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	list := make([]map[string]any, 0, len(identities))
+	for _, identity := range identities {
+		list = append(list, map[string]any{
+			"provider":          identity.Provider,
+			"email":             identity.Email.String,
+			"create_tstamp":     identity.CreateTstamp,
+			"last_login_tstamp": identity.LastLoginTstamp.Int64,
+		})
+	}
+
+	result.Payload["identities"] = list
+	utils.Response(w, result)
+}
+
+/*
+{
+	"provider" : "google"
+}
+*/
+
+func IdP_Unlink(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+
+	param, _ := utils.Request(r)
+
+	provider, ok := param["provider"].(string)
+	if !ok || provider == "" {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Missing provider")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Failed to begin transaction: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	// baris user dikunci supaya dua unlink paralel tidak sama-sama lolos cek di bawah
+	var methods struct {
+		HasPassword    bool  `db:"has_password"`
+		OtherProviders int64 `db:"other_providers"`
+		Passkeys       int64 `db:"passkeys"`
+	}
+	queryMethods := `
+		SELECT (u.password_set OR u.auth_source = $3) AS has_password,
+			(SELECT COUNT(*) FROM sysuser.identity WHERE user_id = u.id AND provider <> $2) AS other_providers,
+			(SELECT COUNT(*) FROM sysuser.webauthn_credential WHERE user_id = u.id) AS passkeys
+		FROM sysuser."user" u WHERE u.id = $1 FOR UPDATE`
+	if err := tx.Get(&methods, queryMethods, userID, provider, authSourceLDAP); err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Failed to load login methods: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	res, err := tx.Exec(`DELETE FROM sysuser.identity WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Failed to delete identity: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if deleted, _ := res.RowsAffected(); deleted == 0 {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Identity not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	// akun JIT memakai password acak, tanpa provider ini user tidak bisa login lagi
+	if !methods.HasPassword && methods.OtherProviders == 0 && methods.Passkeys == 0 {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Refusing to remove the only login method of user: ", userID)
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "Set a password or add another login method before unlinking this provider"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - IdP_Unlink - Failed to commit: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - IdP_Unlink - Identity ", provider, " unlinked from user: ", userID)
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"auth_service/mail"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// requestLocale mengambil locale dari parameter "locale", fallback ke header Accept-Language
+func requestLocale(r *http.Request, param map[string]any) string {
+	if locale, ok := param["locale"].(string); ok && locale != "" {
+		return mail.NormalizeLocale(locale)
+	}
+	return mail.NormalizeLocale(r.Header.Get("Accept-Language"))
+}
+
+// userLocale membaca locale yang tersimpan di sysuser.user.data->>'locale'
+func userLocale(conn *sqlx.DB, userID int64) string {
+	var locale string
+	queryGetLocale := `SELECT COALESCE(data->>'locale', '') FROM sysuser."user" WHERE id = $1`
+	if err := conn.Get(&locale, queryGetLocale, userID); err != nil {
+		return mail.DefaultLocale
+	}
+	return mail.NormalizeLocale(locale)
+}
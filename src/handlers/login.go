@@ -75,6 +75,7 @@ type UserCred struct {
 	ID             int64  `db:"id"`
 	Salt           string `db:"salt"`
 	SaltedPassword string `db:"saltedpassword"`
+	AuthSource     string `db:"auth_source"`
 }
 
 /* type UserData struct {
@@ -148,11 +149,9 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var userCred UserCred
-	queryGetUser := `SELECT id, salt, saltedpassword FROM sysuser.user WHERE username = $1 OR email = $1`
-
-	if err := conn.Get(&userCred, queryGetUser, userData); err != nil {
-		logger.Error(referenceID, "ERROR - Login - User not found: ", err)
+	userCred, err := authenticateLogin(referenceID, conn, userData, password)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Login - Authentication failed: ", err)
 		result.ErrorCode = "401000"
 		result.ErrorMessage = "Unauthorized"
 		utils.Response(w, result)
@@ -204,6 +203,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 type UserData struct {
 	Username string          `db:"username"`
 	Email    string          `db:"email"`
+	Phone    string          `db:"phone"`
 	FullName string          `db:"full_name"`
 	Role     string          `db:"role"`
 	Data     json.RawMessage `db:"data"` // jsonb
@@ -277,63 +277,11 @@ func Verify_Token(w http.ResponseWriter, r *http.Request) {
 		logger.Warning(referenceID, "WARNING - VerifyToken - Token cleanup failed", err)
 	}
 
-	// Generate session ID and session hash
-	sessionID, errMsg := utils.RandomStringGenerator(16)
-	if errMsg != nil {
-		logger.Error(referenceID, "ERROR - VerifyToken - Session ID generation failed")
-		result.ErrorCode = "500000"
-		result.ErrorMessage = "Internal server error"
-		utils.Response(w, result)
-		return
-	}
-
-	sessionHash, errMsg := crypto.GenerateHMAC(tokenClient, sessionID)
-	if errMsg != nil {
-		logger.Error(referenceID, "ERROR - VerifyToken - HMAC computation failed")
-		result.ErrorCode = "500000"
-		result.ErrorMessage = "Internal server error"
+	// user dengan TOTP aktif harus lolos langkah kedua (Verify_TOTP) sebelum sesi dibuat
+	if !completeLogin(referenceID, "VerifyToken", r, conn, userID, tokenClient, &result) {
 		utils.Response(w, result)
 		return
 	}
 
-	// Create or update session
-	currentTime := time.Now().Unix()
-	queryUpsertSession := `
-		INSERT INTO sysuser.session (session_id, user_id, session_hash, tstamp, st)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (user_id) DO UPDATE SET
-			session_id = EXCLUDED.session_id,
-			session_hash = EXCLUDED.session_hash,
-			tstamp = EXCLUDED.tstamp,
-			st = EXCLUDED.st`
-
-	if _, err := conn.Exec(queryUpsertSession, sessionID, userID, sessionHash, currentTime, 1); err != nil {
-		logger.Error(referenceID, "ERROR - VerifyToken - Session upsert failed")
-		result.ErrorCode = "500000"
-		result.ErrorMessage = "Internal server error"
-		utils.Response(w, result)
-		return
-	}
-
-	// Fetch user data
-	var userData UserData
-	queryGetUserData := `SELECT username, email, full_name, role, COALESCE(data, '{}'::jsonb) AS data FROM sysuser.user WHERE id = $1`
-	if err := conn.Get(&userData, queryGetUserData, userID); err != nil {
-		logger.Error(referenceID, "ERROR - VerifyToken - User not found", err)
-		result.ErrorCode = "401000"
-		result.ErrorMessage = "Unauthorized"
-		utils.Response(w, result)
-		return
-	}
-
-	// Prepare response payload
-	result.Payload["session_id"] = sessionID
-	result.Payload["session_hash"] = sessionHash
-	result.Payload["username"] = userData.Username
-	result.Payload["full_name"] = userData.FullName
-	result.Payload["email"] = userData.Email
-	result.Payload["role"] = userData.Role
-	result.Payload["data"] = userData.Data
-
 	utils.Response(w, result)
 }
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/logger"
+	"auth_service/mail"
+	"auth_service/rds"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/*	Penguncian akun setelah faktor kedua (kode TOTP / recovery code) berulang kali salah.
+	Batas per mfa_token (mfaMaxAttempts) saja tidak cukup karena penyerang yang tahu password bisa login ulang
+	untuk mendapat challenge baru, jadi kegagalan juga dihitung per user:
+	- login_failures:<user_id>  jumlah gagal dalam window loginFailureWindow
+	- login_lock:<user_id>      ada selama akun terkunci (TTL loginLockoutTime)
+	Selama terkunci completeLogin, Verify_TOTP dan WebAuthn_Login_Verify menolak login, pemilik akun mendapat email lockout_notice sekali per penguncian.
+	TOTP_Confirm dan TOTP_Disable memakai hitungan yang sama, supaya sesi yang dicuri tidak bisa menebak kode TOTP tanpa batas.
+*/
+
+func loginFailuresKey(userID int64) string {
+	return fmt.Sprintf("login_failures:%d", userID)
+}
+
+func loginLockKey(userID int64) string {
+	return fmt.Sprintf("login_lock:%d", userID)
+}
+
+// loginLockRemaining mengembalikan sisa waktu penguncian akun, 0 bila tidak terkunci
+func loginLockRemaining(userID int64) (time.Duration, error) {
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		return 0, errors.New("redis client is not initialized")
+	}
+
+	ttl, err := redisClient.TTL(context.Background(), loginLockKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// -2 berarti key tidak ada (tidak terkunci)
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// recordLoginFailure menghitung satu kegagalan, akun dikunci saat jumlahnya mencapai loginMaxFailures.
+// Mengembalikan true bila akun terkunci karena kegagalan ini.
+func recordLoginFailure(referenceID string, conn *sqlx.DB, userID int64) (bool, error) {
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		return false, errors.New("redis client is not initialized")
+	}
+
+	ctx := context.Background()
+	failuresKey := loginFailuresKey(userID)
+	failures, err := redisClient.Incr(ctx, failuresKey).Result()
+	if err != nil {
+		return false, err
+	}
+	if failures == 1 {
+		redisClient.Expire(ctx, failuresKey, time.Duration(configs.GetLoginFailureWindow())*time.Second)
+	}
+	if failures < configs.GetLoginMaxFailures() {
+		return false, nil
+	}
+
+	lockout := time.Duration(configs.GetLoginLockoutTime()) * time.Second
+	locked, err := redisClient.SetNX(ctx, loginLockKey(userID), time.Now().Unix(), lockout).Result()
+	if err != nil {
+		return false, err
+	}
+	redisClient.Del(ctx, failuresKey)
+	if !locked {
+		return false, nil
+	}
+
+	logger.Warning(referenceID, "WARNING - Login Lockout - Account locked after ", failures, " failed attempts: ", userID)
+	notifyLockout(referenceID, conn, userID, time.Now().Add(lockout))
+	return true, nil
+}
+
+// clearLoginFailures mereset hitungan gagal setelah faktor kedua berhasil
+func clearLoginFailures(userID int64) {
+	if redisClient := rds.GetRedisClient(); redisClient != nil {
+		redisClient.Del(context.Background(), loginFailuresKey(userID))
+	}
+}
+
+// notifyLockout mengirim email lockout_notice ke pemilik akun, kegagalan kirim hanya dicatat
+func notifyLockout(referenceID string, conn *sqlx.DB, userID int64, unlockTime time.Time) {
+	var user struct {
+		Username string `db:"username"`
+		Email    string `db:"email"`
+		FullName string `db:"full_name"`
+	}
+	if err := conn.Get(&user, `SELECT username, email, full_name FROM sysuser."user" WHERE id = $1`, userID); err != nil {
+		logger.Warning(referenceID, "WARNING - Login Lockout - Failed to load user for lockout notice: ", err)
+		return
+	}
+
+	_, err := mail.EnqueueTemplate(user.Email, userLocale(conn, userID), mail.TemplateLockoutNotice, map[string]any{
+		"FullName":   user.FullName,
+		"Username":   user.Username,
+		"UnlockTime": unlockTime.Format(time.RFC1123),
+		"ResetURL":   fmt.Sprintf("%s/reset-password", configs.GetClientURL()),
+	})
+	if err != nil {
+		logger.Warning(referenceID, "WARNING - Login Lockout - Failed to queue lockout notice: ", err)
+	}
+}
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/mail"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// !NOTE : magic link login
+/*	1. Client kirim email ke /magic-link, server mengirim link sekali pakai <clientURL>/magic-link/<token>
+2. Token hanya disimpan sebagai SHA-256 di Redis dengan TTL magicLinkExpireTime
+3. Halaman frontend mengirim token ke /magic-link/consume, sesi dibuat seperti Verify_Token
+   (user dengan TOTP aktif tetap diminta Verify_TOTP)
+4. Bila request awal mengirim "same_browser": true, server memasang cookie nonce dan consume
+   hanya berhasil dari browser yang sama (fetch dengan credentials: "include")
+*/
+
+const magicLinkCookieName = "magic_link_nonce"
+
+type magicLinkState struct {
+	UserID    int64  `json:"user_id"`
+	NonceHash string `json:"nonce_hash,omitempty"`
+}
+
+func magicLinkKey(token string) string {
+	return "magic_link:" + crypto.HashSHA256(token)
+}
+
+/*
+{
+	"email" : "master@gmail.com",
+	"same_browser" : true	// opsional
+}
+*/
+
+func Magic_Link_Request(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Magic_Link_Request - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	email, _ := param["email"].(string)
+	email = strings.TrimSpace(email)
+	if email == "" {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - Missing email")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+	sameBrowser, _ := param["same_browser"].(bool)
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if ttl, err := utils.SendMailLimiter(redisClient, referenceID, email, "Magic Link", time.Duration(configs.GetOTPExpireTime())*time.Second); err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - ", err)
+		result.ErrorCode = "429001"
+		result.ErrorMessage = fmt.Sprintf("%s. Please try again in %d seconds", err.Error(), int(ttl.Seconds()))
+		result.Payload["remaining_time"] = int(ttl.Seconds())
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var userID int64
+	var fullName string
+	var locale string
+	queryGetUser := `SELECT id, full_name, COALESCE(data->>'locale', '') FROM sysuser.user WHERE email = $1`
+	err = conn.QueryRow(queryGetUser, email).Scan(&userID, &fullName, &locale)
+	if err == sql.ErrNoRows && configs.GetEnumerationProtection() {
+		// response dibuat sama persis dengan email yang terdaftar
+		logger.Warning(referenceID, "WARNING - Magic_Link_Request - Link requested for unknown account")
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+		result.Payload["status"] = "success"
+		utils.Response(w, result)
+		return
+	} else if err == sql.ErrNoRows {
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - Query failed: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	token, err := utils.SecureTokenGenerator(resetTokenBytes)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - Failed to generate token: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	expiry := time.Duration(configs.GetMagicLinkExpireTime()) * time.Second
+	state := magicLinkState{UserID: userID}
+
+	var nonce string
+	if sameBrowser {
+		nonce, err = utils.SecureTokenGenerator(resetTokenBytes)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Magic_Link_Request - Failed to generate browser nonce: ", err)
+			result.ErrorCode = "500005"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+		state.NonceHash = crypto.HashSHA256(nonce)
+	}
+
+	value, _ := json.Marshal(state)
+	if err := redisClient.Set(context.Background(), magicLinkKey(token), value, expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - Failed to store token: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	messageID, err := mail.EnqueueTemplate(email, locale, mail.TemplateMagicLink, map[string]any{
+		"FullName":      fullName,
+		"URL":           fmt.Sprintf("%s/magic-link/%s", configs.GetClientURL(), token),
+		"ExpireMinutes": int(expiry.Minutes()),
+	})
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Request - Failed to queue email: ", err)
+		redisClient.Del(context.Background(), magicLinkKey(token))
+		result.ErrorCode = "500007"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	logger.Info(referenceID, "INFO - Magic_Link_Request - Magic link email queued, message id: ", messageID)
+
+	if sameBrowser {
+		http.SetCookie(w, &http.Cookie{
+			Name:     magicLinkCookieName,
+			Value:    nonce,
+			Path:     "/magic-link",
+			MaxAge:   int(expiry.Seconds()),
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(configs.GetClientURL(), "https://"),
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	if configs.GetEnumerationProtection() {
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+	}
+
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
+
+/*
+{
+	"token" : "<dari URL magic link>"
+}
+*/
+
+func Magic_Link_Consume(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Magic_Link_Consume - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	token, ok := param["token"].(string)
+	if !ok || token == "" {
+		logger.Error(referenceID, "ERROR - Magic_Link_Consume - Missing token")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Consume - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	ctx := context.Background()
+	key := magicLinkKey(token)
+
+	value, err := redisClient.Get(ctx, key).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Consume - Link not found, used or expired: ", err)
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	var state magicLinkState
+	if err := json.Unmarshal([]byte(value), &state); err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Consume - Invalid link data: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// link yang terikat browser tidak dihapus bila dibuka dari browser lain,
+	// supaya link yang diteruskan tidak menghabiskan link milik pemilik
+	if state.NonceHash != "" {
+		cookie, err := r.Cookie(magicLinkCookieName)
+		if err != nil || subtle.ConstantTimeCompare([]byte(crypto.HashSHA256(cookie.Value)), []byte(state.NonceHash)) != 1 {
+			logger.Error(referenceID, "ERROR - Magic_Link_Consume - Browser binding mismatch")
+			result.ErrorCode = "401002"
+			result.ErrorMessage = "Please open the link in the browser where you requested it"
+			utils.Response(w, result)
+			return
+		}
+	}
+
+	// link hanya boleh dipakai sekali
+	if deleted, _ := redisClient.Del(ctx, key).Result(); deleted == 0 {
+		logger.Error(referenceID, "ERROR - Magic_Link_Consume - Link already consumed")
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if state.NonceHash != "" {
+		http.SetCookie(w, &http.Cookie{Name: magicLinkCookieName, Value: "", Path: "/magic-link", MaxAge: -1})
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Magic_Link_Consume - Failed to get DB connection: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if !completeLogin(referenceID, "Magic_Link_Consume", r, conn, state.UserID, token, &result) {
+		utils.Response(w, result)
+		return
+	}
+
+	utils.Response(w, result)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"auth_service/logger"
+	"auth_service/mail"
+	"auth_service/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// batas jumlah job dead-letter yang dikembalikan Mail_Dead_List
+const mailDeadListMax = 500
+
+// Mail_Status mengembalikan status pengiriman satu pesan, ?id=<message_id>
+func Mail_Status(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Mail_Status - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		logger.Error(referenceID, "ERROR - Mail_Status - Missing id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	status, err := mail.Status(id)
+	if errors.Is(err, mail.ErrJobNotFound) {
+		logger.Error(referenceID, "ERROR - Mail_Status - Message not found: ", id)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Mail_Status - Failed to load status: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["message"] = status
+	utils.Response(w, result)
+}
+
+// Mail_Dead_List mengembalikan pesan di dead-letter, ?limit=<n> (default 100)
+func Mail_Dead_List(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Mail_Dead_List - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	limit := 100
+	if text := r.URL.Query().Get("limit"); text != "" {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed <= 0 || parsed > mailDeadListMax {
+			logger.Error(referenceID, "ERROR - Mail_Dead_List - Invalid limit: ", text)
+			result.ErrorCode = "400001"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		limit = parsed
+	}
+
+	messages, err := mail.DeadList(int64(limit))
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Mail_Dead_List - Failed to load dead-letter: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["messages"] = messages
+	utils.Response(w, result)
+}
+
+/*
+{
+	"id" : "<message_id>"
+}
+*/
+
+func Mail_Dead_Requeue(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Mail_Dead_Requeue - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	id, ok := param["id"].(string)
+	if !ok || id == "" {
+		logger.Error(referenceID, "ERROR - Mail_Dead_Requeue - Missing id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	err := mail.RequeueDead(id)
+	if errors.Is(err, mail.ErrJobNotFound) {
+		logger.Error(referenceID, "ERROR - Mail_Dead_Requeue - Message not in dead-letter: ", id)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Mail_Dead_Requeue - Failed to requeue message: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Mail_Dead_Requeue - Message requeued: ", id)
+	result.Payload["id"] = id
+	result.Payload["status"] = mail.StatusQueued
+	utils.Response(w, result)
+}
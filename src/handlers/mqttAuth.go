@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/mqtt"
+	"auth_service/rds"
+	"auth_service/utils"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+/*	Hook broker MQTT (lihat package mqtt untuk protokol dan skema topic).
+	Dua jenis client:
+	- unit    : username unit-<id>, password secret device.unit (sama dengan secret untuk Device_Login)
+	- user    : username email, password API key dengan scope device:read dan / atau device:control,
+	            client id ak_<prefix> atau ak_<prefix>-<bebas> supaya ACL tahu key mana yang dipakai
+	ACL:
+	- unit publish ke <prefix>/<id>/... selain command, subscribe hanya ke <prefix>/<id>/command/...
+	- user subscribe ke unit yang di-grant (role apa pun, atau role admin) dengan scope device:read,
+	  publish hanya ke <prefix>/<id>/command/... dengan role operator atau owner dan scope device:control
+*/
+
+// mqttClientKeyPrefix mengambil prefix API key dari client id ak_<prefix> atau ak_<prefix>-<suffix>
+func mqttClientKeyPrefix(clientID string) (string, bool) {
+	rest, found := strings.CutPrefix(clientID, apiKeyTag)
+	if !found || len(rest) < apiKeyPrefixBytes*2 {
+		return "", false
+	}
+	prefix, suffix := rest[:apiKeyPrefixBytes*2], rest[apiKeyPrefixBytes*2:]
+	if suffix != "" && suffix[0] != '-' {
+		return "", false
+	}
+	if _, err := hex.DecodeString(prefix); err != nil {
+		return "", false
+	}
+	return prefix, true
+}
+
+// mqttAuthUnit memverifikasi secret unit dengan menghitung ulang salted_password
+func mqttAuthUnit(referenceID string, unitID int64, password, clientID string) bool {
+	// client id lain bisa menendang sesi unit yang sedang terhubung, jadi harus sama dengan username
+	if clientID != "" && clientID != mqtt.DeviceUsername(unitID) {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Client id mismatch for unit ", unitID, ": ", clientID)
+		return false
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Failed to get DB connection: ", err)
+		return false
+	}
+
+	var unit deviceCred
+	queryGetUnit := `SELECT id, salt, salted_password FROM device.unit WHERE id = $1 AND status = $2`
+	if err := conn.Get(&unit, queryGetUnit, unitID, deviceStatusActive); err != nil {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Unit not found or disabled: ", unitID)
+		return false
+	}
+
+	saltedPassword, err := crypto.GeneratePBKDF2(password, unit.Salt, 32, configs.GetPBKDF2Iterations())
+	if err != nil {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Failed to derive secret: ", err)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(saltedPassword), []byte(unit.SaltedPassword)) != 1 {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Invalid secret for unit: ", unitID)
+		return false
+	}
+	return true
+}
+
+// mqttAuthAPIKey memverifikasi API key milik user dengan email tersebut, client id harus milik key yang sama
+func mqttAuthAPIKey(referenceID, email, key, clientID string) bool {
+	prefix, ok := ParseAPIKey(key)
+	if !ok {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Password is not an API key")
+		return false
+	}
+	if clientPrefix, ok := mqttClientKeyPrefix(clientID); !ok || clientPrefix != prefix {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Client id does not match API key ", prefix, ": ", clientID)
+		return false
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Failed to get DB connection: ", err)
+		return false
+	}
+
+	var stored struct {
+		KeyHash string `db:"key_hash"`
+		Scopes  string `db:"scopes"`
+	}
+	queryKey := `
+		SELECT k.key_hash, k.scopes
+		FROM sysuser.api_key k
+		JOIN sysuser."user" u ON u.id = k.user_id AND u.st = 1
+		WHERE k.prefix = $1 AND u.email = $2 AND k.revoked_tstamp IS NULL AND (k.expire_tstamp IS NULL OR k.expire_tstamp > $3)`
+	if err := conn.Get(&stored, queryKey, prefix, strings.ToLower(email), time.Now().Unix()); err != nil {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - API key not found or inactive: ", prefix)
+		return false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(crypto.HashSHA256(key))) != 1 {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - API key hash mismatch: ", prefix)
+		return false
+	}
+
+	if !hasScope(stored.Scopes, "device:read") && !hasScope(stored.Scopes, "device:control") {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - API key lacks scope device:read or device:control: ", prefix)
+		return false
+	}
+	return true
+}
+
+// mqttClientIP mengambil IP client MQTT dari parameter hook (EMQX ${peerhost}, parameter ipaddr)
+func mqttClientIP(params map[string]string) string {
+	for _, name := range []string{"peerhost", "ipaddr"} {
+		if ip := net.ParseIP(params[name]); ip != nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+/*
+username=unit-12&password=<secret>&clientid=unit-12&peerhost=203.0.113.7
+*/
+
+func MQTT_Auth_User(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - MQTT_Auth_User - Execution completed in ", duration)
+	}()
+
+	if !mqtt.CheckHookSecret(r) {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Invalid hook secret")
+		mqtt.Respond(w, false, "forbidden")
+		return
+	}
+
+	params, err := mqtt.ReadParams(r)
+	if err != nil || params["username"] == "" || params["password"] == "" {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_User - Missing credentials: ", err)
+		mqtt.Respond(w, false, "invalid request")
+		return
+	}
+	username := params["username"]
+
+	// batasi tebakan password per username dan per IP client (bila broker mengirim peerhost / ipaddr),
+	// IP request sendiri adalah IP broker sehingga tidak dipakai
+	window := time.Duration(configs.GetMQTTAuthRateWindow()) * time.Second
+	limitKeys := []string{"mqtt_auth_user:" + strings.ToLower(username)}
+	if clientIP := mqttClientIP(params); clientIP != "" {
+		limitKeys = append(limitKeys, "mqtt_auth_ip:"+clientIP)
+	}
+	for _, key := range limitKeys {
+		if _, err := utils.ClientRateLimiter(rds.GetRedisClient(), referenceID, key, configs.GetMQTTAuthRateLimit(), window); err != nil {
+			logger.Error(referenceID, "ERROR - MQTT_Auth_User - Rate limited: ", key)
+			mqtt.Respond(w, false, "too many attempts")
+			return
+		}
+	}
+
+	var allowed bool
+	if unitID, ok := mqtt.ParseDeviceUsername(username); ok {
+		allowed = mqttAuthUnit(referenceID, unitID, params["password"], params["clientid"])
+	} else {
+		allowed = mqttAuthAPIKey(referenceID, username, params["password"], params["clientid"])
+	}
+
+	if !allowed {
+		mqtt.Respond(w, false, "invalid credentials")
+		return
+	}
+
+	logger.Info(referenceID, "INFO - MQTT_Auth_User - Client authenticated: ", username)
+	mqtt.Respond(w, true, "")
+}
+
+// MQTT_Auth_Superuser selalu menolak, semua keputusan publish/subscribe lewat MQTT_Auth_ACL
+func MQTT_Auth_Superuser(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	if !mqtt.CheckHookSecret(r) {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_Superuser - Invalid hook secret")
+	}
+	mqtt.Respond(w, false, "not a superuser")
+}
+
+/*
+username=unit-12&clientid=unit-12&topic=device/12/telemetry&acc=2
+*/
+
+func MQTT_Auth_ACL(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - MQTT_Auth_ACL - Execution completed in ", duration)
+	}()
+
+	if !mqtt.CheckHookSecret(r) {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Invalid hook secret")
+		mqtt.Respond(w, false, "forbidden")
+		return
+	}
+
+	params, err := mqtt.ReadParams(r)
+	if err != nil || params["username"] == "" {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Missing username: ", err)
+		mqtt.Respond(w, false, "invalid request")
+		return
+	}
+	username := params["username"]
+	topic := params["topic"]
+
+	access, ok := mqtt.ParseAccess(params)
+	if !ok {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Invalid access: ", params["acc"], params["action"])
+		mqtt.Respond(w, false, "invalid request")
+		return
+	}
+
+	unitID, subtopic, ok := mqtt.ParseTopic(topic)
+	if !ok {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Topic outside device namespace: ", topic)
+		mqtt.Respond(w, false, "topic not allowed")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Failed to get DB connection: ", err)
+		mqtt.Respond(w, false, "internal error")
+		return
+	}
+
+	allowed := false
+	if clientUnitID, isUnit := mqtt.ParseDeviceUsername(username); isUnit {
+		if clientUnitID == unitID {
+			// unit yang dinonaktifkan setelah connect langsung kehilangan akses
+			var active bool
+			if err := conn.Get(&active, `SELECT EXISTS (SELECT 1 FROM device.unit WHERE id = $1 AND status = $2)`, unitID, deviceStatusActive); err != nil {
+				logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Failed to load unit: ", err)
+				mqtt.Respond(w, false, "internal error")
+				return
+			}
+			allowed = mqtt.UnitAllowed(clientUnitID, unitID, active, access, subtopic)
+		}
+	} else {
+		// scope diambil dari key yang dipakai saat connect (client id ak_<prefix>), key yang dicabut langsung kehilangan akses
+		prefix, ok := mqttClientKeyPrefix(params["clientid"])
+		if !ok {
+			logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Client id is not bound to an API key: ", params["clientid"])
+			mqtt.Respond(w, false, "topic not allowed")
+			return
+		}
+
+		var grant struct {
+			IsAdmin bool   `db:"is_admin"`
+			Role    string `db:"role"`
+			Scopes  string `db:"scopes"`
+		}
+		queryGrant := `
+			SELECT u.role = 'admin' AS is_admin, COALESCE(g.role, '') AS role, k.scopes
+			FROM sysuser."user" u
+			JOIN sysuser.api_key k ON k.user_id = u.id AND k.prefix = $3 AND k.revoked_tstamp IS NULL AND (k.expire_tstamp IS NULL OR k.expire_tstamp > $4)
+			LEFT JOIN device.unit_grant g ON g.user_id = u.id AND g.unit_id = $2
+			WHERE u.email = $1 AND u.st = 1`
+		if err := conn.Get(&grant, queryGrant, strings.ToLower(username), unitID, prefix, time.Now().Unix()); err != nil {
+			logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - User or API key not found or inactive: ", username)
+			mqtt.Respond(w, false, "topic not allowed")
+			return
+		}
+		canRead := (grant.Role != "" || grant.IsAdmin) && hasScope(grant.Scopes, "device:read")
+		canControl := (grant.Role == unitRoleOperator || grant.Role == unitRoleOwner) && hasScope(grant.Scopes, "device:control")
+		allowed = mqtt.UserAllowed(canRead, canControl, access, subtopic)
+	}
+
+	if !allowed {
+		logger.Error(referenceID, "ERROR - MQTT_Auth_ACL - Access denied for ", username, " on topic ", topic, " (access ", int(access), ")")
+		mqtt.Respond(w, false, "topic not allowed")
+		return
+	}
+
+	mqtt.Respond(w, true, "")
+}
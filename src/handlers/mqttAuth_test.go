@@ -0,0 +1,35 @@
+package handlers
+
+import "testing"
+
+func TestMQTTClientKeyPrefix(t *testing.T) {
+	const prefix = "0123456789abcdef"
+
+	tests := []struct {
+		name       string
+		clientID   string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{"prefix only", apiKeyTag + prefix, prefix, true},
+		{"prefix with suffix", apiKeyTag + prefix + "-dashboard-1", prefix, true},
+		{"prefix with empty suffix", apiKeyTag + prefix + "-", prefix, true},
+		{"suffix without dash", apiKeyTag + prefix + "x", "", false},
+		{"longer hex prefix", apiKeyTag + prefix + "00", "", false},
+		{"short prefix", apiKeyTag + prefix[:8], "", false},
+		{"non hex prefix", apiKeyTag + "0123456789abcdeg", "", false},
+		{"full api key", apiKeyTag + prefix + "_secret", "", false},
+		{"missing tag", prefix, "", false},
+		{"unit client id", "unit-12", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, ok := mqttClientKeyPrefix(tt.clientID)
+			if ok != tt.wantOK || prefix != tt.wantPrefix {
+				t.Errorf("mqttClientKeyPrefix(%q) = (%q, %v), want (%q, %v)", tt.clientID, prefix, ok, tt.wantPrefix, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/logger"
+	"auth_service/utils"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// !NOTE : OAuth 2.0 authorization server (authorization code + PKCE)
+/*	1. App mengarahkan browser ke /oauth/authorize?response_type=code&client_id=..&redirect_uri=..
+	   &scope=..&state=..&code_challenge=..&code_challenge_method=S256
+2. Server menyimpan request di Redis lalu redirect ke halaman consent frontend (clientURL + oauthConsentPath?request_id=..)
+3. Frontend (sudah login, punya sesi) memanggil /oauth/authorize/details untuk menampilkan consent,
+   lalu /oauth/authorize/decision. Response berisi redirect_to (redirect_uri + code + state)
+4. App menukar code di /oauth/token (grant authorization_code + code_verifier), mendapat access_token + refresh_token
+5. Resource server memeriksa token lewat /oauth/introspect (RFC 7662), token dicabut lewat /oauth/revoke (RFC 7009)
+
+Endpoint /oauth/token, /oauth/revoke dan /oauth/introspect memakai format OAuth (form-urlencoded request,
+JSON response RFC 6749), bukan ResultFormat.
+*/
+
+/*
+CREATE TABLE sysuser.oauth_client (
+	client_id character varying(64) NOT NULL,
+	client_secret_hash character varying(64) NULL, -- SHA-256 hex, NULL untuk public client (SPA / mobile)
+	name character varying(128) NOT NULL,
+	redirect_uris text NOT NULL,                    -- dipisah spasi, dicocokkan persis
+	scopes character varying(512) NOT NULL,         -- scope yang boleh diminta, dipisah spasi
+	is_public boolean NOT NULL DEFAULT false,
+	skip_consent boolean NOT NULL DEFAULT false,    -- aplikasi internal tanpa layar consent
+	create_tstamp int8 NOT NULL,
+	st int4 NOT NULL DEFAULT 1,
+	CONSTRAINT oauth_client_pkey PRIMARY KEY (client_id)
+);
+
+CREATE TABLE sysuser.oauth_token (
+	token_hash character varying(64) NOT NULL,      -- SHA-256 hex
+	token_type character varying(16) NOT NULL,      -- access_token | refresh_token
+	client_id character varying(64) NOT NULL,
+	user_id int8 NULL,
+	scope character varying(512) NOT NULL,
+	grant_id character varying(64) NOT NULL,        -- sama untuk semua token dari satu otorisasi
+	expire_tstamp int8 NOT NULL,
+	revoked boolean NOT NULL DEFAULT false,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT oauth_token_pkey PRIMARY KEY (token_hash),
+	CONSTRAINT oauth_token_client_fkey FOREIGN KEY (client_id) REFERENCES sysuser.oauth_client(client_id) ON DELETE CASCADE,
+	CONSTRAINT oauth_token_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX oauth_token_grant_idx ON sysuser.oauth_token (grant_id);
+
+-- kolom tambahan untuk OIDC (oidc.go) dan client_credentials (oauthClientCredentials.go)
+ALTER TABLE sysuser.oauth_token ADD session_id character varying(16) NULL; -- sesi login asal otorisasi
+CREATE INDEX oauth_token_session_idx ON sysuser.oauth_token (session_id);
+ALTER TABLE sysuser.oauth_client ADD post_logout_redirect_uris text NULL; -- dipisah spasi, dicocokkan persis
+ALTER TABLE sysuser.oauth_client ADD grant_types character varying(128) NOT NULL DEFAULT 'authorization_code refresh_token';
+ALTER TABLE sysuser.oauth_client ADD rate_limit int4 NULL; -- request per window, NULL = default config
+
+CREATE TABLE sysuser.oauth_consent (
+	user_id int8 NOT NULL,
+	client_id character varying(64) NOT NULL,
+	scope character varying(512) NOT NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT oauth_consent_pkey PRIMARY KEY (user_id, client_id),
+	CONSTRAINT oauth_consent_client_fkey FOREIGN KEY (client_id) REFERENCES sysuser.oauth_client(client_id) ON DELETE CASCADE,
+	CONSTRAINT oauth_consent_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+*/
+
+const (
+	oauthTokenTypeAccess  = "access_token"
+	oauthTokenTypeRefresh = "refresh_token"
+
+	oauthTokenBytes = 32
+)
+
+var errInvalidClient = errors.New("invalid client credentials")
+
+type oauthClient struct {
+	ClientID         string         `db:"client_id"`
+	ClientSecretHash sql.NullString `db:"client_secret_hash"`
+	Name             string         `db:"name"`
+	RedirectURIs     string         `db:"redirect_uris"`
+	Scopes           string         `db:"scopes"`
+	IsPublic         bool           `db:"is_public"`
+	SkipConsent      bool           `db:"skip_consent"`
+	PostLogoutURIs   sql.NullString `db:"post_logout_redirect_uris"`
+	GrantTypes       string         `db:"grant_types"`
+	RateLimit        sql.NullInt64  `db:"rate_limit"`
+	CreateTstamp     int64          `db:"create_tstamp"`
+	St               int            `db:"st"`
+}
+
+type oauthToken struct {
+	TokenHash    string         `db:"token_hash"`
+	TokenType    string         `db:"token_type"`
+	ClientID     string         `db:"client_id"`
+	UserID       sql.NullInt64  `db:"user_id"`
+	SessionID    sql.NullString `db:"session_id"`
+	Scope        string         `db:"scope"`
+	GrantID      string         `db:"grant_id"`
+	ExpireTstamp int64          `db:"expire_tstamp"`
+	Revoked      bool           `db:"revoked"`
+	CreateTstamp int64          `db:"create_tstamp"`
+}
+
+func getOAuthClient(conn *sqlx.DB, clientID string) (*oauthClient, error) {
+	var client oauthClient
+	if err := conn.Get(&client, `SELECT * FROM sysuser.oauth_client WHERE client_id = $1 AND st = 1`, clientID); err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// resolveRedirectURI mencocokkan redirect_uri persis dengan yang terdaftar.
+// Bila kosong dan client hanya punya satu redirect_uri, yang terdaftar dipakai.
+func (c *oauthClient) resolveRedirectURI(redirectURI string) (string, bool) {
+	registered := strings.Fields(c.RedirectURIs)
+	if redirectURI == "" {
+		if len(registered) == 1 {
+			return registered[0], true
+		}
+		return "", false
+	}
+	for _, uri := range registered {
+		if uri == redirectURI {
+			return uri, true
+		}
+	}
+	return "", false
+}
+
+// resolveScope memastikan semua scope yang diminta diizinkan untuk client.
+// Scope kosong berarti semua scope client.
+func (c *oauthClient) resolveScope(requested string) (string, bool) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(strings.Fields(c.Scopes), " "), true
+	}
+	if !scopeSubset(requested, c.Scopes) {
+		return "", false
+	}
+	return strings.Join(strings.Fields(requested), " "), true
+}
+
+// scopeSubset mengecek semua scope di requested ada di allowed
+func scopeSubset(requested, allowed string) bool {
+	allowedSet := make(map[string]bool)
+	for _, scope := range strings.Fields(allowed) {
+		allowedSet[scope] = true
+	}
+	for _, scope := range strings.Fields(requested) {
+		if !allowedSet[scope] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyPKCE memeriksa code_verifier terhadap code_challenge (RFC 7636, hanya S256)
+func verifyPKCE(verifier, challenge, method string) bool {
+	if method != "S256" || len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// authenticateOAuthClient membaca kredensial client dari header Basic atau body form
+// (client_secret_basic / client_secret_post). Public client cukup mengirim client_id.
+func authenticateOAuthClient(r *http.Request, conn *sqlx.DB) (*oauthClient, error) {
+	clientID, clientSecret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 §2.3.1: kredensial di-form-encode sebelum base64
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = r.PostFormValue("client_id")
+		clientSecret = r.PostFormValue("client_secret")
+	}
+	if clientID == "" {
+		return nil, errInvalidClient
+	}
+
+	client, err := getOAuthClient(conn, clientID)
+	if err == sql.ErrNoRows {
+		return nil, errInvalidClient
+	} else if err != nil {
+		return nil, err
+	}
+
+	if client.IsPublic {
+		if clientSecret != "" {
+			return nil, errInvalidClient
+		}
+		return client, nil
+	}
+
+	if clientSecret == "" || !client.ClientSecretHash.Valid {
+		return nil, errInvalidClient
+	}
+	secretHash := crypto.HashSHA256(clientSecret)
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(client.ClientSecretHash.String)) == 1 {
+		return client, nil
+	}
+
+	// secret lama masih berlaku selama masa overlap setelah rotasi
+	valid, err := previousClientSecretValid(conn, client.ClientID, secretHash)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, errInvalidClient
+	}
+	return client, nil
+}
+
+// rateLimit mengembalikan batas request per window untuk client
+func (c *oauthClient) rateLimit() int64 {
+	if c.RateLimit.Valid && c.RateLimit.Int64 > 0 {
+		return c.RateLimit.Int64
+	}
+	return configs.GetOAuthClientRateLimit()
+}
+
+// oauthResponse menulis JSON dengan format OAuth (RFC 6749 §5.1), token tidak boleh di-cache
+func oauthResponse(w http.ResponseWriter, status int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+
+	jsonString, err := utils.JSONencode(body)
+	if err != nil {
+		logger.Error("Unknown", "ERROR - OAuth response encoding failed: ", err)
+		return
+	}
+	if _, err := w.Write([]byte(jsonString)); err != nil {
+		logger.Error("Unknown", "ERROR - Failed to write OAuth response: ", err)
+	}
+}
+
+// oauthError menulis error response RFC 6749 §5.2
+func oauthError(w http.ResponseWriter, status int, code, description string) {
+	body := map[string]any{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	oauthResponse(w, status, body)
+}
+
+// oauthClientError menulis invalid_client, dengan WWW-Authenticate bila client memakai Basic
+func oauthClientError(w http.ResponseWriter, r *http.Request) {
+	if _, _, basic := r.BasicAuth(); basic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	oauthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+}
+
+// appendQuery menambahkan parameter ke redirect_uri tanpa menghapus query yang sudah ada
+func appendQuery(rawURL string, values map[string]string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for key, value := range values {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// oauthGrant adalah pemilik token yang akan diterbitkan
+type oauthGrant struct {
+	ClientID  string
+	UserID    sql.NullInt64
+	SessionID sql.NullString // sesi login asal otorisasi, dipakai logout OIDC
+	Scope     string
+	GrantID   string
+}
+
+// issueOAuthTokens menerbitkan access token (dan refresh token bila withRefresh) untuk satu grant.
+// Token hanya disimpan sebagai hash, nilai asli dikembalikan sekali di response.
+func issueOAuthTokens(conn sqlx.Execer, grant oauthGrant, withRefresh bool) (map[string]any, error) {
+	now := time.Now().Unix()
+	queryInsert := `
+		INSERT INTO sysuser.oauth_token (token_hash, token_type, client_id, user_id, session_id, scope, grant_id, expire_tstamp, create_tstamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	accessToken, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	accessExpiry := int64(configs.GetOAuthAccessTokenExpireTime())
+	if _, err := conn.Exec(queryInsert, crypto.HashSHA256(accessToken), oauthTokenTypeAccess, grant.ClientID, grant.UserID, grant.SessionID, grant.Scope, grant.GrantID, now+accessExpiry, now); err != nil {
+		return nil, err
+	}
+
+	response := map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   accessExpiry,
+		"scope":        grant.Scope,
+	}
+
+	if withRefresh {
+		refreshToken, err := utils.SecureTokenGenerator(oauthTokenBytes)
+		if err != nil {
+			return nil, err
+		}
+		refreshExpiry := int64(configs.GetOAuthRefreshTokenExpireTime())
+		if _, err := conn.Exec(queryInsert, crypto.HashSHA256(refreshToken), oauthTokenTypeRefresh, grant.ClientID, grant.UserID, grant.SessionID, grant.Scope, grant.GrantID, now+refreshExpiry, now); err != nil {
+			return nil, err
+		}
+		response["refresh_token"] = refreshToken
+	}
+
+	return response, nil
+}
+
+// revokeOAuthGrant mencabut semua token dari satu otorisasi (refresh token reuse, revoke refresh token)
+func revokeOAuthGrant(conn sqlx.Execer, grantID string) (int64, error) {
+	res, err := conn.Exec(`UPDATE sysuser.oauth_token SET revoked = true WHERE grant_id = $1 AND NOT revoked`, grantID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func getOAuthToken(conn *sqlx.DB, token string) (*oauthToken, error) {
+	var stored oauthToken
+	if err := conn.Get(&stored, `SELECT * FROM sysuser.oauth_token WHERE token_hash = $1`, crypto.HashSHA256(token)); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (t *oauthToken) active() bool {
+	return !t.Revoked && t.ExpireTstamp > time.Now().Unix()
+}
+
+// oauthAuthorizeRequest adalah parameter /oauth/authorize yang menunggu consent user
+type oauthAuthorizeRequest struct {
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Nonce               string `json:"nonce,omitempty"`
+}
+
+// oauthAuthorizationCode adalah state authorization code di Redis
+type oauthAuthorizationCode struct {
+	oauthAuthorizeRequest
+	UserID    int64  `json:"user_id"`
+	SessionID string `json:"session_id"`
+	AuthTime  int64  `json:"auth_time"`
+}
+
+func oauthRequestKey(requestID string) string {
+	return "oauth_request:" + requestID
+}
+
+func oauthCodeKey(code string) string {
+	return "oauth_code:" + crypto.HashSHA256(code)
+}
+
+// oauthUsedCodeKey menyimpan grant dari code yang sudah ditukar, code yang dipakai ulang mencabut grant tersebut
+func oauthUsedCodeKey(code string) string {
+	return "oauth_code_used:" + crypto.HashSHA256(code)
+}
+
+func marshalOAuthState(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
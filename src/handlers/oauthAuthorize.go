@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/oidc"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OAuth_Authorize adalah authorization endpoint (RFC 6749 §4.1.1) dengan PKCE wajib.
+// Client / redirect_uri yang tidak valid tidak di-redirect (dijawab 400),
+// error lain dikirim ke redirect_uri sesuai spesifikasi.
+func OAuth_Authorize(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Authorize - Execution completed in ", duration)
+	}()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		oauthError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+		return
+	}
+
+	clientID := r.FormValue("client_id")
+	if clientID == "" {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - Missing client_id")
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Missing client_id")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	client, err := getOAuthClient(conn, clientID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - Unknown client: ", err)
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Unknown client_id")
+		return
+	}
+
+	redirectURI, ok := client.resolveRedirectURI(r.FormValue("redirect_uri"))
+	if !ok {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - Redirect URI is not registered")
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Invalid redirect_uri")
+		return
+	}
+
+	state := r.FormValue("state")
+	redirectError := func(code, description string) {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - ", code, ": ", description)
+		http.Redirect(w, r, appendQuery(redirectURI, map[string]string{
+			"error":             code,
+			"error_description": description,
+			"state":             state,
+			"iss":               oidc.Issuer(),
+		}), http.StatusFound)
+	}
+
+	if r.FormValue("response_type") != "code" {
+		redirectError("unsupported_response_type", "Only response_type=code is supported")
+		return
+	}
+	if !hasScope(client.GrantTypes, "authorization_code") {
+		redirectError("unauthorized_client", "The client is not allowed to use the authorization code flow")
+		return
+	}
+
+	scope, ok := client.resolveScope(r.FormValue("scope"))
+	if !ok {
+		redirectError("invalid_scope", "Requested scope is not allowed for this client")
+		return
+	}
+
+	codeChallenge := r.FormValue("code_challenge")
+	codeChallengeMethod := r.FormValue("code_challenge_method")
+	if codeChallenge == "" {
+		redirectError("invalid_request", "PKCE code_challenge is required")
+		return
+	}
+	if codeChallengeMethod != "S256" {
+		redirectError("invalid_request", "Only code_challenge_method=S256 is supported")
+		return
+	}
+	if len(codeChallenge) != 43 {
+		redirectError("invalid_request", "Invalid code_challenge")
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - Redis client is not initialized")
+		redirectError("server_error", "")
+		return
+	}
+
+	requestID, err := utils.SecureTokenGenerator(24)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - Failed to generate request id: ", err)
+		redirectError("server_error", "")
+		return
+	}
+
+	authorizeRequest := oauthAuthorizeRequest{
+		ClientID:            client.ClientID,
+		RedirectURI:         redirectURI,
+		Scope:               scope,
+		State:               state,
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		Nonce:               r.FormValue("nonce"),
+	}
+	expiry := time.Duration(configs.GetOAuthAuthorizeRequestExpireTime()) * time.Second
+	if err := redisClient.Set(context.Background(), oauthRequestKey(requestID), marshalOAuthState(authorizeRequest), expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize - Failed to store authorization request: ", err)
+		redirectError("server_error", "")
+		return
+	}
+
+	// frontend menangani login (bila belum) dan layar consent
+	consentURL := appendQuery(configs.GetClientURL()+configs.GetOAuthConsentPath(), map[string]string{"request_id": requestID})
+	http.Redirect(w, r, consentURL, http.StatusFound)
+}
+
+// loadAuthorizeRequest membaca request_id dari query (GET) atau body JSON (POST)
+func loadAuthorizeRequest(r *http.Request) (string, map[string]any) {
+	requestID := r.URL.Query().Get("request_id")
+	var param map[string]any
+	if r.Method == http.MethodPost {
+		param, _ = utils.Request(r)
+		if requestID == "" {
+			requestID, _ = param["request_id"].(string)
+		}
+	}
+	return requestID, param
+}
+
+// consentGranted mengecek apakah user sudah pernah menyetujui semua scope untuk client ini
+func consentGranted(conn *sqlx.DB, userID int64, clientID, scope string) (bool, error) {
+	var grantedScope string
+	err := conn.Get(&grantedScope, `SELECT scope FROM sysuser.oauth_consent WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return scopeSubset(scope, grantedScope), nil
+}
+
+// OAuth_Authorize_Details dipanggil halaman consent untuk menampilkan client dan scope yang diminta
+func OAuth_Authorize_Details(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Authorize_Details - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	requestID, _ := loadAuthorizeRequest(r)
+	if requestID == "" {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Missing request_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	value, err := redisClient.Get(context.Background(), oauthRequestKey(requestID)).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Authorization request not found or expired: ", err)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Authorization request not found or expired"
+		utils.Response(w, result)
+		return
+	}
+
+	var authorizeRequest oauthAuthorizeRequest
+	if err := json.Unmarshal([]byte(value), &authorizeRequest); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Invalid authorization request data: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Failed to get DB connection: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	client, err := getOAuthClient(conn, authorizeRequest.ClientID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Client no longer available: ", err)
+		result.ErrorCode = "404002"
+		result.ErrorMessage = "Client not found"
+		utils.Response(w, result)
+		return
+	}
+
+	granted, err := consentGranted(conn, userID, client.ClientID, authorizeRequest.Scope)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Details - Consent lookup failed: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// consent_required false: frontend boleh langsung mengirim decision tanpa menampilkan layar consent
+	result.Payload["client_id"] = client.ClientID
+	result.Payload["client_name"] = client.Name
+	result.Payload["redirect_uri"] = authorizeRequest.RedirectURI
+	result.Payload["scopes"] = strings.Fields(authorizeRequest.Scope)
+	result.Payload["consent_required"] = !client.SkipConsent && !granted
+	utils.Response(w, result)
+}
+
+/*
+{
+	"request_id" : "<dari query halaman consent>",
+	"approve" : true
+}
+*/
+
+func OAuth_Authorize_Decision(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Authorize_Decision - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	sessionID, _ := r.Context().Value(HTTPContextKey("sessionID")).(string)
+	if userID == 0 || sessionID == "" {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	requestID, param := loadAuthorizeRequest(r)
+	approve, ok := param["approve"].(bool)
+	if requestID == "" || !ok {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Missing request_id or approve")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// request hanya bisa diputuskan sekali
+	ctx := context.Background()
+	value, err := redisClient.GetDel(ctx, oauthRequestKey(requestID)).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Authorization request not found or expired: ", err)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Authorization request not found or expired"
+		utils.Response(w, result)
+		return
+	}
+
+	var authorizeRequest oauthAuthorizeRequest
+	if err := json.Unmarshal([]byte(value), &authorizeRequest); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Invalid authorization request data: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if !approve {
+		logger.Info(referenceID, "INFO - OAuth_Authorize_Decision - User denied access for client: ", authorizeRequest.ClientID)
+		result.Payload["redirect_to"] = appendQuery(authorizeRequest.RedirectURI, map[string]string{
+			"error":             "access_denied",
+			"error_description": "The user denied the request",
+			"state":             authorizeRequest.State,
+			"iss":               oidc.Issuer(),
+		})
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Failed to get DB connection: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var authTime int64
+	if err := conn.Get(&authTime, `SELECT tstamp FROM sysuser.session WHERE session_id = $1`, sessionID); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Session lookup failed: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// consent disimpan (scope digabung) supaya otorisasi berikutnya tidak perlu ditanya lagi
+	queryUpsertConsent := `
+		INSERT INTO sysuser.oauth_consent (user_id, client_id, scope, create_tstamp)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET
+			scope = (
+				SELECT string_agg(DISTINCT s, ' ')
+				FROM unnest(string_to_array(sysuser.oauth_consent.scope || ' ' || EXCLUDED.scope, ' ')) AS s
+				WHERE s <> ''
+			),
+			create_tstamp = EXCLUDED.create_tstamp`
+	if _, err := conn.Exec(queryUpsertConsent, userID, authorizeRequest.ClientID, authorizeRequest.Scope, time.Now().Unix()); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Failed to store consent: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	code, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Failed to generate code: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	authorizationCode := oauthAuthorizationCode{
+		oauthAuthorizeRequest: authorizeRequest,
+		UserID:                userID,
+		SessionID:             sessionID,
+		AuthTime:              authTime,
+	}
+	expiry := time.Duration(configs.GetOAuthCodeExpireTime()) * time.Second
+	if err := redisClient.Set(ctx, oauthCodeKey(code), marshalOAuthState(authorizationCode), expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Authorize_Decision - Failed to store code: ", err)
+		result.ErrorCode = "500007"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - OAuth_Authorize_Decision - Authorization code issued for client: ", authorizeRequest.ClientID)
+	result.Payload["redirect_to"] = appendQuery(authorizeRequest.RedirectURI, map[string]string{
+		"code":  code,
+		"state": authorizeRequest.State,
+		"iss":   oidc.Issuer(), // RFC 9207, mencegah mix-up attack
+	})
+	utils.Response(w, result)
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Pengelolaan client OAuth, hanya untuk role admin (lihat main.go)
+
+// validRedirectURI: URI absolut tanpa fragment, http hanya untuk localhost (development)
+func validRedirectURI(rawURI string) bool {
+	u, err := url.Parse(rawURI)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" || strings.ContainsAny(rawURI, " \t\n") {
+		return false
+	}
+	if u.Scheme == "http" {
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+	return true
+}
+
+/*
+{
+	"name" : "Dashboard",
+	"redirect_uris" : ["https://dashboard.example.com/callback"],
+	"post_logout_redirect_uris" : ["https://dashboard.example.com/"],
+	"scopes" : "openid profile email",
+	"grant_types" : ["authorization_code", "refresh_token"],
+	"is_public" : false,
+	"skip_consent" : false,
+	"rate_limit" : 600
+}
+*/
+
+func OAuth_Client_Create(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Client_Create - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	name, _ := param["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 128 {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Create - Invalid name")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	isPublic, _ := param["is_public"].(bool)
+	skipConsent, _ := param["skip_consent"].(bool)
+
+	// default client aplikasi (authorization code), machine client cukup ["client_credentials"]
+	grantTypes := []string{"authorization_code", "refresh_token"}
+	if list, ok := param["grant_types"].([]any); ok {
+		grantTypes = nil
+		for _, item := range list {
+			grantType, _ := item.(string)
+			if !oauthGrantTypes[grantType] {
+				logger.Error(referenceID, "ERROR - OAuth_Client_Create - Unsupported grant type: ", grantType)
+				result.ErrorCode = "400005"
+				result.ErrorMessage = "Invalid grant_types"
+				utils.Response(w, result)
+				return
+			}
+			grantTypes = append(grantTypes, grantType)
+		}
+	}
+	grantTypesValue := strings.Join(grantTypes, " ")
+	usesAuthorizationCode := hasScope(grantTypesValue, "authorization_code")
+	if len(grantTypes) == 0 ||
+		(hasScope(grantTypesValue, "refresh_token") && !usesAuthorizationCode) ||
+		(hasScope(grantTypesValue, "client_credentials") && isPublic) {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Create - Invalid grant type combination: ", grantTypesValue)
+		result.ErrorCode = "400005"
+		result.ErrorMessage = "Invalid grant_types"
+		utils.Response(w, result)
+		return
+	}
+
+	var rateLimit sql.NullInt64
+	if value, ok := param["rate_limit"].(float64); ok {
+		if value < 1 {
+			logger.Error(referenceID, "ERROR - OAuth_Client_Create - Invalid rate_limit: ", value)
+			result.ErrorCode = "400006"
+			result.ErrorMessage = "Invalid rate_limit"
+			utils.Response(w, result)
+			return
+		}
+		rateLimit = sql.NullInt64{Int64: int64(value), Valid: true}
+	}
+
+	var redirectURIs []string
+	list, _ := param["redirect_uris"].([]any)
+	for _, item := range list {
+		uri, _ := item.(string)
+		if !validRedirectURI(uri) {
+			logger.Error(referenceID, "ERROR - OAuth_Client_Create - Invalid redirect URI: ", uri)
+			result.ErrorCode = "400002"
+			result.ErrorMessage = "Invalid redirect_uris"
+			utils.Response(w, result)
+			return
+		}
+		redirectURIs = append(redirectURIs, uri)
+	}
+	if len(redirectURIs) == 0 && usesAuthorizationCode {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Create - Missing redirect_uris")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid redirect_uris"
+		utils.Response(w, result)
+		return
+	}
+
+	// opsional, tujuan redirect setelah RP-initiated logout (OIDC)
+	var postLogoutURIs []string
+	list, _ = param["post_logout_redirect_uris"].([]any)
+	for _, item := range list {
+		uri, _ := item.(string)
+		if !validRedirectURI(uri) {
+			logger.Error(referenceID, "ERROR - OAuth_Client_Create - Invalid post logout redirect URI: ", uri)
+			result.ErrorCode = "400004"
+			result.ErrorMessage = "Invalid post_logout_redirect_uris"
+			utils.Response(w, result)
+			return
+		}
+		postLogoutURIs = append(postLogoutURIs, uri)
+	}
+	postLogoutURIsValue := sql.NullString{String: strings.Join(postLogoutURIs, " "), Valid: len(postLogoutURIs) > 0}
+
+	scopes, _ := param["scopes"].(string)
+	scopes = strings.Join(strings.Fields(scopes), " ")
+	if scopes == "" {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Create - Missing scopes")
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	clientID, err := utils.SecureTokenGenerator(16)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Create - Failed to generate client id: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// secret hanya ditampilkan sekali, yang disimpan hash-nya
+	var clientSecret string
+	var clientSecretHash sql.NullString
+	if !isPublic {
+		clientSecret, err = utils.SecureTokenGenerator(oauthTokenBytes)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - OAuth_Client_Create - Failed to generate client secret: ", err)
+			result.ErrorCode = "500002"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+		clientSecretHash = sql.NullString{String: crypto.HashSHA256(clientSecret), Valid: true}
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Create - Failed to get DB connection: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	queryInsert := `
+		INSERT INTO sysuser.oauth_client (client_id, client_secret_hash, name, redirect_uris, post_logout_redirect_uris, scopes, grant_types, rate_limit, is_public, skip_consent, create_tstamp, st)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 1)`
+	_, err = conn.Exec(queryInsert, clientID, clientSecretHash, name, strings.Join(redirectURIs, " "), postLogoutURIsValue, scopes, grantTypesValue, rateLimit, isPublic, skipConsent, time.Now().Unix())
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Create - Failed to store client: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - OAuth_Client_Create - Client registered: ", clientID, " (", name, ")")
+	result.Payload["client_id"] = clientID
+	if !isPublic {
+		result.Payload["client_secret"] = clientSecret
+	}
+	result.Payload["name"] = name
+	result.Payload["redirect_uris"] = redirectURIs
+	result.Payload["post_logout_redirect_uris"] = postLogoutURIs
+	result.Payload["scopes"] = scopes
+	result.Payload["grant_types"] = grantTypes
+	if rateLimit.Valid {
+		result.Payload["rate_limit"] = rateLimit.Int64
+	}
+	result.Payload["is_public"] = isPublic
+	result.Payload["skip_consent"] = skipConsent
+	utils.Response(w, result)
+}
+
+func OAuth_Client_List(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Client_List - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_List - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var clients []oauthClient
+	if err := conn.Select(&clients, `SELECT * FROM sysuser.oauth_client WHERE st = 1 ORDER BY create_tstamp`); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_List - Failed to load clients: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	list := make([]map[string]any, 0, len(clients))
+	for _, client := range clients {
+		list = append(list, map[string]any{
+			"client_id":                 client.ClientID,
+			"name":                      client.Name,
+			"redirect_uris":             strings.Fields(client.RedirectURIs),
+			"post_logout_redirect_uris": strings.Fields(client.PostLogoutURIs.String),
+			"scopes":                    client.Scopes,
+			"grant_types":               strings.Fields(client.GrantTypes),
+			"rate_limit":                client.rateLimit(),
+			"is_public":                 client.IsPublic,
+			"skip_consent":              client.SkipConsent,
+			"create_tstamp":             client.CreateTstamp,
+		})
+	}
+
+	result.Payload["clients"] = list
+	utils.Response(w, result)
+}
+
+/*
+{
+	"client_id" : "<client_id>"
+}
+*/
+
+func OAuth_Client_Delete(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Client_Delete - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	clientID, ok := param["client_id"].(string)
+	if !ok || clientID == "" {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Delete - Missing client_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Delete - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Delete - Failed to begin transaction: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	// client dinonaktifkan (bukan dihapus) supaya client_id tidak bisa dipakai ulang
+	res, err := tx.Exec(`UPDATE sysuser.oauth_client SET st = 0 WHERE client_id = $1 AND st = 1`, clientID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Delete - Failed to disable client: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if disabled, _ := res.RowsAffected(); disabled == 0 {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Delete - Client not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	res, err = tx.Exec(`UPDATE sysuser.oauth_token SET revoked = true WHERE client_id = $1 AND NOT revoked`, clientID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Delete - Failed to revoke tokens: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	revokedTokens, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Delete - Failed to commit: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - OAuth_Client_Delete - Client disabled: ", clientID, ", revoked tokens: ", revokedTokens)
+	result.Payload["status"] = "success"
+	result.Payload["revoked_tokens"] = revokedTokens
+	utils.Response(w, result)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// !NOTE : Client credentials (RFC 6749 §4.4) untuk service-to-service
+/*	1. Admin mendaftarkan machine client lewat /admin/oauth/clients/create dengan grant_types ["client_credentials"]
+2. Service menukar client_id + client_secret di /oauth/token (grant_type=client_credentials&scope=..),
+   mendapat access_token tanpa refresh_token dan tanpa user (user_id NULL)
+3. Endpoint service (/service/..., lihat service.go) dilindungi middlewares.RequireScope, token dibaca dari header Authorization: Bearer
+4. Secret dirotasi lewat /admin/oauth/clients/rotate-secret, secret lama tetap berlaku selama masa overlap
+5. Request per client dibatasi (token endpoint dan RequireScope), default configs oauthClientRateLimit
+*/
+
+/*
+ALTER TABLE sysuser.oauth_client ADD grant_types character varying(128) NOT NULL DEFAULT 'authorization_code refresh_token';
+ALTER TABLE sysuser.oauth_client ADD rate_limit int4 NULL; -- request per window, NULL = default config
+
+CREATE TABLE sysuser.oauth_client_secret (
+	id bigserial NOT NULL,
+	client_id character varying(64) NOT NULL,
+	secret_hash character varying(64) NOT NULL,     -- SHA-256 hex, secret sebelum rotasi
+	expire_tstamp int8 NOT NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT oauth_client_secret_pkey PRIMARY KEY (id),
+	CONSTRAINT oauth_client_secret_client_fkey FOREIGN KEY (client_id) REFERENCES sysuser.oauth_client(client_id) ON DELETE CASCADE
+);
+CREATE INDEX oauth_client_secret_client_idx ON sysuser.oauth_client_secret (client_id);
+*/
+
+// oauthGrantTypes adalah grant yang didukung token endpoint
+var oauthGrantTypes = map[string]bool{
+	"authorization_code": true,
+	"refresh_token":      true,
+	"client_credentials": true,
+}
+
+func previousClientSecretValid(conn *sqlx.DB, clientID, secretHash string) (bool, error) {
+	var valid bool
+	queryCheck := `SELECT EXISTS (SELECT 1 FROM sysuser.oauth_client_secret WHERE client_id = $1 AND secret_hash = $2 AND expire_tstamp > $3)`
+	if err := conn.Get(&valid, queryCheck, clientID, secretHash, time.Now().Unix()); err != nil {
+		return false, err
+	}
+	return valid, nil
+}
+
+func oauthClientCredentialsGrant(w http.ResponseWriter, r *http.Request, referenceID string, client *oauthClient) {
+	// public client tidak punya secret, tidak bisa membuktikan identitasnya
+	if client.IsPublic {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Public client requested client_credentials")
+		oauthError(w, http.StatusBadRequest, "unauthorized_client", "The client is not allowed to use this grant type")
+		return
+	}
+
+	scope, ok := client.resolveScope(r.PostFormValue("scope"))
+	if !ok || hasScope(scope, "openid") {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Invalid scope: ", r.PostFormValue("scope"))
+		oauthError(w, http.StatusBadRequest, "invalid_scope", "")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	grantID, err := utils.SecureTokenGenerator(24)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to generate grant id: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	// tanpa refresh token (RFC 6749 §4.4.3), service cukup meminta token baru
+	response, err := issueOAuthTokens(conn, oauthGrant{ClientID: client.ClientID, Scope: scope, GrantID: grantID}, false)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to issue tokens: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	logger.Info(referenceID, "INFO - OAuth_Token - Client credentials token issued for: ", client.ClientID, ", scope: ", scope)
+	oauthResponse(w, http.StatusOK, response)
+}
+
+/*
+{
+	"client_id" : "<client_id>",
+	"overlap_seconds" : 86400
+}
+*/
+
+// OAuth_Client_Rotate_Secret membuat secret baru, secret lama tetap diterima selama overlap_seconds
+func OAuth_Client_Rotate_Secret(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Client_Rotate_Secret - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	clientID, ok := param["client_id"].(string)
+	if !ok || clientID == "" {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Missing client_id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	overlap := int64(configs.GetOAuthSecretRotationOverlap())
+	if value, ok := param["overlap_seconds"].(float64); ok {
+		// 0 berarti secret lama langsung tidak berlaku (misal secret bocor)
+		if value < 0 || value > float64(30*24*3600) {
+			logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Invalid overlap_seconds: ", value)
+			result.ErrorCode = "400002"
+			result.ErrorMessage = "Invalid overlap_seconds"
+			utils.Response(w, result)
+			return
+		}
+		overlap = int64(value)
+	}
+
+	clientSecret, err := utils.SecureTokenGenerator(oauthTokenBytes)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to generate client secret: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to begin transaction: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	var currentHash sql.NullString
+	queryLock := `SELECT client_secret_hash FROM sysuser.oauth_client WHERE client_id = $1 AND st = 1 AND NOT is_public FOR UPDATE`
+	if err := tx.Get(&currentHash, queryLock, clientID); err == sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Confidential client not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to load client: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	now := time.Now().Unix()
+	if _, err := tx.Exec(`DELETE FROM sysuser.oauth_client_secret WHERE client_id = $1 AND expire_tstamp <= $2`, clientID, now); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to clean up expired secrets: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var previousExpire int64
+	if currentHash.Valid && overlap > 0 {
+		previousExpire = now + overlap
+		queryInsert := `INSERT INTO sysuser.oauth_client_secret (client_id, secret_hash, expire_tstamp, create_tstamp) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(queryInsert, clientID, currentHash.String, previousExpire, now); err != nil {
+			logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to keep previous secret: ", err)
+			result.ErrorCode = "500006"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE sysuser.oauth_client SET client_secret_hash = $1 WHERE client_id = $2`, crypto.HashSHA256(clientSecret), clientID); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to store new secret: ", err)
+		result.ErrorCode = "500007"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Client_Rotate_Secret - Failed to commit: ", err)
+		result.ErrorCode = "500008"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - OAuth_Client_Rotate_Secret - Secret rotated for client: ", clientID, ", previous secret valid until: ", previousExpire)
+	result.Payload["client_id"] = clientID
+	result.Payload["client_secret"] = clientSecret
+	result.Payload["previous_secret_expire_tstamp"] = previousExpire
+	utils.Response(w, result)
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OAuth_Token adalah token endpoint (RFC 6749 §3.2), grant authorization_code, refresh_token dan client_credentials
+func OAuth_Token(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Token - Execution completed in ", duration)
+	}()
+
+	if r.Method != http.MethodPost {
+		oauthError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Malformed form body")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	client, err := authenticateOAuthClient(r, conn)
+	if err == errInvalidClient {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Client authentication failed")
+		oauthClientError(w, r)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Client lookup failed: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	grantType := r.PostFormValue("grant_type")
+	logger.Info(referenceID, "INFO - OAuth_Token - grant_type: ", grantType, ", client_id: ", client.ClientID)
+
+	if !oauthGrantTypes[grantType] {
+		oauthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+	if !hasScope(client.GrantTypes, grantType) {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Grant type is not allowed for client: ", grantType)
+		oauthError(w, http.StatusBadRequest, "unauthorized_client", "The client is not allowed to use this grant type")
+		return
+	}
+
+	window := time.Duration(configs.GetOAuthClientRateWindow()) * time.Second
+	if ttl, err := utils.ClientRateLimiter(rds.GetRedisClient(), referenceID, client.ClientID, client.rateLimit(), window); err != nil {
+		if ttl > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(ttl.Seconds())+1))
+			oauthError(w, http.StatusTooManyRequests, "slow_down", "Too many requests")
+			return
+		}
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	switch grantType {
+	case "authorization_code":
+		oauthAuthorizationCodeGrant(w, r, referenceID, client)
+	case "refresh_token":
+		oauthRefreshTokenGrant(w, r, referenceID, client)
+	case "client_credentials":
+		oauthClientCredentialsGrant(w, r, referenceID, client)
+	}
+}
+
+func oauthAuthorizationCodeGrant(w http.ResponseWriter, r *http.Request, referenceID string, client *oauthClient) {
+	code := r.PostFormValue("code")
+	if code == "" {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Missing code")
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Redis client is not initialized")
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	// code sekali pakai, GetDel memastikan hanya satu request yang mendapatkannya
+	ctx := context.Background()
+	value, err := redisClient.GetDel(ctx, oauthCodeKey(code)).Result()
+	if err != nil {
+		// code dipakai ulang: cabut token yang sudah terbit dari code tersebut (RFC 6749 §4.1.2)
+		if grantID, errUsed := redisClient.Get(ctx, oauthUsedCodeKey(code)).Result(); errUsed == nil {
+			revoked, _ := revokeOAuthGrant(conn, grantID)
+			logger.Warning(referenceID, "WARNING - OAuth_Token - Authorization code reused, revoked tokens: ", revoked)
+		}
+		logger.Error(referenceID, "ERROR - OAuth_Token - Code not found, used or expired")
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+		return
+	}
+
+	var authorizationCode oauthAuthorizationCode
+	if err := json.Unmarshal([]byte(value), &authorizationCode); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Invalid code data: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if authorizationCode.ClientID != client.ClientID {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Code was issued to another client")
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "Invalid or expired authorization code")
+		return
+	}
+	if r.PostFormValue("redirect_uri") != authorizationCode.RedirectURI {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Redirect URI mismatch")
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorization request")
+		return
+	}
+	if !verifyPKCE(r.PostFormValue("code_verifier"), authorizationCode.CodeChallenge, authorizationCode.CodeChallengeMethod) {
+		logger.Error(referenceID, "ERROR - OAuth_Token - PKCE verification failed")
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "Invalid code_verifier")
+		return
+	}
+
+	grantID, err := utils.SecureTokenGenerator(24)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to generate grant id: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	grant := oauthGrant{
+		ClientID:  client.ClientID,
+		UserID:    sql.NullInt64{Int64: authorizationCode.UserID, Valid: true},
+		SessionID: sql.NullString{String: authorizationCode.SessionID, Valid: authorizationCode.SessionID != ""},
+		Scope:     authorizationCode.Scope,
+		GrantID:   grantID,
+	}
+	response, err := issueOAuthTokens(conn, grant, true)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to issue tokens: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if hasScope(grant.Scope, "openid") {
+		idToken, err := buildIDToken(conn, grant, authorizationCode.Nonce, authorizationCode.AuthTime, response["access_token"].(string))
+		if err != nil {
+			logger.Error(referenceID, "ERROR - OAuth_Token - Failed to sign ID token: ", err)
+			oauthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	redisClient.Set(ctx, oauthUsedCodeKey(code), grantID, 10*time.Minute)
+
+	oauthResponse(w, http.StatusOK, response)
+}
+
+func oauthRefreshTokenGrant(w http.ResponseWriter, r *http.Request, referenceID string, client *oauthClient) {
+	refreshToken := r.PostFormValue("refresh_token")
+	if refreshToken == "" {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Missing refresh_token")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	stored, err := getOAuthToken(conn, refreshToken)
+	if err != nil || stored.TokenType != oauthTokenTypeRefresh || stored.ClientID != client.ClientID {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Refresh token not found: ", err)
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+		return
+	}
+
+	if stored.Revoked {
+		// refresh token lama dipakai lagi, kemungkinan bocor: cabut seluruh grant
+		revoked, _ := revokeOAuthGrant(conn, stored.GrantID)
+		logger.Warning(referenceID, "WARNING - OAuth_Token - Revoked refresh token reused, revoked tokens: ", revoked)
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+		return
+	}
+	if !stored.active() {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Refresh token expired")
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "Refresh token expired")
+		return
+	}
+
+	// scope boleh dipersempit, tidak boleh diperluas (RFC 6749 §6)
+	scope := stored.Scope
+	if requested := r.PostFormValue("scope"); requested != "" {
+		if !scopeSubset(requested, stored.Scope) {
+			oauthError(w, http.StatusBadRequest, "invalid_scope", "Requested scope exceeds the original grant")
+			return
+		}
+		scope, _ = client.resolveScope(requested)
+	}
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to begin transaction: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	defer tx.Rollback()
+
+	// rotasi: refresh token lama dicabut, update kondisional menolak refresh paralel
+	res, err := tx.Exec(`UPDATE sysuser.oauth_token SET revoked = true WHERE token_hash = $1 AND NOT revoked`, stored.TokenHash)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to rotate refresh token: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	if rotated, _ := res.RowsAffected(); rotated == 0 {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Refresh token already rotated")
+		oauthError(w, http.StatusBadRequest, "invalid_grant", "Invalid refresh token")
+		return
+	}
+
+	grant := oauthGrant{
+		ClientID:  client.ClientID,
+		UserID:    stored.UserID,
+		SessionID: stored.SessionID,
+		Scope:     scope,
+		GrantID:   stored.GrantID,
+	}
+	response, err := issueOAuthTokens(tx, grant, true)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to issue tokens: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	// ID token baru tanpa nonce (OIDC Core §12.2), auth_time diambil dari sesi asal bila masih ada
+	if hasScope(grant.Scope, "openid") && grant.UserID.Valid {
+		var authTime int64
+		if grant.SessionID.Valid {
+			conn.Get(&authTime, `SELECT tstamp FROM sysuser.session WHERE session_id = $1`, grant.SessionID.String)
+		}
+		idToken, err := buildIDToken(conn, grant, "", authTime, response["access_token"].(string))
+		if err != nil {
+			logger.Error(referenceID, "ERROR - OAuth_Token - Failed to sign ID token: ", err)
+			oauthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Token - Failed to commit: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	oauthResponse(w, http.StatusOK, response)
+}
+
+// OAuth_Revoke mencabut token (RFC 7009). Token yang tidak dikenal tetap dijawab 200.
+// Mencabut refresh token ikut mencabut access token dari grant yang sama.
+func OAuth_Revoke(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Revoke - Execution completed in ", duration)
+	}()
+
+	if r.Method != http.MethodPost {
+		oauthError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Malformed form body")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Revoke - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	client, err := authenticateOAuthClient(r, conn)
+	if err == errInvalidClient {
+		logger.Error(referenceID, "ERROR - OAuth_Revoke - Client authentication failed")
+		oauthClientError(w, r)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Revoke - Client lookup failed: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	token := r.PostFormValue("token")
+	if token == "" {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Missing token")
+		return
+	}
+
+	stored, err := getOAuthToken(conn, token)
+	if err == sql.ErrNoRows {
+		oauthResponse(w, http.StatusOK, map[string]any{})
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Revoke - Token lookup failed: ", err)
+		oauthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "")
+		return
+	}
+
+	// token milik client lain diabaikan tanpa memberi tahu pemanggil
+	if stored.ClientID != client.ClientID {
+		logger.Warning(referenceID, "WARNING - OAuth_Revoke - Token belongs to another client")
+		oauthResponse(w, http.StatusOK, map[string]any{})
+		return
+	}
+
+	if stored.TokenType == oauthTokenTypeRefresh {
+		_, err = revokeOAuthGrant(conn, stored.GrantID)
+	} else {
+		_, err = conn.Exec(`UPDATE sysuser.oauth_token SET revoked = true WHERE token_hash = $1`, stored.TokenHash)
+	}
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Revoke - Failed to revoke token: ", err)
+		oauthError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "")
+		return
+	}
+
+	logger.Info(referenceID, "INFO - OAuth_Revoke - Revoked ", stored.TokenType, " for client: ", client.ClientID)
+	oauthResponse(w, http.StatusOK, map[string]any{})
+}
+
+// OAuth_Introspect memberi status token ke resource server (RFC 7662).
+// Hanya confidential client yang boleh memanggil.
+func OAuth_Introspect(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OAuth_Introspect - Execution completed in ", duration)
+	}()
+
+	if r.Method != http.MethodPost {
+		oauthError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Malformed form body")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Introspect - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	client, err := authenticateOAuthClient(r, conn)
+	if err == errInvalidClient || (err == nil && client.IsPublic) {
+		logger.Error(referenceID, "ERROR - OAuth_Introspect - Client authentication failed")
+		oauthClientError(w, r)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - OAuth_Introspect - Client lookup failed: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	token := r.PostFormValue("token")
+	if token == "" {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Missing token")
+		return
+	}
+
+	stored, err := getOAuthToken(conn, token)
+	if err != nil || !stored.active() {
+		oauthResponse(w, http.StatusOK, map[string]any{"active": false})
+		return
+	}
+
+	response := map[string]any{
+		"active":     true,
+		"scope":      stored.Scope,
+		"client_id":  stored.ClientID,
+		"exp":        stored.ExpireTstamp,
+		"iat":        stored.CreateTstamp,
+		"token_type": "Bearer",
+	}
+	if stored.TokenType == oauthTokenTypeRefresh {
+		delete(response, "token_type")
+	}
+	if stored.UserID.Valid {
+		var username string
+		if err := conn.Get(&username, `SELECT username FROM sysuser."user" WHERE id = $1`, stored.UserID.Int64); err != nil {
+			logger.Error(referenceID, "ERROR - OAuth_Introspect - Token owner not found: ", err)
+			oauthResponse(w, http.StatusOK, map[string]any{"active": false})
+			return
+		}
+		response["sub"] = strconv.FormatInt(stored.UserID.Int64, 10)
+		response["username"] = username
+	}
+
+	oauthResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	// contoh RFC 7636 Appendix B
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		method    string
+		want      bool
+	}{
+		{"rfc 7636 example", verifier, challenge, "S256", true},
+		{"plain method rejected", verifier, verifier, "plain", false},
+		{"empty method rejected", verifier, challenge, "", false},
+		{"lowercase method rejected", verifier, challenge, "s256", false},
+		{"wrong verifier", strings.Replace(verifier, "d", "e", 1), challenge, "S256", false},
+		{"padded challenge", verifier, challenge + "=", "S256", false},
+		{"empty challenge", verifier, "", "S256", false},
+		{"verifier too short", verifier[:42], challenge, "S256", false},
+		{"verifier too long", strings.Repeat("a", 129), challenge, "S256", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyPKCE(tt.verifier, tt.challenge, tt.method); got != tt.want {
+				t.Errorf("verifyPKCE() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyPKCELengthBounds(t *testing.T) {
+	// panjang verifier 43 dan 128 karakter masih diterima (RFC 7636 §4.1)
+	for _, n := range []int{43, 128} {
+		verifier := strings.Repeat("a", n)
+		challenge := pkceChallenge(verifier)
+		if !verifyPKCE(verifier, challenge, "S256") {
+			t.Errorf("verifyPKCE with %d character verifier = false, want true", n)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/oidc"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// !NOTE : OpenID Connect di atas OAuth 2.0 (lihat oauth.go)
+/*	1. Client meminta scope openid (+ profile / email / phone) dan nonce di /oauth/authorize
+2. /oauth/token menambahkan id_token (JWT RS256, kunci di /.well-known/jwks.json)
+3. /userinfo mengembalikan claims user sesuai scope, memakai access token (Bearer)
+4. RP-initiated logout: /oauth/logout?id_token_hint=..&post_logout_redirect_uri=..&state=..
+   redirect ke halaman konfirmasi frontend, setelah user setuju /oauth/logout/decision menghapus
+   sysuser.session asal otorisasi (claim sid) dan mencabut token OAuth dari sesi tersebut
+*/
+
+/*
+ALTER TABLE sysuser.oauth_token ADD session_id character varying(16) NULL; -- sesi login asal otorisasi
+CREATE INDEX oauth_token_session_idx ON sysuser.oauth_token (session_id);
+
+ALTER TABLE sysuser.oauth_client ADD post_logout_redirect_uris text NULL; -- dipisah spasi, dicocokkan persis
+*/
+
+var oidcScopes = []string{"openid", "profile", "email", "phone"}
+
+// oidcUserClaims membaca claims user dari sysuser.user sesuai scope yang diberikan
+func oidcUserClaims(conn *sqlx.DB, userID int64, scope string) (map[string]any, error) {
+	var user UserData
+	queryGetUser := `SELECT username, COALESCE(email, '') AS email, COALESCE(phone, '') AS phone, full_name, role, data FROM sysuser."user" WHERE id = $1`
+	if err := conn.Get(&user, queryGetUser, userID); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]any{
+		"sub": strconv.FormatInt(userID, 10),
+	}
+	if hasScope(scope, "profile") {
+		claims["preferred_username"] = user.Username
+		claims["name"] = user.FullName
+		claims["role"] = user.Role
+	}
+	if hasScope(scope, "email") && user.Email != "" {
+		claims["email"] = user.Email
+	}
+	if hasScope(scope, "phone") && user.Phone != "" {
+		claims["phone_number"] = user.Phone
+	}
+	return claims, nil
+}
+
+// buildIDToken membuat ID token (OIDC Core §2) untuk grant yang baru diterbitkan
+func buildIDToken(conn *sqlx.DB, grant oauthGrant, nonce string, authTime int64, accessToken string) (string, error) {
+	claims, err := oidcUserClaims(conn, grant.UserID.Int64, grant.Scope)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().Unix()
+	claims["iss"] = oidc.Issuer()
+	claims["aud"] = grant.ClientID
+	claims["azp"] = grant.ClientID
+	claims["iat"] = now
+	claims["exp"] = now + int64(configs.GetOIDCIDTokenExpireTime())
+	claims["at_hash"] = oidc.AccessTokenHash(accessToken)
+	if authTime > 0 {
+		claims["auth_time"] = authTime
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if grant.SessionID.Valid {
+		claims["sid"] = grant.SessionID.String
+	}
+
+	return oidc.Sign(claims)
+}
+
+// OIDC_Discovery adalah OpenID Provider Metadata (OIDC Discovery §3)
+func OIDC_Discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := oidc.Issuer()
+	metadata := map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"end_session_endpoint":                  issuer + "/oauth/logout",
+		"revocation_endpoint":                   issuer + "/oauth/revoke",
+		"introspection_endpoint":                issuer + "/oauth/introspect",
+		"scopes_supported":                      oidcScopes,
+		"response_types_supported":              []string{"code"},
+		"response_modes_supported":              []string{"query"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token", "client_credentials"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "sid", "at_hash",
+			"preferred_username", "name", "role", "email", "phone_number",
+		},
+		"authorization_response_iss_parameter_supported": true,
+	}
+
+	oidcPublicResponse(w, metadata)
+}
+
+// OIDC_JWKS mengembalikan public key untuk verifikasi ID token
+func OIDC_JWKS(w http.ResponseWriter, r *http.Request) {
+	oidcPublicResponse(w, oidc.JWKS())
+}
+
+// oidcPublicResponse menulis metadata publik yang boleh di-cache client
+func oidcPublicResponse(w http.ResponseWriter, body map[string]any) {
+	jsonString, err := utils.JSONencode(body)
+	if err != nil {
+		logger.Error("Unknown", "ERROR - OIDC response encoding failed: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if _, err := w.Write([]byte(jsonString)); err != nil {
+		logger.Error("Unknown", "ERROR - Failed to write OIDC response: ", err)
+	}
+}
+
+// bearerToken membaca access token dari header Authorization atau form (RFC 6750 §2.1, §2.2)
+func bearerToken(r *http.Request) string {
+	if authorization := r.Header.Get("Authorization"); len(authorization) > 7 && strings.EqualFold(authorization[:7], "Bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	if r.Method == http.MethodPost {
+		return r.PostFormValue("access_token")
+	}
+	return ""
+}
+
+// OIDC_UserInfo adalah UserInfo endpoint (OIDC Core §5.3)
+func OIDC_UserInfo(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OIDC_UserInfo - Execution completed in ", duration)
+	}()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		oauthError(w, http.StatusMethodNotAllowed, "invalid_request", "Method not allowed")
+		return
+	}
+
+	invalidToken := func(description string) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="`+description+`"`)
+		oauthError(w, http.StatusUnauthorized, "invalid_token", description)
+	}
+
+	accessToken := bearerToken(r)
+	if accessToken == "" {
+		logger.Error(referenceID, "ERROR - OIDC_UserInfo - Missing access token")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="userinfo"`)
+		oauthError(w, http.StatusUnauthorized, "invalid_request", "Missing access token")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_UserInfo - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	stored, err := getOAuthToken(conn, accessToken)
+	if err != nil || stored.TokenType != oauthTokenTypeAccess || !stored.active() || !stored.UserID.Valid {
+		logger.Error(referenceID, "ERROR - OIDC_UserInfo - Invalid access token: ", err)
+		invalidToken("The access token is invalid or expired")
+		return
+	}
+	if !hasScope(stored.Scope, "openid") {
+		logger.Error(referenceID, "ERROR - OIDC_UserInfo - Token has no openid scope")
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		oauthError(w, http.StatusForbidden, "insufficient_scope", "The access token has no openid scope")
+		return
+	}
+
+	claims, err := oidcUserClaims(conn, stored.UserID.Int64, stored.Scope)
+	if err == sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - OIDC_UserInfo - User not found")
+		invalidToken("The access token is invalid or expired")
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_UserInfo - Failed to load user: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	oauthResponse(w, http.StatusOK, claims)
+}
+
+// OIDC_Logout adalah RP-initiated logout (OIDC RP-Initiated Logout 1.0).
+// id_token_hint wajib karena sesi tidak memakai cookie, sesi ditentukan dari claim sid.
+// Browser diarahkan ke halaman konfirmasi frontend (clientURL + oidcLogoutPath?logout_id=..).
+func OIDC_Logout(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OIDC_Logout - Execution completed in ", duration)
+	}()
+
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Malformed request")
+		return
+	}
+
+	// token kedaluwarsa tetap diterima selama belum melewati oidcLogoutHintMaxAge, tanda tangan dan issuer harus valid
+	claims, err := oidc.Verify(r.FormValue("id_token_hint"), true)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - Invalid id_token_hint: ", err)
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Invalid id_token_hint")
+		return
+	}
+	exp, _ := claims["exp"].(float64)
+	if time.Now().Unix()-int64(exp) > int64(configs.GetOIDCLogoutHintMaxAge()) {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - id_token_hint expired too long ago")
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Expired id_token_hint")
+		return
+	}
+
+	clientID, _ := claims["aud"].(string)
+	sessionID, _ := claims["sid"].(string)
+	subject, _ := claims["sub"].(string)
+	if clientIDParam := r.FormValue("client_id"); clientIDParam != "" && clientIDParam != clientID {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - client_id does not match id_token_hint")
+		oauthError(w, http.StatusBadRequest, "invalid_request", "client_id does not match id_token_hint")
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - Failed to get DB connection: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	// post_logout_redirect_uri harus terdaftar persis, dicek sebelum logout supaya tidak jadi open redirect
+	redirectURI := r.FormValue("post_logout_redirect_uri")
+	if redirectURI != "" {
+		client, err := getOAuthClient(conn, clientID)
+		if err != nil || !client.PostLogoutURIs.Valid || !containsField(client.PostLogoutURIs.String, redirectURI) {
+			logger.Error(referenceID, "ERROR - OIDC_Logout - post_logout_redirect_uri is not registered: ", redirectURI)
+			oauthError(w, http.StatusBadRequest, "invalid_request", "Invalid post_logout_redirect_uri")
+			return
+		}
+	}
+
+	if sessionID == "" {
+		// tidak ada sesi yang perlu diakhiri, langsung kembali ke client
+		if redirectURI != "" {
+			http.Redirect(w, r, appendQuery(redirectURI, map[string]string{"state": r.FormValue("state")}), http.StatusFound)
+			return
+		}
+		oauthResponse(w, http.StatusOK, map[string]any{"status": "success"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - Invalid sub claim: ", err)
+		oauthError(w, http.StatusBadRequest, "invalid_request", "Invalid id_token_hint")
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - Redis client is not initialized")
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	logoutID, err := utils.SecureTokenGenerator(24)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - Failed to generate logout id: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	logoutRequest := oidcLogoutRequest{
+		ClientID:    clientID,
+		SessionID:   sessionID,
+		UserID:      userID,
+		RedirectURI: redirectURI,
+		State:       r.FormValue("state"),
+	}
+	expiry := time.Duration(configs.GetOIDCLogoutRequestExpireTime()) * time.Second
+	if err := redisClient.Set(context.Background(), oidcLogoutKey(logoutID), marshalOAuthState(logoutRequest), expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout - Failed to store logout request: ", err)
+		oauthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	// sesi baru diakhiri setelah user mengonfirmasi di frontend (OIDC_Logout_Decision),
+	// supaya halaman pihak ketiga yang memegang id_token_hint tidak bisa me-logout user diam-diam
+	confirmURL := appendQuery(configs.GetClientURL()+configs.GetOIDCLogoutPath(), map[string]string{"logout_id": logoutID})
+	http.Redirect(w, r, confirmURL, http.StatusFound)
+}
+
+/*
+{
+	"logout_id" : "<dari query halaman konfirmasi logout>",
+	"confirm" : true
+}
+*/
+
+// OIDC_Logout_Decision dipanggil halaman konfirmasi logout di frontend.
+// Hanya pemilik sesi (sub pada id_token_hint) yang bisa mengonfirmasi, response berisi redirect_to bila client mengirim post_logout_redirect_uri.
+func OIDC_Logout_Decision(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - OIDC_Logout_Decision - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+	logoutID, _ := param["logout_id"].(string)
+	confirm, ok := param["confirm"].(bool)
+	if logoutID == "" || !ok {
+		logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Missing logout_id or confirm")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// request hanya bisa diputuskan sekali
+	value, err := redisClient.GetDel(context.Background(), oidcLogoutKey(logoutID)).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Logout request not found or expired: ", err)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Logout request not found or expired"
+		utils.Response(w, result)
+		return
+	}
+
+	var logoutRequest oidcLogoutRequest
+	if err := json.Unmarshal([]byte(value), &logoutRequest); err != nil {
+		logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Invalid logout request data: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if logoutRequest.UserID != userID {
+		logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Logout request belongs to another user")
+		result.ErrorCode = "403001"
+		result.ErrorMessage = "Forbidden"
+		utils.Response(w, result)
+		return
+	}
+
+	if confirm {
+		conn, err := db.GetConnection()
+		if err != nil {
+			logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Failed to get DB connection: ", err)
+			result.ErrorCode = "500003"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		deletedSessions, revokedTokens, err := endOIDCSession(conn, logoutRequest.SessionID, userID)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - OIDC_Logout_Decision - Failed to end session: ", err)
+			result.ErrorCode = "500004"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		logger.Info(referenceID, "INFO - OIDC_Logout_Decision - Session ended: ", logoutRequest.SessionID, ", deleted: ", deletedSessions, ", revoked tokens: ", revokedTokens)
+	} else {
+		logger.Info(referenceID, "INFO - OIDC_Logout_Decision - User declined logout for client: ", logoutRequest.ClientID)
+	}
+
+	result.Payload["logged_out"] = confirm
+	if logoutRequest.RedirectURI != "" {
+		result.Payload["redirect_to"] = appendQuery(logoutRequest.RedirectURI, map[string]string{"state": logoutRequest.State})
+	}
+	utils.Response(w, result)
+}
+
+// oidcLogoutRequest adalah RP-initiated logout yang menunggu konfirmasi user
+type oidcLogoutRequest struct {
+	ClientID    string `json:"client_id"`
+	SessionID   string `json:"session_id"`
+	UserID      int64  `json:"user_id"`
+	RedirectURI string `json:"redirect_uri,omitempty"`
+	State       string `json:"state,omitempty"`
+}
+
+func oidcLogoutKey(logoutID string) string {
+	return "oidc_logout:" + logoutID
+}
+
+// endOIDCSession menghapus sysuser.session asal otorisasi dan mencabut token OAuth dari sesi tersebut
+func endOIDCSession(conn *sqlx.DB, sessionID string, userID int64) (int64, int64, error) {
+	tx, err := conn.Beginx()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM sysuser.session WHERE session_id = $1 AND user_id = $2`, sessionID, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	deletedSessions, _ := res.RowsAffected()
+
+	res, err = tx.Exec(`UPDATE sysuser.oauth_token SET revoked = true WHERE session_id = $1 AND user_id = $2 AND NOT revoked`, sessionID, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	revokedTokens, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return deletedSessions, revokedTokens, nil
+}
+
+func containsField(fields, value string) bool {
+	for _, field := range strings.Fields(fields) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/phone"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+/*	Verifikasi nomor telepon akun:
+	1. /account/phone/verify/request  simpan nomor ke phone_pending (opsional nomor baru) lalu kirim OTP ke nomor itu
+	2. /account/phone/verify/confirm  kode benar -> phone = phone_pending, phone_pending = NULL
+	Kolom phone unik, jadi nomor baru berpindah ke akun hanya setelah pemiliknya membuktikan menerima OTP.
+	Challenge disimpan di Redis phone_verify:<user_id> = "<sha256 kode>|<nomor>", maksimal mfaMaxAttempts percobaan.
+*/
+
+func phoneVerifyKey(userID int64) string {
+	return fmt.Sprintf("phone_verify:%d", userID)
+}
+
+func phoneVerifyAttemptsKey(userID int64) string {
+	return fmt.Sprintf("phone_verify_attempts:%d", userID)
+}
+
+/*
+{
+	"phone" : "+6281234567890",	// opsional, default phone_pending yang tersimpan
+	"channel" : "sms"			// opsional: sms (default) | whatsapp
+}
+*/
+
+func Phone_Verify_Request(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Phone_Verify_Request - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	channel, _ := param["channel"].(string)
+	if channel == "" {
+		channel = phone.ChannelSMS
+	}
+	if !phone.IsChannel(channel) {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - Invalid channel: ", channel)
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	phoneNumber, _ := param["phone"].(string)
+	if phoneNumber != "" {
+		normalized, err := phone.NormalizeE164(phoneNumber)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Phone_Verify_Request - Invalid phone: ", err)
+			result.ErrorCode = "400002"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		phoneNumber = normalized
+
+		if _, err := conn.Exec(`UPDATE sysuser."user" SET phone_pending = $2 WHERE id = $1`, userID, phoneNumber); err != nil {
+			logger.Error(referenceID, "ERROR - Phone_Verify_Request - Failed to store pending phone: ", err)
+			result.ErrorCode = "500002"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+	} else {
+		var pending sql.NullString
+		if err := conn.Get(&pending, `SELECT phone_pending FROM sysuser."user" WHERE id = $1`, userID); err != nil || !pending.Valid {
+			logger.Error(referenceID, "ERROR - Phone_Verify_Request - No pending phone: ", err)
+			result.ErrorCode = "400003"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		phoneNumber = pending.String
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - Redis client is not initialized")
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	expiry := time.Duration(configs.GetOTPExpireTime()) * time.Second
+	ttl, err := utils.SendMailLimiter(redisClient, referenceID, phoneNumber, "Phone verification OTP", expiry)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - ", err)
+		result.ErrorCode = "429001"
+		result.ErrorMessage = fmt.Sprintf("%s. Please try again in %d seconds", err.Error(), int(ttl.Seconds()))
+		utils.Response(w, result)
+		return
+	}
+
+	otpInt, err := utils.RandoNnumberGenerator(6)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - Failed to generate OTP: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	otp := fmt.Sprintf("%06d", otpInt)
+
+	ctx := context.Background()
+	challenge := crypto.HashSHA256(otp) + "|" + phoneNumber
+	if err := redisClient.Set(ctx, phoneVerifyKey(userID), challenge, expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - Failed to store challenge: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	redisClient.Del(ctx, phoneVerifyAttemptsKey(userID))
+
+	messageID, err := phone.Enqueue(channel, phoneNumber, phone.Format(userLocale(conn, userID), "phone_verify", otp, int(expiry.Seconds())))
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Request - Failed to queue OTP via ", channel, ": ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	logger.Info(referenceID, "INFO - Phone_Verify_Request - OTP ", channel, " queued, message id: ", messageID)
+
+	result.Payload["status"] = "success"
+	result.Payload["phone_pending"] = phoneNumber
+	result.Payload["expire_seconds"] = int(expiry.Seconds())
+	utils.Response(w, result)
+}
+
+/*
+{
+	"code" : "123456"
+}
+*/
+
+func Phone_Verify_Confirm(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Phone_Verify_Confirm - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	code, ok := param["code"].(string)
+	if !ok || code == "" {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Missing code")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	ctx := context.Background()
+	challenge, err := redisClient.Get(ctx, phoneVerifyKey(userID)).Result()
+	codeHash, phoneNumber, found := strings.Cut(challenge, "|")
+	if err != nil || !found {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - No active challenge: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(crypto.HashSHA256(code)), []byte(codeHash)) != 1 {
+		attempts, _ := redisClient.Incr(ctx, phoneVerifyAttemptsKey(userID)).Result()
+		redisClient.Expire(ctx, phoneVerifyAttemptsKey(userID), time.Duration(configs.GetOTPExpireTime())*time.Second)
+		if attempts >= configs.GetMFAMaxAttempts() {
+			// challenge dibuang, pengguna harus meminta kode baru
+			redisClient.Del(ctx, phoneVerifyKey(userID), phoneVerifyAttemptsKey(userID))
+		}
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Invalid code, attempt ", attempts)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+	redisClient.Del(ctx, phoneVerifyKey(userID), phoneVerifyAttemptsKey(userID))
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	res, err := conn.Exec(`UPDATE sysuser."user" SET phone = phone_pending, phone_pending = NULL WHERE id = $1 AND phone_pending = $2`, userID, phoneNumber)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Phone already used by another account")
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "phone already exists"
+		utils.Response(w, result)
+		return
+	}
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Failed to update phone: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		// phone_pending diganti oleh request lain setelah kode dikirim
+		logger.Error(referenceID, "ERROR - Phone_Verify_Confirm - Pending phone changed")
+		result.ErrorCode = "409002"
+		result.ErrorMessage = "Conflict"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Phone_Verify_Confirm - Phone verified for user: ", userID)
+	result.Payload["status"] = "success"
+	result.Payload["phone"] = phoneNumber
+	utils.Response(w, result)
+}
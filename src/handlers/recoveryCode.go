@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/mail"
+	"auth_service/utils"
+	cryptorand "crypto/rand"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/*
+CREATE TABLE sysuser.recovery_code (
+	id serial4 NOT NULL,
+	user_id int8 NOT NULL,
+	code_hash character varying(64) NOT NULL, -- SHA-256 hex dari kode yang sudah dinormalisasi
+	used boolean NOT NULL DEFAULT false,
+	used_tstamp int8 NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT recovery_code_pkey PRIMARY KEY (id),
+	CONSTRAINT recovery_code_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX recovery_code_user_idx ON sysuser.recovery_code (user_id) WHERE NOT used;
+*/
+
+// tanpa karakter yang mudah tertukar (0/o, 1/l/i)
+const recoveryCodeCharset = "abcdefghjkmnpqrstuvwxyz23456789"
+const recoveryCodeLength = 10
+
+// normalizeRecoveryCode membuang pemisah dan spasi, kode boleh diketik dengan/tanpa "-"
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return code
+}
+
+func newRecoveryCode() (string, error) {
+	max := big.NewInt(int64(len(recoveryCodeCharset)))
+	buf := make([]byte, recoveryCodeLength)
+	for i := range buf {
+		n, err := cryptorand.Int(cryptorand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = recoveryCodeCharset[n.Int64()]
+	}
+	return string(buf[:recoveryCodeLength/2]) + "-" + string(buf[recoveryCodeLength/2:]), nil
+}
+
+// generateRecoveryCodes mengganti seluruh kode pemulihan user dengan set baru.
+// Hanya hash yang disimpan, kode plaintext dikembalikan sekali ke pemanggil.
+func generateRecoveryCodes(conn sqlx.Execer, userID int64) ([]string, error) {
+	if _, err := conn.Exec(`DELETE FROM sysuser.recovery_code WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	codes := make([]string, 0, configs.GetRecoveryCodeCount())
+	for i := 0; i < configs.GetRecoveryCodeCount(); i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		_, err = conn.Exec(`INSERT INTO sysuser.recovery_code (user_id, code_hash, create_tstamp) VALUES ($1, $2, $3)`,
+			userID, crypto.HashSHA256(normalizeRecoveryCode(code)), now)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// consumeRecoveryCode menandai kode sebagai terpakai. Update kondisional, kode yang sama
+// hanya bisa dipakai satu kali walaupun dikirim paralel.
+func consumeRecoveryCode(conn *sqlx.DB, userID int64, code string) (bool, error) {
+	queryConsume := `
+		UPDATE sysuser.recovery_code SET used = true, used_tstamp = $3
+		WHERE user_id = $1 AND code_hash = $2 AND NOT used`
+	res, err := conn.Exec(queryConsume, userID, crypto.HashSHA256(normalizeRecoveryCode(code)), time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	consumed, _ := res.RowsAffected()
+	return consumed == 1, nil
+}
+
+func remainingRecoveryCodes(conn *sqlx.DB, userID int64) (int64, error) {
+	var remaining int64
+	err := conn.Get(&remaining, `SELECT COUNT(*) FROM sysuser.recovery_code WHERE user_id = $1 AND NOT used`, userID)
+	return remaining, err
+}
+
+// notifyRecoveryCodeUsed mengirim email peringatan bila sisa kode sudah di bawah ambang
+func notifyRecoveryCodeUsed(referenceID string, conn *sqlx.DB, userID int64, remaining int64) {
+	if remaining > configs.GetRecoveryCodeLowThreshold() {
+		return
+	}
+
+	var user struct {
+		Username string `db:"username"`
+		Email    string `db:"email"`
+		FullName string `db:"full_name"`
+	}
+	if err := conn.Get(&user, `SELECT username, email, full_name FROM sysuser."user" WHERE id = $1`, userID); err != nil {
+		logger.Warning(referenceID, "WARNING - Recovery Code - Failed to load user for low-codes notice: ", err)
+		return
+	}
+
+	_, err := mail.EnqueueTemplate(user.Email, userLocale(conn, userID), mail.TemplateRecoveryCodesLow, map[string]any{
+		"FullName":  user.FullName,
+		"Username":  user.Username,
+		"Remaining": remaining,
+		"Time":      time.Now().Format(time.RFC1123),
+	})
+	if err != nil {
+		logger.Warning(referenceID, "WARNING - Recovery Code - Failed to queue low-codes notice: ", err)
+	}
+}
+
+/*
+Buat ulang kode pemulihan, set lama langsung tidak berlaku.
+{
+	"password" : "master123"
+}
+*/
+
+func Recovery_Codes_Regenerate(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Recovery_Codes_Regenerate - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	password, ok := param["password"].(string)
+	if !ok || password == "" {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Missing password")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var userData struct {
+		userPasswordData
+		TOTPEnabled bool `db:"totp_enabled"`
+	}
+	queryGetUser := `SELECT username, email, full_name, salt, saltedpassword, totp_enabled FROM sysuser."user" WHERE id = $1`
+	if err := conn.Get(&userData, queryGetUser, userID); err != nil {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - User not found: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if !passwordMatches(password, userData.Salt, userData.SaltedPassword) {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Password mismatch")
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if !userData.TOTPEnabled {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Two-factor authentication is not enabled")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Failed to begin transaction: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	codes, err := generateRecoveryCodes(tx, userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Failed to generate recovery codes: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Regenerate - Failed to commit: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["recovery_codes"] = codes
+	utils.Response(w, result)
+}
+
+// Recovery_Codes_Status mengembalikan jumlah kode pemulihan yang masih bisa dipakai
+func Recovery_Codes_Status(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Recovery_Codes_Status - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Status - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Status - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	remaining, err := remainingRecoveryCodes(conn, userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Recovery_Codes_Status - Failed to count recovery codes: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["remaining"] = remaining
+	result.Payload["low"] = remaining <= configs.GetRecoveryCodeLowThreshold()
+	utils.Response(w, result)
+}
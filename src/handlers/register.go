@@ -6,22 +6,35 @@ import (
 	"auth_service/db"
 	"auth_service/logger"
 	"auth_service/mail"
+	"auth_service/phone"
+	"auth_service/policy"
 	"auth_service/rds"
 	"strings"
 
 	"auth_service/utils"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 /*
 {
 	"username" : "master",
 	"full_name" : "Master User",
-	"password" : "master123"
+	"email" : "master@mail.com",
+	"password" : "master123",
+	"phone" : "+6281234567890",	// opsional, format E.164
+	"otp_channel" : "email"		// opsional: email (default) | sms | whatsapp
 }
+
+Nomor telepon hanya disimpan ke kolom phone (unik, dipakai reset password) bila OTP dikirim ke nomor itu.
+Bila OTP lewat email, nomor disimpan di phone_pending sampai diverifikasi lewat /account/phone/verify,
+supaya nomor milik orang lain tidak bisa "dipesan" saat registrasi.
 */
 
 func Register(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +89,7 @@ func Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	password, ok := param["password"].(string)
-	if !ok || password == "" || len(password) < 8 {
+	if !ok || password == "" {
 		logger.Error(referenceID, "ERROR - Register - Missing password")
 		result.ErrorCode = "400003"
 		result.ErrorMessage = "Invalid request"
@@ -84,6 +97,49 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	otpChannel, _ := param["otp_channel"].(string)
+	if otpChannel == "" {
+		otpChannel = "email"
+	}
+	if otpChannel != "email" && !phone.IsChannel(otpChannel) {
+		logger.Error(referenceID, "ERROR - Register - Invalid otp_channel: ", otpChannel)
+		result.ErrorCode = "400005"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	phoneNumber, _ := param["phone"].(string)
+	if phoneNumber != "" {
+		normalized, err := phone.NormalizeE164(phoneNumber)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Register - Invalid phone: ", err)
+			result.ErrorCode = "400006"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		phoneNumber = normalized
+	} else if phone.IsChannel(otpChannel) {
+		logger.Error(referenceID, "ERROR - Register - Missing phone for otp_channel: ", otpChannel)
+		result.ErrorCode = "400006"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	locale := requestLocale(r, param)
+
+	violations := policy.Validate(referenceID, password, policy.Subject{Username: username, Email: email, FullName: fullName})
+	if len(violations) > 0 {
+		logger.Error(referenceID, "ERROR - Register - Password policy violations: ", violations)
+		result.ErrorCode = "400004"
+		result.ErrorMessage = "Invalid request"
+		result.Payload["violations"] = violations
+		utils.Response(w, result)
+		return
+	}
+
 	conn, err := db.GetConnection()
 	if err != nil {
 		logger.Error(referenceID, "ERROR - Register - Failed to get DB connection: ", err)
@@ -94,14 +150,25 @@ func Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// cek apakah email atau username sudah ada
-	var existingField string
-	queryCheck := `SELECT CASE WHEN EXISTS (SELECT 1 FROM sysuser."user" WHERE username = $1) THEN 'username' WHEN EXISTS (SELECT 1 FROM sysuser."user" WHERE email = $2) THEN 'email' ELSE NULL END AS existing_field;`
-	errCheck := conn.Get(&existingField, queryCheck, username, email)
-	if errCheck == nil && existingField != "" {
-		result.ErrorCode = "409001"
-		result.ErrorMessage = fmt.Sprintf("%s already exists", existingField)
-		utils.Response(w, result)
-		return
+	emailRegistered := false
+	if configs.GetEnumerationProtection() {
+		// cek duplikat ditunda ke langkah email (username dicek di Register_Verify_OTP),
+		// response HTTP dibuat identik untuk akun yang ada maupun tidak
+		queryCheckEmail := `SELECT EXISTS (SELECT 1 FROM sysuser."user" WHERE email = $1)`
+		if err := conn.Get(&emailRegistered, queryCheckEmail, email); err != nil {
+			logger.Error(referenceID, "ERROR - Register - Failed to check existing email: ", err)
+			result.ErrorCode = "500006"
+			result.ErrorMessage = "Internal Server Error"
+			utils.Response(w, result)
+			return
+		}
+	} else {
+		if existingField := existingUserField(conn, username, email, verifiedPhone(otpChannel, phoneNumber)); existingField != "" {
+			result.ErrorCode = "409001"
+			result.ErrorMessage = fmt.Sprintf("%s already exists", existingField)
+			utils.Response(w, result)
+			return
+		}
 	}
 
 	redisClient := rds.GetRedisClient()
@@ -113,7 +180,13 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ttl, err := utils.SendMailLimiter(redisClient, referenceID, email, "Registration OTP", time.Duration(configs.GetOTPExpireTime())*time.Second)
+	// limit dihitung per tujuan OTP (email atau nomor telepon)
+	otpDestination := email
+	if phone.IsChannel(otpChannel) {
+		otpDestination = phoneNumber
+	}
+
+	ttl, err := utils.SendMailLimiter(redisClient, referenceID, otpDestination, "Registration OTP", time.Duration(configs.GetOTPExpireTime())*time.Second)
 	if err != nil {
 		logger.Error(referenceID, "ERROR - Register - ", err)
 		result.ErrorCode = "429001"
@@ -140,6 +213,22 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if emailRegistered {
+		logger.Warning(referenceID, "WARNING - Register - Registration attempted with existing email")
+		_, err = mail.EnqueueTemplate(email, locale, mail.TemplateRegisterExistingAccount, map[string]any{
+			"ResetURL": fmt.Sprintf("%s/reset-password", configs.GetClientURL()),
+		})
+		if err != nil {
+			logger.Warning(referenceID, "WARNING - Register - Failed to queue existing account notice: ", err)
+		}
+
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+		result.Payload["otp_expire_tstamp"] = time.Now().Unix() + int64(configs.GetOTPExpireTime())
+		result.Payload["status"] = "success"
+		utils.Response(w, result)
+		return
+	}
+
 	otpInt, err := utils.RandoNnumberGenerator(6)
 	otp := fmt.Sprintf("%06d", otpInt) // Pastikan selalu 6 digit dengan padding nol jika perlu
 
@@ -152,7 +241,7 @@ func Register(w http.ResponseWriter, r *http.Request) {
 	}
 	logger.Info(referenceID, "INFO - Register - OTP generated: ", otp)
 
-	message := fmt.Sprintf("%s|%s|%s|%s", email, fullName, password, username)
+	message := fmt.Sprintf("%s|%s|%s|%s|%s|%s|%s", email, fullName, password, username, locale, phoneNumber, otpChannel)
 	logger.Info(referenceID, "INFO - Register - message: ", message)
 	logger.Info(referenceID, "INFO - Register - key (otp): ", otp)
 
@@ -179,21 +268,45 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send OTP via SMTP
-	err = mail.SendEmail(email, "OTP Verification", fmt.Sprintf("Your OTP is: %s\nThis will expire in %.0f seconds.", otp, expiry.Seconds()))
-
-	if err != nil {
-		logger.Error(referenceID, "ERROR - Register - Failed to send OTP via email: ", err)
-		result.ErrorCode = "500005"
-		result.ErrorMessage = "Internal Server Error"
-		utils.Response(w, result)
-		return
+	if phone.IsChannel(otpChannel) {
+		// OTP dikirim oleh worker antrian telepon, request tidak menunggu gateway
+		messageID, err := phone.Enqueue(otpChannel, phoneNumber, phone.Format(locale, "registration_otp", otp, int(expiry.Seconds())))
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Register - Failed to queue OTP via ", otpChannel, ": ", err)
+			result.ErrorCode = "500005"
+			result.ErrorMessage = "Internal Server Error"
+			utils.Response(w, result)
+			return
+		}
+
+		logger.Info(referenceID, "INFO - Register - OTP ", otpChannel, " queued, message id: ", messageID)
+	} else {
+		// OTP dikirim oleh worker antrian email, request tidak menunggu SMTP
+		messageID, err := mail.EnqueueTemplate(email, locale, mail.TemplateRegistrationOTP, map[string]any{
+			"FullName":      fullName,
+			"OTP":           otp,
+			"ExpireSeconds": int(expiry.Seconds()),
+		})
+
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Register - Failed to queue OTP email: ", err)
+			result.ErrorCode = "500005"
+			result.ErrorMessage = "Internal Server Error"
+			utils.Response(w, result)
+			return
+		}
+
+		logger.Info(referenceID, "INFO - Register - OTP email queued, message id: ", messageID)
 	}
 
 	// count OTPExpireTstamp
 	OTPExpireTstamp := time.Now().Unix() + int64(configs.GetOTPExpireTime())
 	logger.Info(referenceID, "INFO - Register - calculated OTPExpireTstamp: ", OTPExpireTstamp)
 
+	if configs.GetEnumerationProtection() {
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+	}
+
 	result.Payload["otp_expire_tstamp"] = OTPExpireTstamp
 	result.Payload["status"] = "success"
 
@@ -255,8 +368,8 @@ func Register_Verify_OTP(w http.ResponseWriter, r *http.Request) {
 	// Memisahkan berdasarkan '|'
 	parts := strings.Split(message, "|")
 
-	// Pastikan jumlah bagian sesuai sebelum mengaksesnya
-	if len(parts) != 4 {
+	// Pastikan jumlah bagian sesuai sebelum mengaksesnya (4 / 5 / 6 bagian = format lama tanpa locale / phone / otp_channel)
+	if len(parts) < 4 || len(parts) > 7 {
 		logger.Error(referenceID, "ERROR - Reg_Verify_OTP - Invalid data format in Redis")
 		result.ErrorCode = "500007"
 		result.ErrorMessage = "Internal Server Error"
@@ -268,15 +381,29 @@ func Register_Verify_OTP(w http.ResponseWriter, r *http.Request) {
 	fullName := parts[1]
 	password := parts[2]
 	username := parts[3]
+	locale := mail.DefaultLocale
+	if len(parts) >= 5 {
+		locale = mail.NormalizeLocale(parts[4])
+	}
+	// nomor yang menerima OTP sudah terbukti, selain itu (termasuk format lama) disimpan sebagai pending
+	var phoneNumber, phonePending sql.NullString
+	if len(parts) >= 6 && parts[5] != "" {
+		otpChannel := ""
+		if len(parts) == 7 {
+			otpChannel = parts[6]
+		}
+		if verified := verifiedPhone(otpChannel, parts[5]); verified != nil {
+			phoneNumber = sql.NullString{String: parts[5], Valid: true}
+		} else {
+			phonePending = sql.NullString{String: parts[5], Valid: true}
+		}
+	}
 
 	logger.Info(referenceID, "INFO - Reg_Verify_OTP - username: ", username)
 	logger.Info(referenceID, "INFO - Reg_Verify_OTP - email: ", email)
 	logger.Info(referenceID, "INFO - Reg_Verify_OTP - password: ", password)
 	logger.Info(referenceID, "INFO - Reg_Verify_OTP - full_name: ", fullName)
 
-	salt, _ := utils.RandomStringGenerator(16)
-	saltedPassword, _ := crypto.GeneratePBKDF2(password, salt, 32, configs.GetPBKDF2Iterations())
-
 	conn, err := db.GetConnection()
 	if err != nil {
 		logger.Error(referenceID, "ERROR - Reg_Verify_OTP - Failed to get DB connection: ", err)
@@ -286,9 +413,42 @@ func Register_Verify_OTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	queryToRegister := `INSERT INTO sysuser.user (username, full_name, email, st, salt, saltedpassword, data, role) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;`
-	var newUserId int
-	err = conn.Get(&newUserId, queryToRegister, username, fullName, email, 1, salt, saltedPassword, "{}", "system user")
+	// username / email bisa saja sudah terpakai sejak OTP dikirim (atau cek duplikat ditunda ke langkah ini)
+	existingField := existingUserField(conn, username, email, phoneNumber)
+	if existingField != "" {
+		logger.Error(referenceID, "ERROR - Reg_Verify_OTP - Duplicate ", existingField)
+		redisClient.Del(context.Background(), redisKey)
+
+		if configs.GetEnumerationProtection() {
+			// response sama dengan OTP tidak valid, alasannya hanya dikirim ke email yang sudah dibuktikan lewat OTP
+			_, err = mail.EnqueueTemplate(email, locale, mail.TemplateRegisterUnavailable, map[string]any{
+				"Username": username,
+				"ResetURL": fmt.Sprintf("%s/reset-password", configs.GetClientURL()),
+			})
+			if err != nil {
+				logger.Warning(referenceID, "WARNING - Reg_Verify_OTP - Failed to queue registration unavailable notice: ", err)
+			}
+			result.ErrorCode = "401002"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		result.ErrorCode = "409001"
+		result.ErrorMessage = fmt.Sprintf("%s already exists", existingField)
+		utils.Response(w, result)
+		return
+	}
+
+	newUserId, err := createUser(referenceID, "Reg_Verify_OTP", conn, newUser{
+		Username:     username,
+		FullName:     fullName,
+		Email:        email,
+		Password:     password,
+		Locale:       locale,
+		Phone:        phoneNumber,
+		PhonePending: phonePending,
+	})
 	if err != nil {
 		logger.Error(referenceID, "ERROR - Reg_Verify_OTP - Failed to insert new account: ", err)
 		result.ErrorCode = "500003"
@@ -303,3 +463,63 @@ func Register_Verify_OTP(w http.ResponseWriter, r *http.Request) {
 	result.Payload["success"] = "success"
 	utils.Response(w, result)
 }
+
+// newUser adalah data akun baru, dipakai registrasi OTP dan provisioning dari identity provider
+type newUser struct {
+	Username       string
+	FullName       string
+	Email          string
+	Password       string
+	RandomPassword bool // password dibuat sistem (JIT), user belum mengetahuinya
+	Locale         string
+	Phone          sql.NullString // nomor terverifikasi
+	PhonePending   sql.NullString // nomor yang belum diverifikasi
+	Role           string         // kosong berarti "system user"
+	AuthSource     string         // kosong berarti "local"
+}
+
+// verifiedPhone mengembalikan nomor bila OTP registrasi dikirim ke nomor itu (sudah terbukti milik pendaftar), selain itu nil
+func verifiedPhone(otpChannel, phoneNumber string) any {
+	if phoneNumber == "" || !phone.IsChannel(otpChannel) {
+		return nil
+	}
+	return phoneNumber
+}
+
+// existingUserField mengembalikan kolom unik (username / email / phone) yang sudah dipakai akun lain
+func existingUserField(conn *sqlx.DB, username, email string, phoneNumber any) string {
+	var existingField sql.NullString
+	queryCheck := `SELECT CASE WHEN EXISTS (SELECT 1 FROM sysuser."user" WHERE username = $1) THEN 'username' WHEN EXISTS (SELECT 1 FROM sysuser."user" WHERE email = $2) THEN 'email' WHEN EXISTS (SELECT 1 FROM sysuser."user" WHERE phone = $3) THEN 'phone' ELSE NULL END AS existing_field;`
+	if err := conn.Get(&existingField, queryCheck, username, email, phoneNumber); err != nil {
+		return ""
+	}
+	return existingField.String
+}
+
+// createUser menyimpan akun baru beserta hash password dan riwayat password
+func createUser(referenceID, logTag string, conn *sqlx.DB, user newUser) (int64, error) {
+	salt, _ := utils.RandomStringGenerator(16)
+	saltedPassword, _ := crypto.GeneratePBKDF2(user.Password, salt, 32, configs.GetPBKDF2Iterations())
+
+	role := user.Role
+	if role == "" {
+		role = "system user"
+	}
+	authSource := user.AuthSource
+	if authSource == "" {
+		authSource = authSourceLocal
+	}
+
+	queryToRegister := `INSERT INTO sysuser.user (username, full_name, email, st, salt, saltedpassword, data, role, phone, phone_pending, auth_source, password_set) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id;`
+	var newUserID int64
+	userDataJSON, _ := json.Marshal(map[string]any{"locale": user.Locale})
+	err := conn.Get(&newUserID, queryToRegister, user.Username, user.FullName, user.Email, 1, salt, saltedPassword, string(userDataJSON), role, user.Phone, user.PhonePending, authSource, !user.RandomPassword)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := policy.RecordHistory(conn, newUserID, salt, saltedPassword); err != nil {
+		logger.Warning(referenceID, "WARNING - ", logTag, " - Failed to record password history: ", err)
+	}
+	return newUserID, nil
+}
@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/mail"
+	"auth_service/phone"
+	"auth_service/policy"
+	"auth_service/rds"
+	"auth_service/utils"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// !NOTE : reset password procedure
+/*	1. Client send request for reset password with email (or phone + channel sms / whatsapp)
+2. Server check if email is exist in database , if exist, generate random token, store only its SHA-256 and send url with the token via email
+3. Client receive email and click the link, then user will be redirected to reset password page fill new password and send it back to server
+4. Server look up the token hash, check expiry and used flag, then consume the token and update password in database
+*/
+
+/*
+CREATE TABLE sysuser.password_reset (
+    token_hash character varying(64) PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES sysuser."user"(id) ON DELETE CASCADE,
+    expire_tstamp bigint NOT NULL,
+    used boolean NOT NULL DEFAULT false,
+    used_tstamp bigint,
+    create_tstamp bigint NOT NULL
+);
+CREATE INDEX password_reset_user_id_idx ON sysuser.password_reset (user_id) WHERE NOT used;
+*/
+
+// panjang token reset dalam byte sebelum di-encode (256 bit)
+const resetTokenBytes = 32
+
+// invalidatePasswordResetTokens menghapus semua token reset user yang belum dipakai, dipanggil saat reset baru
+// diterbitkan atau password berubah. Baris dihapus (bukan ditandai used) supaya link yang tergantikan
+// dilaporkan tidak valid, status "used" hanya untuk link yang benar-benar sudah dipakai.
+func invalidatePasswordResetTokens(conn sqlx.Execer, userID int64) (int64, error) {
+	res, err := conn.Exec(`DELETE FROM sysuser.password_reset WHERE user_id = $1 AND NOT used`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func Reset_Password(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Reset_Password - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	// reset bisa lewat email atau nomor telepon (channel sms / whatsapp)
+	email, _ := param["email"].(string)
+	phoneNumber, _ := param["phone"].(string)
+	channel, _ := param["channel"].(string)
+
+	if phoneNumber != "" {
+		normalized, err := phone.NormalizeE164(phoneNumber)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Reset_Password - Invalid phone: ", err)
+			result.ErrorCode = "400002"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		phoneNumber = normalized
+		if channel == "" {
+			channel = phone.ChannelSMS
+		}
+		if !phone.IsChannel(channel) {
+			logger.Error(referenceID, "ERROR - Reset_Password - Invalid channel: ", channel)
+			result.ErrorCode = "400003"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+	} else if email == "" {
+		logger.Error(referenceID, "ERROR - Reset_Password - Missing email")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	destination := email
+	if phoneNumber != "" {
+		destination = phoneNumber
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - ResetPassword - Redis client is not initialized")
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if ttl, err := utils.SendMailLimiter(redisClient, referenceID, destination, "Reset Password", time.Duration(configs.GetOTPExpireTime())*time.Second); err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password - ", err)
+		result.ErrorCode = "429001"
+		result.ErrorMessage = fmt.Sprintf("%s. Please try again in %d seconds", err.Error(), int(ttl.Seconds()))
+		result.Payload["remaining_time"] = int(ttl.Seconds())
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var userID int64
+	var fullName string
+	var locale string
+	queryGetUser := `SELECT id, full_name, COALESCE(data->>'locale', '') FROM sysuser.user WHERE email = $1`
+	if phoneNumber != "" {
+		queryGetUser = `SELECT id, full_name, COALESCE(data->>'locale', '') FROM sysuser.user WHERE phone = $1`
+	}
+	err = conn.QueryRow(queryGetUser, destination).Scan(&userID, &fullName, &locale)
+	if err == sql.ErrNoRows && configs.GetEnumerationProtection() {
+		// response dibuat sama persis dengan email yang terdaftar
+		logger.Warning(referenceID, "WARNING - ResetPassword - Reset requested for unknown account")
+		// notifikasi hanya lewat email, SMS ke nomor acak tidak dikirim
+		if configs.GetNotifyUnknownResetAttempt() && phoneNumber == "" {
+			_, err = mail.EnqueueTemplate(email, requestLocale(r, param), mail.TemplateResetUnknownAccount, map[string]any{})
+			if err != nil {
+				logger.Warning(referenceID, "WARNING - ResetPassword - Failed to queue unknown account notice: ", err)
+			}
+		}
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+		result.Payload["status"] = "success"
+		utils.Response(w, result)
+		return
+	} else if err == sql.ErrNoRows {
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - ResetPassword - Query failed: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	resetToken, err := utils.SecureTokenGenerator(resetTokenBytes)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - ResetPassword - Failed to generate reset token: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// hanya link terbaru yang berlaku
+	invalidated, err := invalidatePasswordResetTokens(conn, userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - ResetPassword - Failed to invalidate previous reset tokens: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	logger.Info(referenceID, "INFO - ResetPassword - Invalidated previous reset tokens: ", invalidated)
+
+	expiry := time.Duration(configs.GetResetPassExpTime()) * time.Second
+	URLExpireTstamp := time.Now().Add(expiry).Unix()
+
+	queryInsertToken := `INSERT INTO sysuser.password_reset (token_hash, user_id, expire_tstamp, used, create_tstamp) VALUES ($1, $2, $3, false, $4)`
+	if _, err := conn.Exec(queryInsertToken, crypto.HashSHA256(resetToken), userID, URLExpireTstamp, time.Now().Unix()); err != nil {
+		logger.Error(referenceID, "ERROR - ResetPassword - Failed to store reset token: ", err)
+		result.ErrorCode = "500007"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - ResetPassword - URL expire timestamp: ", URLExpireTstamp)
+
+	clientURL := fmt.Sprintf("%s/reset-password-confirm/%s", configs.GetClientURL(), resetToken)
+
+	if phoneNumber != "" {
+		messageID, err := phone.Enqueue(channel, phoneNumber, phone.Format(mail.NormalizeLocale(locale), "reset_link", clientURL, int(expiry.Minutes())))
+		if err != nil {
+			logger.Error(referenceID, "ERROR - ResetPassword - Failed to queue reset link via ", channel, ": ", err)
+			result.ErrorCode = "500008"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+		logger.Info(referenceID, "INFO - ResetPassword - Reset link ", channel, " queued, message id: ", messageID)
+	} else {
+		messageID, err := mail.EnqueueTemplate(email, locale, mail.TemplateResetLink, map[string]any{
+			"FullName":      fullName,
+			"URL":           clientURL,
+			"ExpireMinutes": int(expiry.Minutes()),
+		})
+		if err != nil {
+			logger.Error(referenceID, "ERROR - ResetPassword - Failed to queue email: ", err)
+			result.ErrorCode = "500008"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+		logger.Info(referenceID, "INFO - ResetPassword - Reset email queued, message id: ", messageID)
+	}
+
+	if configs.GetEnumerationProtection() {
+		utils.PadResponseTime(startTime, time.Duration(configs.GetUniformResponseTime())*time.Millisecond)
+	}
+
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
+
+func Reset_Password_Verify_URL(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Reset_Password_Verify_URL - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	newPassword, ok := param["new_password"].(string)
+	if !ok || newPassword == "" {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Missing or invalid new password")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	// "url_signature" dipertahankan untuk client lama
+	resetToken, ok := param["token"].(string)
+	if !ok || resetToken == "" {
+		resetToken, ok = param["url_signature"].(string)
+	}
+	if !ok || resetToken == "" {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Missing token")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	tokenHash := crypto.HashSHA256(resetToken)
+
+	var userID int64
+	var expireTstamp int64
+	var used bool
+	queryGetToken := `SELECT user_id, expire_tstamp, used FROM sysuser.password_reset WHERE token_hash = $1`
+	err = conn.QueryRow(queryGetToken, tokenHash).Scan(&userID, &expireTstamp, &used)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Invalid reset token: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if used {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Reset link already used")
+		result.ErrorCode = "410002"
+		result.ErrorMessage = "Gone"
+		result.Payload["reason"] = "used"
+		utils.Response(w, result)
+		return
+	}
+
+	if time.Now().Unix() > expireTstamp {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Reset link expired")
+		result.ErrorCode = "410001"
+		result.ErrorMessage = "Gone"
+		result.Payload["reason"] = "expired"
+		utils.Response(w, result)
+		return
+	}
+
+	var subject policy.Subject
+	var authSource string
+	queryGetUser := `SELECT username, email, full_name, auth_source FROM sysuser."user" WHERE id = $1`
+	err = conn.QueryRow(queryGetUser, userID).Scan(&subject.Username, &subject.Email, &subject.FullName, &authSource)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - User not found: ", err)
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if !passwordManagedLocally(authSource) {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Password is managed by ", authSource)
+		result.ErrorCode = "403001"
+		result.ErrorMessage = "Forbidden"
+		utils.Response(w, result)
+		return
+	}
+
+	violations := policy.Validate(referenceID, newPassword, subject)
+	reused, err := policy.IsReused(conn, userID, newPassword)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Password history check failed: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if reused {
+		violations = append(violations, policy.Reused)
+	}
+
+	if len(violations) > 0 {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Password policy violations: ", violations)
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid request"
+		result.Payload["violations"] = violations
+		utils.Response(w, result)
+		return
+	}
+
+	salt, _ := utils.RandomStringGenerator(16)
+	hashedPassword, _ := crypto.GeneratePBKDF2(newPassword, salt, 32, configs.GetPBKDF2Iterations())
+
+	tx, err := conn.Beginx()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Failed to begin transaction: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	// consume token secara atomik, request paralel dengan token yang sama hanya satu yang lolos
+	queryConsumeToken := `UPDATE sysuser.password_reset SET used = true, used_tstamp = $2 WHERE token_hash = $1 AND NOT used AND expire_tstamp >= $2`
+	res, err := tx.Exec(queryConsumeToken, tokenHash, time.Now().Unix())
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Failed to consume reset token: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if consumed, _ := res.RowsAffected(); consumed == 0 {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Reset token consumed concurrently")
+		result.ErrorCode = "410002"
+		result.ErrorMessage = "Gone"
+		utils.Response(w, result)
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE sysuser."user" SET saltedpassword = $1, salt = $2, password_set = true WHERE id = $3`, hashedPassword, salt, userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Failed to update password: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if _, err := invalidatePasswordResetTokens(tx, userID); err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Failed to invalidate reset tokens: ", err)
+		result.ErrorCode = "500007"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Verify_URL - Failed to commit password update: ", err)
+		result.ErrorCode = "500008"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := policy.RecordHistory(conn, userID, salt, hashedPassword); err != nil {
+		logger.Warning(referenceID, "WARNING - Reset_Password_Verify_URL - Failed to record password history: ", err)
+	}
+
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
+
+/*
+Validasi link reset sebelum user mengisi password baru, token TIDAK di-consume.
+
+	GET  /reset-password/validate?token=<token>
+	POST /reset-password/validate  { "token" : "<token>" }
+*/
+func Reset_Password_Validate(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Reset_Password_Validate - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		logger.Error(referenceID, "ERROR - Reset_Password_Validate - Invalid method: ", r.Method)
+		result.ErrorCode = "405001"
+		result.ErrorMessage = "Method Not Allowed"
+		utils.Response(w, result)
+		return
+	}
+
+	resetToken := r.URL.Query().Get("token")
+	if resetToken == "" && r.Method == http.MethodPost {
+		param, _ := utils.Request(r)
+		resetToken, _ = param["token"].(string)
+		if resetToken == "" {
+			resetToken, _ = param["url_signature"].(string)
+		}
+	}
+
+	if resetToken == "" {
+		logger.Error(referenceID, "ERROR - Reset_Password_Validate - Missing token")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Validate - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var email string
+	var expireTstamp int64
+	var used bool
+	queryGetToken := `
+		SELECT u.email, pr.expire_tstamp, pr.used
+		FROM sysuser.password_reset pr
+		JOIN sysuser."user" u ON u.id = pr.user_id
+		WHERE pr.token_hash = $1`
+	err = conn.QueryRow(queryGetToken, crypto.HashSHA256(resetToken)).Scan(&email, &expireTstamp, &used)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Reset_Password_Validate - Invalid reset token: ", err)
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if used {
+		logger.Error(referenceID, "ERROR - Reset_Password_Validate - Reset link already used")
+		result.ErrorCode = "410002"
+		result.ErrorMessage = "Gone"
+		result.Payload["reason"] = "used"
+		utils.Response(w, result)
+		return
+	}
+
+	remainingTime := expireTstamp - time.Now().Unix()
+	if remainingTime <= 0 {
+		logger.Error(referenceID, "ERROR - Reset_Password_Validate - Reset link expired")
+		result.ErrorCode = "410001"
+		result.ErrorMessage = "Gone"
+		result.Payload["reason"] = "expired"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["status"] = "valid"
+	result.Payload["email"] = utils.MaskEmail(email)
+	result.Payload["remaining_time"] = remainingTime
+	result.Payload["expire_tstamp"] = expireTstamp
+	utils.Response(w, result)
+}
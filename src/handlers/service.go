@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/utils"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Endpoint service-to-service, hanya dipasang di belakang middlewares.RequireScope (access token client_credentials).
+// Scope yang dipakai:
+//	users.read  /service/users        cari akun berdasarkan id, username atau email
+//	mail.read   /service/mail/status  status pengiriman email berdasarkan message id (Mail_Status)
+
+type serviceUser struct {
+	ID         int64  `db:"id"`
+	Username   string `db:"username"`
+	Email      string `db:"email"`
+	Phone      string `db:"phone"`
+	FullName   string `db:"full_name"`
+	Role       string `db:"role"`
+	St         int    `db:"st"`
+	AuthSource string `db:"auth_source"`
+}
+
+// Service_User_Lookup mengembalikan data akun, ?id=<id> atau ?username=<username> atau ?email=<email>
+func Service_User_Lookup(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Service_User_Lookup - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	clientID, _ := r.Context().Value(HTTPContextKey("clientID")).(string)
+
+	query := r.URL.Query()
+	var column string
+	var value any
+	switch {
+	case query.Get("id") != "":
+		id, err := strconv.ParseInt(query.Get("id"), 10, 64)
+		if err != nil {
+			logger.Error(referenceID, "ERROR - Service_User_Lookup - Invalid id: ", query.Get("id"))
+			result.ErrorCode = "400001"
+			result.ErrorMessage = "Invalid request"
+			utils.Response(w, result)
+			return
+		}
+		column, value = "id", id
+	case query.Get("username") != "":
+		column, value = "username", query.Get("username")
+	case query.Get("email") != "":
+		column, value = "email", query.Get("email")
+	default:
+		logger.Error(referenceID, "ERROR - Service_User_Lookup - Missing id, username or email")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Service_User_Lookup - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// column hanya salah satu nilai tetap di atas, bukan input pengguna
+	queryGetUser := `SELECT id, username, COALESCE(email, '') AS email, COALESCE(phone, '') AS phone, full_name, role, st, auth_source FROM sysuser."user" WHERE ` + column + ` = $1`
+
+	var user serviceUser
+	err = conn.Get(&user, queryGetUser, value)
+	if err == sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - Service_User_Lookup - User not found, client: ", clientID)
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Service_User_Lookup - Failed to load user: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - Service_User_Lookup - User ", user.ID, " read by client: ", clientID)
+	result.Payload["user"] = map[string]any{
+		"id":          user.ID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"phone":       user.Phone,
+		"full_name":   user.FullName,
+		"role":        user.Role,
+		"active":      user.St == 1,
+		"auth_source": user.AuthSource,
+	}
+	utils.Response(w, result)
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/logger"
+	"auth_service/mail"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// createSession membuat atau mengganti sesi user lalu mengisi payload seperti Verify_Token.
+// sessionKey dipakai sebagai teks HMAC session_hash (token login pada alur nonce).
+// Mengembalikan false bila gagal, result sudah berisi error code untuk dikirim ke client.
+func createSession(referenceID, logTag string, r *http.Request, conn *sqlx.DB, userID int64, sessionKey string, result *utils.ResultFormat) bool {
+	// Generate session ID and session hash
+	sessionID, errMsg := utils.RandomStringGenerator(16)
+	if errMsg != nil {
+		logger.Error(referenceID, "ERROR - ", logTag, " - Session ID generation failed")
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		return false
+	}
+
+	sessionHash, errMsg := crypto.GenerateHMAC(sessionKey, sessionID)
+	if errMsg != nil {
+		logger.Error(referenceID, "ERROR - ", logTag, " - HMAC computation failed")
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		return false
+	}
+
+	// Create or update session
+	currentTime := time.Now().Unix()
+	queryUpsertSession := `
+		INSERT INTO sysuser.session (session_id, user_id, session_hash, tstamp, st)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			session_id = EXCLUDED.session_id,
+			session_hash = EXCLUDED.session_hash,
+			tstamp = EXCLUDED.tstamp,
+			st = EXCLUDED.st`
+
+	if _, err := conn.Exec(queryUpsertSession, sessionID, userID, sessionHash, currentTime, 1); err != nil {
+		logger.Error(referenceID, "ERROR - ", logTag, " - Session upsert failed")
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		return false
+	}
+
+	// Fetch user data
+	var userData UserData
+	queryGetUserData := `SELECT username, email, COALESCE(phone, '') AS phone, full_name, role, COALESCE(data, '{}'::jsonb) AS data FROM sysuser.user WHERE id = $1`
+	if err := conn.Get(&userData, queryGetUserData, userID); err != nil {
+		logger.Error(referenceID, "ERROR - ", logTag, " - User not found", err)
+		result.ErrorCode = "401000"
+		result.ErrorMessage = "Unauthorized"
+		return false
+	}
+
+	// Prepare response payload
+	result.Payload["session_id"] = sessionID
+	result.Payload["session_hash"] = sessionHash
+	result.Payload["username"] = userData.Username
+	result.Payload["full_name"] = userData.FullName
+	result.Payload["email"] = userData.Email
+	result.Payload["phone"] = userData.Phone
+	result.Payload["role"] = userData.Role
+	result.Payload["data"] = userData.Data
+
+	notifyNewDevice(referenceID, logTag, r, conn, userID, userData)
+	return true
+}
+
+// notifyNewDevice mengirim email new_device_login bila user-agent belum pernah dipakai login oleh user ini.
+// Perangkat dicatat di Redis set login_devices:<user_id>, login pertama (belum ada perangkat tercatat) tidak dikirimi email.
+func notifyNewDevice(referenceID, logTag string, r *http.Request, conn *sqlx.DB, userID int64, userData UserData) {
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		return
+	}
+
+	ctx := context.Background()
+	devicesKey := fmt.Sprintf("login_devices:%d", userID)
+	known, err := redisClient.Exists(ctx, devicesKey).Result()
+	if err != nil {
+		logger.Warning(referenceID, "WARNING - ", logTag, " - Failed to read known devices: ", err)
+		return
+	}
+	added, err := redisClient.SAdd(ctx, devicesKey, crypto.HashSHA256(r.UserAgent())).Result()
+	if err != nil {
+		logger.Warning(referenceID, "WARNING - ", logTag, " - Failed to record login device: ", err)
+		return
+	}
+	redisClient.Expire(ctx, devicesKey, time.Duration(configs.GetKnownDeviceRetention())*time.Second)
+
+	if known == 0 || added == 0 {
+		return
+	}
+
+	_, err = mail.EnqueueTemplate(userData.Email, userLocale(conn, userID), mail.TemplateNewDeviceLogin, map[string]any{
+		"FullName":  userData.FullName,
+		"Username":  userData.Username,
+		"Time":      time.Now().Format(time.RFC1123),
+		"IPAddress": utils.ClientIP(r, configs.GetTrustProxyHeaders()),
+		"UserAgent": r.UserAgent(),
+	})
+	if err != nil {
+		logger.Warning(referenceID, "WARNING - ", logTag, " - Failed to queue new device notice: ", err)
+	}
+}
+
+// completeLogin dipanggil setelah faktor pertama lolos (token nonce, magic link, dst).
+// User dengan TOTP aktif mendapat mfa_token untuk Verify_TOTP, selain itu sesi langsung dibuat.
+// Akun yang sedang terkunci (lihat loginLockout.go) ditolak.
+func completeLogin(referenceID, logTag string, r *http.Request, conn *sqlx.DB, userID int64, sessionKey string, result *utils.ResultFormat) bool {
+	remaining, err := loginLockRemaining(userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - ", logTag, " - Failed to read lockout status", err)
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		return false
+	}
+	if remaining > 0 {
+		logger.Error(referenceID, "ERROR - ", logTag, " - Account is locked: ", userID)
+		result.ErrorCode = "429000"
+		result.ErrorMessage = "Too many attempts"
+		result.Payload["remaining_time"] = int(remaining.Seconds())
+		return false
+	}
+
+	var totpEnabled bool
+	if err := conn.Get(&totpEnabled, `SELECT totp_enabled FROM sysuser.user WHERE id = $1`, userID); err != nil {
+		logger.Error(referenceID, "ERROR - ", logTag, " - Failed to read 2FA status", err)
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		return false
+	}
+
+	if !totpEnabled {
+		return createSession(referenceID, logTag, r, conn, userID, sessionKey, result)
+	}
+
+	mfaToken, expireTstamp, err := createMFAChallenge(userID, sessionKey)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - ", logTag, " - Failed to create 2FA challenge", err)
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		return false
+	}
+
+	result.Payload["mfa_required"] = true
+	result.Payload["mfa_token"] = mfaToken
+	result.Payload["mfa_methods"] = []string{"totp"}
+	result.Payload["mfa_expire_tstamp"] = expireTstamp
+	return true
+}
@@ -0,0 +1,721 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/*
+ALTER TABLE sysuser."user" ADD COLUMN totp_secret character varying(255);          -- AES-GCM (crypto.EncryptSecret)
+ALTER TABLE sysuser."user" ADD COLUMN totp_pending_secret character varying(255);  -- secret enrollment yang belum dikonfirmasi
+ALTER TABLE sysuser."user" ADD COLUMN totp_enabled boolean NOT NULL DEFAULT false;
+ALTER TABLE sysuser."user" ADD COLUMN totp_last_step bigint;                       -- time-step terakhir yang dipakai (anti replay)
+*/
+
+// !NOTE : login dengan TOTP
+/*	1. Login -> Verify_Token seperti biasa
+2. Bila totp_enabled, Verify_Token TIDAK membuat sesi, melainkan mengembalikan mfa_required + mfa_token
+3. Client kirim mfa_token + code ke /verify-token/totp, sesi dibuat setelah kode valid
+4. Bila authenticator hilang, recovery_code (sekali pakai) bisa dikirim sebagai pengganti code
+*/
+
+type mfaChallenge struct {
+	UserID     int64  `json:"user_id"`
+	SessionKey string `json:"session_key"`
+}
+
+func mfaChallengeKey(mfaToken string) string {
+	return "mfa_pending:" + crypto.HashSHA256(mfaToken)
+}
+
+func mfaAttemptsKey(mfaToken string) string {
+	return "mfa_attempts:" + crypto.HashSHA256(mfaToken)
+}
+
+// createMFAChallenge menyimpan state login yang menunggu faktor kedua di Redis
+func createMFAChallenge(userID int64, sessionKey string) (string, int64, error) {
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		return "", 0, errors.New("redis client is not initialized")
+	}
+
+	mfaToken, err := utils.SecureTokenGenerator(32)
+	if err != nil {
+		return "", 0, err
+	}
+
+	value, _ := json.Marshal(mfaChallenge{UserID: userID, SessionKey: sessionKey})
+	expiry := time.Duration(configs.GetMFAPendingExpireTime()) * time.Second
+	if err := redisClient.Set(context.Background(), mfaChallengeKey(mfaToken), value, expiry).Err(); err != nil {
+		return "", 0, err
+	}
+
+	return mfaToken, time.Now().Add(expiry).Unix(), nil
+}
+
+// verifyTOTPCode mencocokkan kode dengan secret aktif user dan mencatat time-step yang dipakai.
+// Kode dengan step yang sama atau lebih lama dari step terakhir ditolak (replay).
+func verifyTOTPCode(conn *sqlx.DB, userID int64, code string) (bool, error) {
+	var encryptedSecret sql.NullString
+	if err := conn.Get(&encryptedSecret, `SELECT totp_secret FROM sysuser."user" WHERE id = $1 AND totp_enabled`, userID); err != nil {
+		return false, err
+	}
+	if !encryptedSecret.Valid {
+		return false, errors.New("TOTP secret is not set")
+	}
+
+	secret, err := crypto.DecryptSecret(encryptedSecret.String)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := crypto.ValidateTOTP(secret, code, time.Now(), configs.GetTOTPSkew())
+	if !ok {
+		return false, nil
+	}
+
+	// update kondisional, request paralel dengan kode yang sama hanya satu yang lolos
+	res, err := conn.Exec(`UPDATE sysuser."user" SET totp_last_step = $2 WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	updated, _ := res.RowsAffected()
+	return updated == 1, nil
+}
+
+/*
+{
+	"mfa_token" : "<dari payload Verify_Token>",
+	"code" : "123456"
+}
+atau
+{
+	"mfa_token" : "<dari payload Verify_Token>",
+	"recovery_code" : "abcde-fgh23"
+}
+*/
+
+func Verify_TOTP(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - Verify_TOTP - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	mfaToken, ok := param["mfa_token"].(string)
+	if !ok || mfaToken == "" {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Missing mfa_token")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	code, _ := param["code"].(string)
+	recoveryCode, _ := param["recovery_code"].(string)
+	if code == "" && recoveryCode == "" {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Missing code")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	ctx := context.Background()
+	challengeKey := mfaChallengeKey(mfaToken)
+	attemptsKey := mfaAttemptsKey(mfaToken)
+
+	value, err := redisClient.Get(ctx, challengeKey).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - 2FA challenge not found or expired: ", err)
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	var challenge mfaChallenge
+	if err := json.Unmarshal([]byte(value), &challenge); err != nil {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Invalid 2FA challenge data: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	remaining, err := loginLockRemaining(challenge.UserID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Failed to read lockout status: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if remaining > 0 {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Account is locked, challenge revoked")
+		redisClient.Del(ctx, challengeKey, attemptsKey)
+		result.ErrorCode = "429002"
+		result.ErrorMessage = "Too many attempts"
+		result.Payload["remaining_time"] = int(remaining.Seconds())
+		utils.Response(w, result)
+		return
+	}
+
+	attempts, err := redisClient.Incr(ctx, attemptsKey).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Failed to count attempts: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	redisClient.Expire(ctx, attemptsKey, time.Duration(configs.GetMFAPendingExpireTime())*time.Second)
+
+	if attempts > configs.GetMFAMaxAttempts() {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Too many attempts, challenge revoked")
+		redisClient.Del(ctx, challengeKey, attemptsKey)
+		result.ErrorCode = "429001"
+		result.ErrorMessage = "Too many attempts. Please login again"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Failed to get DB connection: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var valid bool
+	if code != "" {
+		valid, err = verifyTOTPCode(conn, challenge.UserID, code)
+	} else {
+		valid, err = consumeRecoveryCode(conn, challenge.UserID, recoveryCode)
+	}
+	if err != nil {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Second factor verification failed: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if !valid {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - Invalid or reused code")
+		if locked, err := recordLoginFailure(referenceID, conn, challenge.UserID); err != nil {
+			logger.Warning(referenceID, "WARNING - Verify_TOTP - Failed to record failed attempt: ", err)
+		} else if locked {
+			redisClient.Del(ctx, challengeKey, attemptsKey)
+			result.ErrorCode = "429002"
+			result.ErrorMessage = "Too many attempts"
+			result.Payload["remaining_time"] = configs.GetLoginLockoutTime()
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		result.Payload["remaining_attempts"] = configs.GetMFAMaxAttempts() - attempts
+		utils.Response(w, result)
+		return
+	}
+
+	// challenge hanya boleh dipakai sekali
+	if deleted, _ := redisClient.Del(ctx, challengeKey).Result(); deleted == 0 {
+		logger.Error(referenceID, "ERROR - Verify_TOTP - 2FA challenge already consumed")
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+	redisClient.Del(ctx, attemptsKey)
+	clearLoginFailures(challenge.UserID)
+
+	if !createSession(referenceID, "Verify_TOTP", r, conn, challenge.UserID, challenge.SessionKey, &result) {
+		utils.Response(w, result)
+		return
+	}
+
+	if code == "" {
+		remaining, err := remainingRecoveryCodes(conn, challenge.UserID)
+		if err != nil {
+			logger.Warning(referenceID, "WARNING - Verify_TOTP - Failed to count recovery codes: ", err)
+		} else {
+			logger.Info(referenceID, "INFO - Verify_TOTP - Login with recovery code, remaining: ", remaining)
+			result.Payload["recovery_codes_remaining"] = remaining
+			notifyRecoveryCodeUsed(referenceID, conn, challenge.UserID, remaining)
+		}
+	}
+
+	utils.Response(w, result)
+}
+
+/*
+Enroll / re-enroll TOTP, butuh sesi + password saat ini.
+Secret baru disimpan sebagai pending, secret lama (bila ada) tetap aktif sampai TOTP_Confirm.
+{
+	"password" : "master123"
+}
+*/
+
+func TOTP_Enroll(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - TOTP_Enroll - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	password, ok := param["password"].(string)
+	if !ok || password == "" {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - Missing password")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var userData userPasswordData
+	queryGetUser := `SELECT username, email, full_name, salt, saltedpassword FROM sysuser."user" WHERE id = $1`
+	if err := conn.Get(&userData, queryGetUser, userID); err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - User not found: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if !passwordMatches(password, userData.Salt, userData.SaltedPassword) {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - Password mismatch")
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	secret, err := crypto.GenerateTOTPSecret()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - Failed to generate secret: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	encryptedSecret, err := crypto.EncryptSecret(secret)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - Failed to encrypt secret: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if _, err := conn.Exec(`UPDATE sysuser."user" SET totp_pending_secret = $1 WHERE id = $2`, encryptedSecret, userID); err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Enroll - Failed to store pending secret: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["secret"] = secret
+	result.Payload["otpauth_uri"] = crypto.TOTPURI(configs.GetTOTPIssuer(), userData.Username, secret)
+	result.Payload["digits"] = crypto.TOTPDigits
+	result.Payload["period"] = crypto.TOTPPeriod
+	utils.Response(w, result)
+}
+
+/*
+Konfirmasi enrollment dengan kode pertama dari authenticator app.
+{
+	"code" : "123456"
+}
+*/
+
+func TOTP_Confirm(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - TOTP_Confirm - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	code, ok := param["code"].(string)
+	if !ok || code == "" {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Missing code")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	// kode TOTP hanya 6 digit, percobaan salah ikut dihitung di penguncian login (loginLockout.go)
+	remaining, err := loginLockRemaining(userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Failed to read lockout status: ", err)
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if remaining > 0 {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Account is locked")
+		result.ErrorCode = "429001"
+		result.ErrorMessage = "Too many attempts"
+		result.Payload["remaining_time"] = int(remaining.Seconds())
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var pendingSecret sql.NullString
+	if err := conn.Get(&pendingSecret, `SELECT totp_pending_secret FROM sysuser."user" WHERE id = $1`, userID); err != nil || !pendingSecret.Valid {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - No pending enrollment: ", err)
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	secret, err := crypto.DecryptSecret(pendingSecret.String)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Failed to decrypt pending secret: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	step, valid := crypto.ValidateTOTP(secret, code, time.Now(), configs.GetTOTPSkew())
+	if !valid {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Invalid code")
+		if locked, err := recordLoginFailure(referenceID, conn, userID); err != nil {
+			logger.Warning(referenceID, "WARNING - TOTP_Confirm - Failed to record failed attempt: ", err)
+		} else if locked {
+			result.ErrorCode = "429001"
+			result.ErrorMessage = "Too many attempts"
+			result.Payload["remaining_time"] = configs.GetLoginLockoutTime()
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	queryActivate := `
+		UPDATE sysuser."user"
+		SET totp_secret = totp_pending_secret, totp_pending_secret = NULL, totp_enabled = true, totp_last_step = $2
+		WHERE id = $1 AND totp_pending_secret = $3`
+	tx, err := conn.Beginx()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Failed to begin transaction: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(queryActivate, userID, step, pendingSecret.String)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Failed to activate TOTP: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if activated, _ := res.RowsAffected(); activated == 0 {
+		// enrollment diganti oleh request lain di antara select dan update
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Pending enrollment changed")
+		result.ErrorCode = "409001"
+		result.ErrorMessage = "Conflict"
+		utils.Response(w, result)
+		return
+	}
+
+	// set kode pemulihan baru setiap enrollment, hanya ditampilkan sekali di response ini
+	recoveryCodes, err := generateRecoveryCodes(tx, userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Failed to generate recovery codes: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Confirm - Failed to commit: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	clearLoginFailures(userID)
+
+	result.Payload["status"] = "success"
+	result.Payload["totp_enabled"] = true
+	result.Payload["recovery_codes"] = recoveryCodes
+	utils.Response(w, result)
+}
+
+/*
+Nonaktifkan TOTP, butuh password saat ini dan kode TOTP yang valid.
+{
+	"password" : "master123",
+	"code" : "123456"
+}
+*/
+
+func TOTP_Disable(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - TOTP_Disable - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	password, ok := param["password"].(string)
+	if !ok || password == "" {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Missing password")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	code, ok := param["code"].(string)
+	if !ok || code == "" {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Missing code")
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	// kode TOTP hanya 6 digit, percobaan salah ikut dihitung di penguncian login (loginLockout.go)
+	remaining, err := loginLockRemaining(userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Failed to read lockout status: ", err)
+		result.ErrorCode = "500000"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if remaining > 0 {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Account is locked")
+		result.ErrorCode = "429001"
+		result.ErrorMessage = "Too many attempts"
+		result.Payload["remaining_time"] = int(remaining.Seconds())
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var userData userPasswordData
+	queryGetUser := `SELECT username, email, full_name, salt, saltedpassword FROM sysuser."user" WHERE id = $1`
+	if err := conn.Get(&userData, queryGetUser, userID); err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - User not found: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if !passwordMatches(password, userData.Salt, userData.SaltedPassword) {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Password mismatch")
+		if locked, err := recordLoginFailure(referenceID, conn, userID); err != nil {
+			logger.Warning(referenceID, "WARNING - TOTP_Disable - Failed to record failed attempt: ", err)
+		} else if locked {
+			result.ErrorCode = "429001"
+			result.ErrorMessage = "Too many attempts"
+			result.Payload["remaining_time"] = configs.GetLoginLockoutTime()
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	valid, err := verifyTOTPCode(conn, userID, code)
+	if err == sql.ErrNoRows {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - TOTP is not enabled")
+		result.ErrorCode = "400003"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	} else if err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - TOTP verification failed: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if !valid {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Invalid or reused code")
+		if locked, err := recordLoginFailure(referenceID, conn, userID); err != nil {
+			logger.Warning(referenceID, "WARNING - TOTP_Disable - Failed to record failed attempt: ", err)
+		} else if locked {
+			result.ErrorCode = "429001"
+			result.ErrorMessage = "Too many attempts"
+			result.Payload["remaining_time"] = configs.GetLoginLockoutTime()
+			utils.Response(w, result)
+			return
+		}
+		result.ErrorCode = "401004"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	queryDisable := `UPDATE sysuser."user" SET totp_secret = NULL, totp_pending_secret = NULL, totp_enabled = false, totp_last_step = NULL WHERE id = $1`
+	if _, err := conn.Exec(queryDisable, userID); err != nil {
+		logger.Error(referenceID, "ERROR - TOTP_Disable - Failed to disable TOTP: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	if _, err := conn.Exec(`DELETE FROM sysuser.recovery_code WHERE user_id = $1`, userID); err != nil {
+		logger.Warning(referenceID, "WARNING - TOTP_Disable - Failed to delete recovery codes: ", err)
+	}
+
+	clearLoginFailures(userID)
+
+	result.Payload["status"] = "success"
+	result.Payload["totp_enabled"] = false
+	utils.Response(w, result)
+}
@@ -0,0 +1,785 @@
+package handlers
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/logger"
+	"auth_service/rds"
+	"auth_service/utils"
+	"auth_service/webauthn"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE sysuser.webauthn_credential (
+	id serial4 NOT NULL,
+	user_id int8 NOT NULL,
+	credential_id character varying(1400) NOT NULL, -- base64url tanpa padding
+	public_key bytea NOT NULL,                      -- COSE key
+	sign_count int8 NOT NULL DEFAULT 0,
+	aaguid character varying(36) NULL,
+	attestation_format character varying(16) NOT NULL,
+	transports character varying(128) NULL,         -- dipisah koma, dikirim ulang di allowCredentials
+	name character varying(64) NULL,
+	create_tstamp int8 NOT NULL,
+	last_used_tstamp int8 NULL,
+	CONSTRAINT webauthn_credential_pkey PRIMARY KEY (id),
+	CONSTRAINT webauthn_credential_id_unique UNIQUE (credential_id),
+	CONSTRAINT webauthn_credential_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX webauthn_credential_user_idx ON sysuser.webauthn_credential (user_id);
+*/
+
+// !NOTE : alur passkey
+/*	REGISTRASI (butuh sesi)
+	1. /account/webauthn/register/options -> public_key untuk navigator.credentials.create()
+	2. /account/webauthn/register/verify  -> kirim hasil create(), credential disimpan
+
+	LOGIN (tanpa password)
+	1. /webauthn/login/options -> public_key untuk navigator.credentials.get(), username opsional
+	2. /webauthn/login/verify  -> kirim hasil get(), sesi dibuat seperti Verify_Token
+	Passkey sudah memenuhi dua faktor (perangkat + biometrik/PIN), langkah TOTP tidak diminta.
+
+	Semua nilai biner dikirim sebagai base64url.
+*/
+
+type webauthnCredential struct {
+	ID                int64          `db:"id"`
+	UserID            int64          `db:"user_id"`
+	CredentialID      string         `db:"credential_id"`
+	PublicKey         []byte         `db:"public_key"`
+	SignCount         int64          `db:"sign_count"`
+	AAGUID            sql.NullString `db:"aaguid"`
+	AttestationFormat string         `db:"attestation_format"`
+	Transports        sql.NullString `db:"transports"`
+	Name              sql.NullString `db:"name"`
+	CreateTstamp      int64          `db:"create_tstamp"`
+	LastUsedTstamp    sql.NullInt64  `db:"last_used_tstamp"`
+}
+
+type webauthnLoginChallenge struct {
+	UserID int64 `json:"user_id"` // 0 bila login tanpa username (discoverable credential)
+}
+
+func webauthnRegisterKey(userID int64) string {
+	return "webauthn_register:" + strconv.FormatInt(userID, 10)
+}
+
+func webauthnLoginKey(challenge string) string {
+	return "webauthn_login:" + crypto.HashSHA256(challenge)
+}
+
+// webauthnUserHandle adalah user.id di WebAuthn, tidak memuat data pribadi
+func webauthnUserHandle(userID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(userID, 10)))
+}
+
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+func userVerificationRequirement() string {
+	if webauthn.GetConfig().RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+// credentialDescriptors menyusun daftar credential user untuk allowCredentials / excludeCredentials
+func credentialDescriptors(userID int64) ([]map[string]any, error) {
+	conn, err := db.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials []webauthnCredential
+	if err := conn.Select(&credentials, `SELECT * FROM sysuser.webauthn_credential WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	descriptors := make([]map[string]any, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptor := map[string]any{"type": "public-key", "id": credential.CredentialID}
+		if credential.Transports.Valid && credential.Transports.String != "" {
+			descriptor["transports"] = strings.Split(credential.Transports.String, ",")
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors, nil
+}
+
+func WebAuthn_Register_Options(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - WebAuthn_Register_Options - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Options - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Options - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var user struct {
+		Username string `db:"username"`
+		FullName string `db:"full_name"`
+	}
+	if err := conn.Get(&user, `SELECT username, full_name FROM sysuser."user" WHERE id = $1`, userID); err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Options - User not found: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	// credential yang sudah terdaftar dikecualikan agar authenticator yang sama tidak didaftarkan dua kali
+	excludeCredentials, err := credentialDescriptors(userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Options - Failed to load credentials: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Options - Redis client is not initialized")
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Options - Failed to generate challenge: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	expiry := time.Duration(configs.GetWebAuthnChallengeExpireTime()) * time.Second
+	if err := redisClient.Set(context.Background(), webauthnRegisterKey(userID), challenge, expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Options - Failed to store challenge: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	rp := webauthn.GetConfig()
+	result.Payload["public_key"] = map[string]any{
+		"challenge": challenge,
+		"rp":        map[string]any{"id": rp.RPID, "name": rp.RPName},
+		"user": map[string]any{
+			"id":          webauthnUserHandle(userID),
+			"name":        user.Username,
+			"displayName": user.FullName,
+		},
+		"pubKeyCredParams": []map[string]any{
+			{"type": "public-key", "alg": webauthn.AlgES256},
+			{"type": "public-key", "alg": webauthn.AlgRS256},
+		},
+		"timeout":            expiry.Milliseconds(),
+		"attestation":        "none",
+		"excludeCredentials": excludeCredentials,
+		"authenticatorSelection": map[string]any{
+			"residentKey":      "preferred",
+			"userVerification": userVerificationRequirement(),
+		},
+	}
+	utils.Response(w, result)
+}
+
+/*
+{
+	"client_data_json" : "<base64url>",
+	"attestation_object" : "<base64url>",
+	"transports" : ["internal", "hybrid"],
+	"name" : "MacBook Touch ID"
+}
+*/
+
+func WebAuthn_Register_Verify(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - WebAuthn_Register_Verify - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	clientDataParam, _ := param["client_data_json"].(string)
+	attestationParam, _ := param["attestation_object"].(string)
+	clientDataJSON, errClientData := webauthn.DecodeBase64URL(clientDataParam)
+	attestationObject, errAttestation := webauthn.DecodeBase64URL(attestationParam)
+	if clientDataParam == "" || attestationParam == "" || errClientData != nil || errAttestation != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Missing or malformed credential")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	name, _ := param["name"].(string)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	var transports []string
+	if list, ok := param["transports"].([]any); ok {
+		for _, item := range list {
+			if transport, ok := item.(string); ok && transport != "" && !strings.Contains(transport, ",") {
+				transports = append(transports, transport)
+			}
+		}
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// challenge hanya bisa dipakai sekali
+	challenge, err := redisClient.GetDel(context.Background(), webauthnRegisterKey(userID)).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Challenge not found or expired: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	credential, err := webauthn.VerifyRegistration(clientDataJSON, attestationObject, challenge)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Attestation verification failed: ", err)
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Failed to get DB connection: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+	queryInsert := `
+		INSERT INTO sysuser.webauthn_credential
+			(user_id, credential_id, public_key, sign_count, aaguid, attestation_format, transports, name, create_tstamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+	var id int64
+	err = conn.QueryRow(queryInsert, userID, credentialID, credential.PublicKey, int64(credential.SignCount),
+		sql.NullString{String: formatAAGUID(credential.AAGUID), Valid: formatAAGUID(credential.AAGUID) != ""},
+		credential.AttestationFormat,
+		sql.NullString{String: strings.Join(transports, ","), Valid: len(transports) > 0},
+		sql.NullString{String: name, Valid: name != ""},
+		time.Now().Unix()).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Credential already registered")
+			result.ErrorCode = "409001"
+			result.ErrorMessage = "Conflict"
+			utils.Response(w, result)
+			return
+		}
+		logger.Error(referenceID, "ERROR - WebAuthn_Register_Verify - Failed to store credential: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	logger.Info(referenceID, "INFO - WebAuthn_Register_Verify - Credential registered, format: ", credential.AttestationFormat)
+	result.Payload["status"] = "success"
+	result.Payload["id"] = id
+	result.Payload["credential_id"] = credentialID
+	utils.Response(w, result)
+}
+
+/*
+{
+	"username" : "admin"	// opsional, tanpa username browser menampilkan passkey yang tersimpan
+}
+*/
+
+func WebAuthn_Login_Options(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - WebAuthn_Login_Options - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+	username, _ := param["username"].(string)
+
+	var userID int64
+	allowCredentials := []map[string]any{}
+	if username != "" {
+		conn, err := db.GetConnection()
+		if err != nil {
+			logger.Error(referenceID, "ERROR - WebAuthn_Login_Options - Failed to get DB connection: ", err)
+			result.ErrorCode = "500001"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		// username yang tidak dikenal diperlakukan seperti login tanpa username (tidak membocorkan akun)
+		err = conn.Get(&userID, `SELECT id FROM sysuser."user" WHERE username = $1`, username)
+		if err != nil && err != sql.ErrNoRows {
+			logger.Error(referenceID, "ERROR - WebAuthn_Login_Options - User lookup failed: ", err)
+			result.ErrorCode = "500002"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		if userID != 0 {
+			allowCredentials, err = credentialDescriptors(userID)
+			if err != nil {
+				logger.Error(referenceID, "ERROR - WebAuthn_Login_Options - Failed to load credentials: ", err)
+				result.ErrorCode = "500003"
+				result.ErrorMessage = "Internal server error"
+				utils.Response(w, result)
+				return
+			}
+		}
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Options - Redis client is not initialized")
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Options - Failed to generate challenge: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	// state disimpan dengan key hash challenge, client cukup mengirim balik clientDataJSON
+	value, _ := json.Marshal(webauthnLoginChallenge{UserID: userID})
+	expiry := time.Duration(configs.GetWebAuthnChallengeExpireTime()) * time.Second
+	if err := redisClient.Set(context.Background(), webauthnLoginKey(challenge), value, expiry).Err(); err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Options - Failed to store challenge: ", err)
+		result.ErrorCode = "500006"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["public_key"] = map[string]any{
+		"challenge":        challenge,
+		"rpId":             webauthn.GetConfig().RPID,
+		"timeout":          expiry.Milliseconds(),
+		"userVerification": userVerificationRequirement(),
+		"allowCredentials": allowCredentials,
+	}
+	utils.Response(w, result)
+}
+
+/*
+{
+	"credential_id" : "<base64url, PublicKeyCredential.id>",
+	"client_data_json" : "<base64url>",
+	"authenticator_data" : "<base64url>",
+	"signature" : "<base64url>",
+	"user_handle" : "<base64url, opsional>"
+}
+*/
+
+func WebAuthn_Login_Verify(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - WebAuthn_Login_Verify - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	param, _ := utils.Request(r)
+
+	credentialID, _ := param["credential_id"].(string)
+	clientDataParam, _ := param["client_data_json"].(string)
+	authDataParam, _ := param["authenticator_data"].(string)
+	signatureParam, _ := param["signature"].(string)
+	userHandle, _ := param["user_handle"].(string)
+
+	clientDataJSON, errClientData := webauthn.DecodeBase64URL(clientDataParam)
+	authenticatorData, errAuthData := webauthn.DecodeBase64URL(authDataParam)
+	signature, errSignature := webauthn.DecodeBase64URL(signatureParam)
+	if credentialID == "" || clientDataParam == "" || authDataParam == "" || signatureParam == "" ||
+		errClientData != nil || errAuthData != nil || errSignature != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Missing or malformed assertion")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+	credentialID = strings.TrimRight(credentialID, "=")
+
+	clientData, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Invalid client data: ", err)
+		result.ErrorCode = "400002"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	redisClient := rds.GetRedisClient()
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Redis client is not initialized")
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	value, err := redisClient.GetDel(context.Background(), webauthnLoginKey(clientData.Challenge)).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Challenge not found or expired: ", err)
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	var challengeState webauthnLoginChallenge
+	if err := json.Unmarshal([]byte(value), &challengeState); err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Invalid challenge data: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Failed to get DB connection: ", err)
+		result.ErrorCode = "500003"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var credential webauthnCredential
+	if err := conn.Get(&credential, `SELECT * FROM sysuser.webauthn_credential WHERE credential_id = $1`, credentialID); err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Credential not found: ", err)
+		result.ErrorCode = "401002"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if challengeState.UserID != 0 && challengeState.UserID != credential.UserID {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Credential does not belong to requested user")
+		result.ErrorCode = "401003"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if userHandle != "" && strings.TrimRight(userHandle, "=") != webauthnUserHandle(credential.UserID) {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - User handle mismatch")
+		result.ErrorCode = "401004"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	// passkey tidak boleh melewati penguncian akun dari jalur login lain
+	remaining, err := loginLockRemaining(credential.UserID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Failed to read lockout status: ", err)
+		result.ErrorCode = "500005"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if remaining > 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Account is locked: ", credential.UserID)
+		result.ErrorCode = "429000"
+		result.ErrorMessage = "Too many attempts"
+		result.Payload["remaining_time"] = int(remaining.Seconds())
+		utils.Response(w, result)
+		return
+	}
+
+	signCount, err := webauthn.VerifyAssertion(credential.PublicKey, uint32(credential.SignCount), clientDataJSON, authenticatorData, signature, clientData.Challenge)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Assertion verification failed: ", err)
+		result.ErrorCode = "401005"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	// update kondisional terhadap sign_count lama, assertion paralel dengan counter yang sama ditolak
+	queryUpdate := `UPDATE sysuser.webauthn_credential SET sign_count = $3, last_used_tstamp = $4 WHERE id = $1 AND sign_count = $2`
+	res, err := conn.Exec(queryUpdate, credential.ID, credential.SignCount, int64(signCount), time.Now().Unix())
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Failed to update credential: ", err)
+		result.ErrorCode = "500004"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if updated, _ := res.RowsAffected(); updated == 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Login_Verify - Concurrent use of credential")
+		result.ErrorCode = "401006"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	// challenge dipakai sebagai kunci HMAC session_hash, sama seperti token pada Verify_Token
+	if !createSession(referenceID, "WebAuthn_Login_Verify", r, conn, credential.UserID, clientData.Challenge, &result) {
+		utils.Response(w, result)
+		return
+	}
+
+	utils.Response(w, result)
+}
+
+// WebAuthn_Credentials menampilkan passkey milik user yang sedang login
+func WebAuthn_Credentials(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - WebAuthn_Credentials - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credentials - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credentials - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	var credentials []webauthnCredential
+	if err := conn.Select(&credentials, `SELECT * FROM sysuser.webauthn_credential WHERE user_id = $1 ORDER BY id`, userID); err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credentials - Failed to load credentials: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	list := make([]map[string]any, 0, len(credentials))
+	for _, credential := range credentials {
+		list = append(list, map[string]any{
+			"id":               credential.ID,
+			"credential_id":    credential.CredentialID,
+			"name":             credential.Name.String,
+			"aaguid":           credential.AAGUID.String,
+			"create_tstamp":    credential.CreateTstamp,
+			"last_used_tstamp": credential.LastUsedTstamp.Int64,
+		})
+	}
+
+	result.Payload["credentials"] = list
+	utils.Response(w, result)
+}
+
+/*
+{
+	"id" : 12
+}
+*/
+
+func WebAuthn_Credential_Delete(w http.ResponseWriter, r *http.Request) {
+	var ctxKey HTTPContextKey = "requestID"
+	referenceID, _ := r.Context().Value(ctxKey).(string)
+	if referenceID == "" {
+		referenceID = "unknown"
+	}
+
+	startTime := time.Now()
+	defer func() {
+		duration := time.Since(startTime)
+		logger.Debug(referenceID, "DEBUG - WebAuthn_Credential_Delete - Execution completed in ", duration)
+	}()
+
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	userID, _ := r.Context().Value(HTTPContextKey("userID")).(int64)
+	if userID == 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credential_Delete - Missing session context")
+		result.ErrorCode = "401001"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	param, _ := utils.Request(r)
+
+	id, ok := param["id"].(float64)
+	if !ok || id <= 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credential_Delete - Missing id")
+		result.ErrorCode = "400001"
+		result.ErrorMessage = "Invalid request"
+		utils.Response(w, result)
+		return
+	}
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credential_Delete - Failed to get DB connection: ", err)
+		result.ErrorCode = "500001"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	res, err := conn.Exec(`DELETE FROM sysuser.webauthn_credential WHERE id = $1 AND user_id = $2`, int64(id), userID)
+	if err != nil {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credential_Delete - Failed to delete credential: ", err)
+		result.ErrorCode = "500002"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+	if deleted, _ := res.RowsAffected(); deleted == 0 {
+		logger.Error(referenceID, "ERROR - WebAuthn_Credential_Delete - Credential not found")
+		result.ErrorCode = "404001"
+		result.ErrorMessage = "Not found"
+		utils.Response(w, result)
+		return
+	}
+
+	result.Payload["status"] = "success"
+	utils.Response(w, result)
+}
@@ -0,0 +1,182 @@
+package idp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Identity adalah user dari provider setelah claim mapping
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	FullName      string
+}
+
+// AuthCodeURL membuat URL authorization request (authorization code + PKCE S256)
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeVerifier string) (string, error) {
+	if err := p.ensureMetadata(ctx); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(codeVerifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("scope", strings.Join(p.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+	query.Set("code_challenge_method", "S256")
+	if p.usesOpenID() {
+		query.Set("nonce", nonce)
+	}
+
+	separator := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+func (p *Provider) usesOpenID() bool {
+	for _, scope := range p.Scopes {
+		if scope == "openid" {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate menukar code ke token endpoint provider lalu membaca identity dari
+// ID token (bila ada, diverifikasi) dan / atau userinfo endpoint.
+func (p *Provider) Authenticate(ctx context.Context, redirectURI, code, codeVerifier, nonce string) (*Identity, error) {
+	if err := p.ensureMetadata(ctx); err != nil {
+		return nil, err
+	}
+
+	token, err := p.exchange(ctx, redirectURI, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if token.IDToken != "" {
+		claims, err = p.verifyIDToken(ctx, token.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+	} else if p.usesOpenID() {
+		return nil, errors.New("idp: provider returned no ID token for openid scope")
+	}
+
+	if p.UserInfoEndpoint != "" && token.AccessToken != "" {
+		var userInfo map[string]any
+		if err := getJSON(ctx, p.UserInfoEndpoint, token.AccessToken, &userInfo); err != nil {
+			return nil, fmt.Errorf("idp: userinfo request failed: %w", err)
+		}
+		if claims == nil {
+			claims = userInfo
+		} else {
+			// userinfo harus milik subject yang sama dengan ID token (OIDC Core §5.3.2)
+			if claimString(userInfo[p.Claims.Subject]) != claimString(claims[p.Claims.Subject]) {
+				return nil, errors.New("idp: userinfo subject does not match ID token")
+			}
+			for key, value := range userInfo {
+				if _, exists := claims[key]; !exists {
+					claims[key] = value
+				}
+			}
+		}
+	}
+	if claims == nil {
+		return nil, ErrNoIdentity
+	}
+
+	identity := &Identity{
+		Subject:  claimString(claims[p.Claims.Subject]),
+		Email:    strings.ToLower(strings.TrimSpace(claimString(claims[p.Claims.Email]))),
+		Username: claimString(claims[p.Claims.Username]),
+		FullName: claimString(claims[p.Claims.FullName]),
+	}
+	if identity.Subject == "" {
+		return nil, ErrNoIdentity
+	}
+	if identity.Email != "" {
+		identity.EmailVerified = p.TrustEmail || claimString(claims[p.Claims.EmailVerified]) == "true"
+	}
+	return identity, nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *Provider) exchange(ctx context.Context, redirectURI, code, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", codeVerifier)
+	if p.TokenAuthMethod == "client_secret_post" {
+		form.Set("client_id", p.ClientID)
+		form.Set("client_secret", p.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.TokenAuthMethod == "client_secret_basic" {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("idp: invalid token response (status %d): %w", resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("idp: token endpoint error: %s %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || (token.AccessToken == "" && token.IDToken == "") {
+		return nil, fmt.Errorf("idp: token exchange failed with status %d", resp.StatusCode)
+	}
+	return &token, nil
+}
+
+// claimString mengubah nilai claim (string, angka, boolean) menjadi string
+func claimString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
@@ -0,0 +1,251 @@
+package idp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Konektor generik ke identity provider eksternal (OIDC / OAuth 2.0), tanpa kode khusus vendor.
+// Provider dibaca dari file JSON, contoh:
+/*
+[
+	{
+		"name": "google",
+		"display_name": "Google",
+		"issuer": "https://accounts.google.com",
+		"client_id": "<client_id>",
+		"client_secret_env": "GOOGLE_CLIENT_SECRET",
+		"scopes": ["openid", "email", "profile"],
+		"allow_signup": true
+	},
+	{
+		"name": "github",
+		"display_name": "GitHub",
+		"authorization_endpoint": "https://github.com/login/oauth/authorize",
+		"token_endpoint": "https://github.com/login/oauth/access_token",
+		"userinfo_endpoint": "https://api.github.com/user",
+		"client_id": "<client_id>",
+		"client_secret": "<client_secret>",
+		"token_auth_method": "client_secret_post",
+		"scopes": ["read:user", "user:email"],
+		"claims": {"subject": "id", "username": "login", "full_name": "name", "email": "email"}
+	}
+]
+*/
+// Bila issuer diisi, endpoint yang kosong diambil dari <issuer>/.well-known/openid-configuration.
+
+// ClaimMapping menentukan nama claim provider untuk setiap atribut user
+type ClaimMapping struct {
+	Subject       string `json:"subject"`
+	Email         string `json:"email"`
+	EmailVerified string `json:"email_verified"`
+	Username      string `json:"username"`
+	FullName      string `json:"full_name"`
+}
+
+type Provider struct {
+	Name                  string       `json:"name"`
+	DisplayName           string       `json:"display_name"`
+	Issuer                string       `json:"issuer"`
+	AuthorizationEndpoint string       `json:"authorization_endpoint"`
+	TokenEndpoint         string       `json:"token_endpoint"`
+	UserInfoEndpoint      string       `json:"userinfo_endpoint"`
+	JWKSURI               string       `json:"jwks_uri"`
+	ClientID              string       `json:"client_id"`
+	ClientSecret          string       `json:"client_secret"`
+	ClientSecretEnv       string       `json:"client_secret_env"`
+	TokenAuthMethod       string       `json:"token_auth_method"` // client_secret_basic (default) | client_secret_post
+	Scopes                []string     `json:"scopes"`
+	Claims                ClaimMapping `json:"claims"`
+	AllowSignup           bool         `json:"allow_signup"`  // buat akun baru (JIT) untuk identity yang belum terhubung
+	TrustEmail            bool         `json:"trust_email"`   // email dari provider dianggap terverifikasi tanpa claim email_verified
+	LinkByEmail           bool         `json:"link_by_email"` // hubungkan otomatis ke akun dengan email terverifikasi yang sama
+
+	metadataMu sync.Mutex
+	metadataOK bool
+	keys       *keySet
+}
+
+var (
+	providers   map[string]*Provider
+	providersMu sync.RWMutex
+
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+
+	namePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+)
+
+// Init membaca konfigurasi provider dari file JSON. File kosong berarti fitur dimatikan.
+func Init(configFile string) error {
+	loaded := make(map[string]*Provider)
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+		var list []*Provider
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("idp: invalid config: %w", err)
+		}
+		for _, p := range list {
+			if err := p.prepare(); err != nil {
+				return err
+			}
+			if _, exists := loaded[p.Name]; exists {
+				return fmt.Errorf("idp: duplicate provider %q", p.Name)
+			}
+			loaded[p.Name] = p
+		}
+	}
+
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers = loaded
+	return nil
+}
+
+func (p *Provider) prepare() error {
+	if !namePattern.MatchString(p.Name) {
+		return fmt.Errorf("idp: invalid provider name %q", p.Name)
+	}
+	if p.ClientID == "" {
+		return fmt.Errorf("idp: provider %s has no client_id", p.Name)
+	}
+	if p.ClientSecretEnv != "" {
+		p.ClientSecret = os.Getenv(p.ClientSecretEnv)
+		if p.ClientSecret == "" {
+			return fmt.Errorf("idp: provider %s: environment variable %s is empty", p.Name, p.ClientSecretEnv)
+		}
+	}
+	if p.Issuer == "" && (p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.UserInfoEndpoint == "") {
+		return fmt.Errorf("idp: provider %s needs an issuer or authorization, token and userinfo endpoints", p.Name)
+	}
+	switch p.TokenAuthMethod {
+	case "":
+		p.TokenAuthMethod = "client_secret_basic"
+	case "client_secret_basic", "client_secret_post":
+	default:
+		return fmt.Errorf("idp: provider %s: unsupported token_auth_method %q", p.Name, p.TokenAuthMethod)
+	}
+	if p.DisplayName == "" {
+		p.DisplayName = p.Name
+	}
+	if len(p.Scopes) == 0 {
+		p.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	if p.Claims.Subject == "" {
+		p.Claims.Subject = "sub"
+	}
+	if p.Claims.Email == "" {
+		p.Claims.Email = "email"
+	}
+	if p.Claims.EmailVerified == "" {
+		p.Claims.EmailVerified = "email_verified"
+	}
+	if p.Claims.Username == "" {
+		p.Claims.Username = "preferred_username"
+	}
+	if p.Claims.FullName == "" {
+		p.Claims.FullName = "name"
+	}
+
+	p.Issuer = strings.TrimRight(p.Issuer, "/")
+	p.keys = &keySet{}
+	return nil
+}
+
+// Get mengembalikan provider berdasarkan nama
+func Get(name string) (*Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// List mengembalikan semua provider yang dikonfigurasi
+func List() []*Provider {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	list := make([]*Provider, 0, len(providers))
+	for _, p := range providers {
+		list = append(list, p)
+	}
+	return list
+}
+
+// ensureMetadata melengkapi endpoint dari discovery document issuer (sekali, diulang bila gagal)
+func (p *Provider) ensureMetadata(ctx context.Context) error {
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+	if p.metadataOK || p.Issuer == "" {
+		return nil
+	}
+
+	var metadata struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := getJSON(ctx, p.Issuer+"/.well-known/openid-configuration", "", &metadata); err != nil {
+		return fmt.Errorf("idp: discovery for %s failed: %w", p.Name, err)
+	}
+	if strings.TrimRight(metadata.Issuer, "/") != p.Issuer {
+		return fmt.Errorf("idp: discovery issuer mismatch for %s: %s", p.Name, metadata.Issuer)
+	}
+
+	if p.AuthorizationEndpoint == "" {
+		p.AuthorizationEndpoint = metadata.AuthorizationEndpoint
+	}
+	if p.TokenEndpoint == "" {
+		p.TokenEndpoint = metadata.TokenEndpoint
+	}
+	if p.UserInfoEndpoint == "" {
+		p.UserInfoEndpoint = metadata.UserInfoEndpoint
+	}
+	if p.JWKSURI == "" {
+		p.JWKSURI = metadata.JWKSURI
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" {
+		return fmt.Errorf("idp: discovery for %s has no authorization or token endpoint", p.Name)
+	}
+
+	p.metadataOK = true
+	return nil
+}
+
+func getJSON(ctx context.Context, endpoint, bearer string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, endpoint)
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+var ErrNoIdentity = errors.New("idp: provider returned no identity")
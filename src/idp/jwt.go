@@ -0,0 +1,212 @@
+package idp
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keySet adalah cache JWKS provider, diambil ulang bila kid tidak dikenal (paling cepat 1 menit sekali)
+type keySet struct {
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+const jwksRefreshInterval = time.Minute
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (p *Provider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.keys.mu.Lock()
+	defer p.keys.mu.Unlock()
+
+	if key, ok := p.keys.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keys.fetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("idp: unknown key id %q", kid)
+	}
+	if p.JWKSURI == "" {
+		return nil, errors.New("idp: provider has no jwks_uri")
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, p.JWKSURI, "", &document); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := parseJWK(jwk); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys.keys = keys
+	p.keys.fetchedAt = time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("idp: unknown key id %q", kid)
+}
+
+func parseJWK(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("idp: invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, errors.New("idp: unsupported curve " + jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("idp: EC point is not on curve")
+		}
+		return key, nil
+	}
+	return nil, errors.New("idp: unsupported key type " + jwk.Kty)
+}
+
+// verifyIDToken memeriksa tanda tangan (RS256 / ES256), iss, aud, exp dan nonce ID token (OIDC Core §3.1.3.7)
+func (p *Provider) verifyIDToken(ctx context.Context, token, nonce string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("idp: malformed ID token")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("idp: malformed ID token header")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, errors.New("idp: malformed ID token header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("idp: malformed ID token signature")
+	}
+
+	key, err := p.publicKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch header.Alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("idp: invalid ID token signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return nil, errors.New("idp: invalid ID token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return nil, errors.New("idp: invalid ID token signature")
+		}
+	default:
+		return nil, errors.New("idp: unsupported ID token algorithm " + header.Alg)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("idp: malformed ID token payload")
+	}
+	var claims map[string]any
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, errors.New("idp: malformed ID token payload")
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != p.Issuer {
+		return nil, errors.New("idp: ID token issuer mismatch")
+	}
+	if !audienceContains(claims["aud"], p.ClientID) {
+		return nil, errors.New("idp: ID token audience mismatch")
+	}
+	// toleransi 1 menit untuk perbedaan jam
+	if exp, err := claimInt64(claims["exp"]); err != nil || exp+60 < time.Now().Unix() {
+		return nil, errors.New("idp: ID token expired")
+	}
+	if claimNonce, _ := claims["nonce"].(string); nonce != "" && claimNonce != nonce {
+		return nil, errors.New("idp: ID token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+func audienceContains(aud any, clientID string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == clientID
+	case []any:
+		for _, item := range value {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func claimInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Int64()
+	case float64:
+		return int64(v), nil
+	}
+	return 0, errors.New("idp: claim is not a number")
+}
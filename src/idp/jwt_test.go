@@ -0,0 +1,290 @@
+package idp
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testClientID = "auth-service"
+	testNonce    = "nonce-0001"
+)
+
+// testKeys adalah key penandatangan IdP palsu, dipublikasikan lewat JWKS httptest
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (k *testKeys) jwks() []jsonWebKey {
+	return []jsonWebKey{
+		{Kty: "RSA", Kid: "rsa-1", Use: "sig", N: b64(k.rsa.PublicKey.N.Bytes()), E: b64(big.NewInt(int64(k.rsa.PublicKey.E)).Bytes())},
+		{Kty: "EC", Kid: "ec-1", Crv: "P-256", X: b64(k.ec.PublicKey.X.FillBytes(make([]byte, 32))), Y: b64(k.ec.PublicKey.Y.FillBytes(make([]byte, 32)))},
+		{Kty: "RSA", Kid: "enc-1", Use: "enc", N: b64(k.rsa.PublicKey.N.Bytes()), E: "AQAB"},
+	}
+}
+
+// newTestProvider menjalankan server JWKS dan mengembalikan provider beserta penghitung request JWKS
+func newTestProvider(t *testing.T, keys *testKeys) (*Provider, *int32) {
+	t.Helper()
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys.jwks()})
+	}))
+	t.Cleanup(server.Close)
+
+	return &Provider{Name: "test", Issuer: testIssuer, ClientID: testClientID, JWKSURI: server.URL, keys: &keySet{}}, &fetches
+}
+
+// sign menyusun ID token dengan header alg/kid dan claims yang diberikan
+func (k *testKeys) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, k.ec, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + b64(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":   testIssuer,
+		"aud":   testClientID,
+		"sub":   "user-123",
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"nonce": testNonce,
+	}
+}
+
+func withClaim(name string, value any) map[string]any {
+	claims := validClaims()
+	if value == nil {
+		delete(claims, name)
+	} else {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	keys := newTestKeys(t)
+	valid := keys.sign(t, "RS256", "rsa-1", validClaims())
+	parts := strings.Split(valid, ".")
+	tamperedPayload, _ := json.Marshal(withClaim("sub", "admin"))
+
+	tests := []struct {
+		name    string
+		token   string
+		nonce   string
+		wantErr bool
+	}{
+		{"RS256", valid, testNonce, false},
+		{"ES256", keys.sign(t, "ES256", "ec-1", validClaims()), testNonce, false},
+		{"issuer with trailing slash", keys.sign(t, "RS256", "rsa-1", withClaim("iss", testIssuer+"/")), testNonce, false},
+		{"audience list", keys.sign(t, "RS256", "rsa-1", withClaim("aud", []string{"other", testClientID})), testNonce, false},
+		{"expired within clock skew", keys.sign(t, "RS256", "rsa-1", withClaim("exp", time.Now().Add(-30*time.Second).Unix())), testNonce, false},
+		{"nonce not requested", keys.sign(t, "RS256", "rsa-1", withClaim("nonce", nil)), "", false},
+		{"issuer mismatch", keys.sign(t, "RS256", "rsa-1", withClaim("iss", "https://evil.example.com")), testNonce, true},
+		{"audience mismatch", keys.sign(t, "RS256", "rsa-1", withClaim("aud", "other")), testNonce, true},
+		{"audience list without client", keys.sign(t, "RS256", "rsa-1", withClaim("aud", []string{"other"})), testNonce, true},
+		{"expired", keys.sign(t, "RS256", "rsa-1", withClaim("exp", time.Now().Add(-2*time.Minute).Unix())), testNonce, true},
+		{"missing exp", keys.sign(t, "RS256", "rsa-1", withClaim("exp", nil)), testNonce, true},
+		{"exp not a number", keys.sign(t, "RS256", "rsa-1", withClaim("exp", "tomorrow")), testNonce, true},
+		{"nonce mismatch", valid, "other-nonce", true},
+		{"nonce missing", keys.sign(t, "RS256", "rsa-1", withClaim("nonce", nil)), testNonce, true},
+		{"tampered payload", parts[0] + "." + b64(tamperedPayload) + "." + parts[2], testNonce, true},
+		{"unknown key id", keys.sign(t, "RS256", "rsa-2", validClaims()), testNonce, true},
+		{"encryption key", keys.sign(t, "RS256", "enc-1", validClaims()), testNonce, true},
+		{"alg none", b64([]byte(`{"alg":"none","kid":"rsa-1"}`)) + "." + parts[1] + ".", testNonce, true},
+		{"RS256 header with EC key", keys.sign(t, "RS256", "ec-1", validClaims()), testNonce, true},
+		{"ES256 header with RSA key", keys.sign(t, "ES256", "rsa-1", validClaims()), testNonce, true},
+		{"two parts", parts[0] + "." + parts[1], testNonce, true},
+		{"header not base64", "!!." + parts[1] + "." + parts[2], testNonce, true},
+		{"payload not json", parts[0] + "." + b64([]byte("not json")) + "." + parts[2], testNonce, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, _ := newTestProvider(t, keys)
+			claims, err := provider.verifyIDToken(context.Background(), tt.token, tt.nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("verifyIDToken expected error, got claims %v", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyIDToken: %v", err)
+			}
+			if claims["sub"] != "user-123" {
+				t.Errorf("sub = %v, want user-123", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestPublicKeyRefresh(t *testing.T) {
+	keys := newTestKeys(t)
+	provider, fetches := newTestProvider(t, keys)
+	ctx := context.Background()
+
+	if _, err := provider.publicKey(ctx, "rsa-1"); err != nil {
+		t.Fatalf("publicKey(rsa-1): %v", err)
+	}
+	if _, err := provider.publicKey(ctx, "ec-1"); err != nil {
+		t.Fatalf("publicKey(ec-1): %v", err)
+	}
+	if *fetches != 1 {
+		t.Errorf("JWKS fetched %d times, want 1 (cached)", *fetches)
+	}
+
+	// kid tidak dikenal tidak boleh memicu fetch ulang sebelum jwksRefreshInterval
+	if _, err := provider.publicKey(ctx, "rotated"); err == nil {
+		t.Errorf("publicKey(rotated) expected error")
+	}
+	if *fetches != 1 {
+		t.Errorf("JWKS fetched %d times within refresh interval, want 1", *fetches)
+	}
+
+	provider.keys.fetchedAt = time.Now().Add(-2 * jwksRefreshInterval)
+	if _, err := provider.publicKey(ctx, "rotated"); err == nil {
+		t.Errorf("publicKey(rotated) expected error")
+	}
+	if *fetches != 2 {
+		t.Errorf("JWKS fetched %d times after refresh interval, want 2", *fetches)
+	}
+
+	noJWKS := &Provider{Name: "no-jwks", keys: &keySet{}}
+	if _, err := noJWKS.publicKey(ctx, "rsa-1"); err == nil {
+		t.Errorf("publicKey without jwks_uri expected error")
+	}
+}
+
+func TestParseJWK(t *testing.T) {
+	keys := newTestKeys(t)
+	jwks := keys.jwks()
+	rsaJWK, ecJWK := jwks[0], jwks[1]
+
+	with := func(jwk jsonWebKey, change func(*jsonWebKey)) jsonWebKey {
+		change(&jwk)
+		return jwk
+	}
+	offCurveY := new(big.Int).Add(keys.ec.PublicKey.Y, big.NewInt(1)).FillBytes(make([]byte, 32))
+
+	tests := []struct {
+		name    string
+		jwk     jsonWebKey
+		wantErr bool
+	}{
+		{"RSA", rsaJWK, false},
+		{"EC P-256", ecJWK, false},
+		{"RSA modulus not base64", with(rsaJWK, func(j *jsonWebKey) { j.N = "!!" }), true},
+		{"RSA empty exponent", with(rsaJWK, func(j *jsonWebKey) { j.E = "" }), true},
+		{"RSA exponent too long", with(rsaJWK, func(j *jsonWebKey) { j.E = b64(make([]byte, 5)) }), true},
+		{"EC P-384", with(ecJWK, func(j *jsonWebKey) { j.Crv = "P-384" }), true},
+		{"EC point off curve", with(ecJWK, func(j *jsonWebKey) { j.Y = b64(offCurveY) }), true},
+		{"EC x not base64", with(ecJWK, func(j *jsonWebKey) { j.X = "!!" }), true},
+		{"symmetric key", jsonWebKey{Kty: "oct", Kid: "hmac"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseJWK(tt.jwk)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseJWK() = %v, error = %v, wantErr %v", key, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAudienceContains(t *testing.T) {
+	tests := []struct {
+		name string
+		aud  any
+		want bool
+	}{
+		{"string match", testClientID, true},
+		{"string mismatch", "other", false},
+		{"list match", []any{"other", testClientID}, true},
+		{"list mismatch", []any{"other"}, false},
+		{"empty list", []any{}, false},
+		{"missing", nil, false},
+		{"number", json.Number("1"), false},
+	}
+
+	for _, tt := range tests {
+		if got := audienceContains(tt.aud, testClientID); got != tt.want {
+			t.Errorf("%s: audienceContains(%v) = %v, want %v", tt.name, tt.aud, got, tt.want)
+		}
+	}
+}
+
+func TestClaimInt64(t *testing.T) {
+	tests := []struct {
+		value   any
+		want    int64
+		wantErr bool
+	}{
+		{json.Number("1700000000"), 1700000000, false},
+		{float64(1700000000), 1700000000, false},
+		{json.Number("1.5"), 0, true},
+		{"1700000000", 0, true},
+		{nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := claimInt64(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("claimInt64(%#v) = %d (%v), want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// Encoding BER minimal untuk LDAPv3 (RFC 4511 §5.1): hanya definite length, tag satu byte
+
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	constructed = 0x20
+
+	maxMessageSize = 16 << 20
+)
+
+var errMalformed = errors.New("ldap: malformed BER data")
+
+type tlv struct {
+	tag     byte
+	content []byte
+}
+
+func encodeTLV(tag byte, content []byte) []byte {
+	length := len(content)
+	var header []byte
+	switch {
+	case length < 0x80:
+		header = []byte{tag, byte(length)}
+	case length <= 0xff:
+		header = []byte{tag, 0x81, byte(length)}
+	case length <= 0xffff:
+		header = []byte{tag, 0x82, byte(length >> 8), byte(length)}
+	default:
+		header = []byte{tag, 0x84, byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)}
+	}
+	return append(header, content...)
+}
+
+func encodeSequence(tag byte, children ...[]byte) []byte {
+	var content []byte
+	for _, child := range children {
+		content = append(content, child...)
+	}
+	return encodeTLV(tag, content)
+}
+
+func encodeString(tag byte, value string) []byte {
+	return encodeTLV(tag, []byte(value))
+}
+
+// encodeInteger menulis integer two's complement dengan jumlah byte minimal
+func encodeInteger(tag byte, value int64) []byte {
+	var content []byte
+	for {
+		content = append([]byte{byte(value)}, content...)
+		value >>= 8
+		if (value == 0 && content[0]&0x80 == 0) || (value == -1 && content[0]&0x80 != 0) {
+			break
+		}
+	}
+	return encodeTLV(tag, content)
+}
+
+func encodeBoolean(value bool) []byte {
+	if value {
+		return encodeTLV(tagBoolean, []byte{0xff})
+	}
+	return encodeTLV(tagBoolean, []byte{0x00})
+}
+
+// readTLV membaca satu elemen BER lengkap dari stream
+func readTLV(r *bufio.Reader) (tlv, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return tlv{}, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return tlv{}, err
+	}
+
+	length := int(first)
+	if first&0x80 != 0 {
+		count := int(first & 0x7f)
+		if count == 0 || count > 4 {
+			return tlv{}, errMalformed
+		}
+		length = 0
+		for i := 0; i < count; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return tlv{}, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxMessageSize {
+		return tlv{}, errMalformed
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return tlv{}, err
+	}
+	return tlv{tag: tag, content: content}, nil
+}
+
+// parseChildren memecah isi elemen constructed menjadi elemen-elemen di dalamnya
+func parseChildren(content []byte) ([]tlv, error) {
+	var children []tlv
+	for len(content) > 0 {
+		if len(content) < 2 {
+			return nil, errMalformed
+		}
+		tag := content[0]
+		length := int(content[1])
+		offset := 2
+		if content[1]&0x80 != 0 {
+			count := int(content[1] & 0x7f)
+			if count == 0 || count > 4 || len(content) < 2+count {
+				return nil, errMalformed
+			}
+			length = 0
+			for i := 0; i < count; i++ {
+				length = length<<8 | int(content[2+i])
+			}
+			offset += count
+		}
+		if length < 0 || len(content) < offset+length {
+			return nil, errMalformed
+		}
+		children = append(children, tlv{tag: tag, content: content[offset : offset+length]})
+		content = content[offset+length:]
+	}
+	return children, nil
+}
+
+func decodeInteger(content []byte) (int64, error) {
+	if len(content) == 0 || len(content) > 8 {
+		return 0, errMalformed
+	}
+	value := int64(int8(content[0]))
+	for _, b := range content[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
@@ -0,0 +1,181 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestEncodeTLVLength(t *testing.T) {
+	tests := []struct {
+		name       string
+		length     int
+		wantHeader string
+	}{
+		{"short form", 5, "0405"},
+		{"short form max", 0x7f, "047f"},
+		{"one length byte", 0x80, "048180"},
+		{"one length byte max", 0xff, "0481ff"},
+		{"two length bytes", 0x100, "04820100"},
+		{"four length bytes", 0x10000, "048400010000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := encodeTLV(tagOctetString, make([]byte, tt.length))
+			header := mustHex(t, tt.wantHeader)
+			if !bytes.Equal(encoded[:len(header)], header) || len(encoded) != len(header)+tt.length {
+				t.Errorf("encodeTLV header = %x, want %s", encoded[:len(header)], tt.wantHeader)
+			}
+
+			decoded, err := readTLV(bufio.NewReader(bytes.NewReader(encoded)))
+			if err != nil || decoded.tag != tagOctetString || len(decoded.content) != tt.length {
+				t.Errorf("readTLV = tag %x, %d bytes (%v)", decoded.tag, len(decoded.content), err)
+			}
+		})
+	}
+}
+
+func TestEncodeInteger(t *testing.T) {
+	tests := []struct {
+		value int64
+		want  string
+	}{
+		{0, "020100"},
+		{1, "020101"},
+		{127, "02017f"},
+		{128, "02020080"},
+		{255, "020200ff"},
+		{256, "02020100"},
+		{-1, "0201ff"},
+		{-128, "020180"},
+		{-129, "0202ff7f"},
+		{2147483647, "02047fffffff"},
+	}
+
+	for _, tt := range tests {
+		got := encodeInteger(tagInteger, tt.value)
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("encodeInteger(%d) = %x, want %s", tt.value, got, tt.want)
+		}
+		decoded, err := decodeInteger(got[2:])
+		if err != nil || decoded != tt.value {
+			t.Errorf("decodeInteger(%x) = %d (%v), want %d", got[2:], decoded, err, tt.value)
+		}
+	}
+}
+
+func TestDecodeIntegerErrors(t *testing.T) {
+	for _, content := range [][]byte{nil, make([]byte, 9)} {
+		if _, err := decodeInteger(content); err == nil {
+			t.Errorf("decodeInteger(%x) expected error", content)
+		}
+	}
+}
+
+func TestEncodeBoolean(t *testing.T) {
+	if got := hex.EncodeToString(encodeBoolean(true)); got != "0101ff" {
+		t.Errorf("encodeBoolean(true) = %s", got)
+	}
+	if got := hex.EncodeToString(encodeBoolean(false)); got != "010100" {
+		t.Errorf("encodeBoolean(false) = %s", got)
+	}
+}
+
+func TestReadTLVErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"empty", "", io.EOF},
+		{"missing length", "30", io.EOF},
+		{"indefinite length", "3080", errMalformed},
+		{"length of length too big", "308500000000ff", errMalformed},
+		{"message too large", "308401000001", errMalformed},
+		{"truncated length", "308201", io.EOF},
+		{"truncated content", "300301", io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readTLV(bufio.NewReader(bytes.NewReader(mustHex(t, tt.data))))
+			if err != tt.want {
+				t.Errorf("readTLV(%s) error = %v, want %v", tt.data, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChildren(t *testing.T) {
+	long := encodeString(tagOctetString, string(make([]byte, 300)))
+	content := append(append(encodeInteger(tagInteger, 7), long...), encodeBoolean(true)...)
+
+	children, err := parseChildren(content)
+	if err != nil {
+		t.Fatalf("parseChildren: %v", err)
+	}
+	if len(children) != 3 || children[0].tag != tagInteger || len(children[1].content) != 300 || children[2].tag != tagBoolean {
+		t.Errorf("parseChildren = %+v", children)
+	}
+
+	if children, err := parseChildren(nil); err != nil || len(children) != 0 {
+		t.Errorf("parseChildren(nil) = %v (%v), want empty", children, err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"single byte", "02"},
+		{"content shorter than length", "020301"},
+		{"indefinite length", "3080"},
+		{"length bytes missing", "0482"},
+		{"long form content short", "04820100ff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseChildren(mustHex(t, tt.data)); err != errMalformed {
+				t.Errorf("parseChildren(%s) error = %v, want errMalformed", tt.data, err)
+			}
+		})
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	result := func(code int64, message string) tlv {
+		encoded := encodeSequence(appBindResponse,
+			encodeInteger(tagEnumerated, code),
+			encodeString(tagOctetString, ""),
+			encodeString(tagOctetString, message),
+		)
+		op, _ := readTLV(bufio.NewReader(bytes.NewReader(encoded)))
+		return op
+	}
+
+	if err := parseResult(result(resultSuccess, "")); err != nil {
+		t.Errorf("parseResult(success) = %v", err)
+	}
+	if err := parseResult(result(resultInvalidCredentials, "80090308: LdapErr")); err != ErrInvalidCredentials {
+		t.Errorf("parseResult(49) = %v, want ErrInvalidCredentials", err)
+	}
+	err := parseResult(result(50, "insufficient access"))
+	if resultErr, ok := err.(*ResultError); !ok || resultErr.Code != 50 || resultErr.Message != "insufficient access" {
+		t.Errorf("parseResult(50) = %v", err)
+	}
+	if err := parseResult(tlv{tag: appBindResponse, content: encodeInteger(tagInteger, 0)}); err != errMalformed {
+		t.Errorf("parseResult(integer code) = %v, want errMalformed", err)
+	}
+}
@@ -0,0 +1,292 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client LDAPv3 sinkron minimal: bind sederhana, StartTLS dan search (RFC 4511)
+
+const (
+	appBindRequest           = 0x60
+	appBindResponse          = 0x61
+	appUnbindRequest         = 0x42
+	appSearchRequest         = 0x63
+	appSearchResultEntry     = 0x64
+	appSearchResultDone      = 0x65
+	appSearchResultRef       = 0x73
+	appExtendedRequest       = 0x77
+	appExtendedResponse      = 0x78
+	bindSimple               = 0x80
+	extendedRequestName      = 0x80
+	startTLSOID              = "1.3.6.1.4.1.1466.20037"
+	resultSuccess            = 0
+	resultInvalidCredentials = 49
+
+	scopeWholeSubtree = 2
+	derefNever        = 0
+)
+
+// ErrInvalidCredentials dikembalikan bila bind ditolak (resultCode 49)
+var ErrInvalidCredentials = errors.New("ldap: invalid credentials")
+
+// ResultError adalah LDAPResult selain success
+type ResultError struct {
+	Code    int64
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Entry adalah hasil search, nama atribut disimpan lowercase
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// First mengembalikan nilai pertama atribut atau string kosong
+func (e *Entry) First(attribute string) string {
+	values := e.Attributes[strings.ToLower(attribute)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+type conn struct {
+	netConn   net.Conn
+	reader    *bufio.Reader
+	messageID int64
+	timeout   time.Duration
+}
+
+func dial(cfg *Config) (*conn, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	tlsConfig := cfg.tlsConfig(u.Hostname())
+	dialer := &net.Dialer{Timeout: cfg.timeout()}
+
+	var netConn net.Conn
+	switch u.Scheme {
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		netConn, err = tls.DialWithDialer(dialer, "tcp", host, tlsConfig)
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		netConn, err = dialer.Dial("tcp", host)
+	default:
+		return nil, fmt.Errorf("ldap: unsupported URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &conn{netConn: netConn, reader: bufio.NewReader(netConn), timeout: cfg.timeout()}
+	if u.Scheme == "ldap" && cfg.StartTLS {
+		if err := c.startTLS(tlsConfig); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *conn) close() {
+	c.send(encodeTLV(appUnbindRequest, nil))
+	c.netConn.Close()
+}
+
+func (c *conn) send(operation []byte) (int64, error) {
+	c.messageID++
+	message := encodeSequence(tagSequence, encodeInteger(tagInteger, c.messageID), operation)
+	c.netConn.SetDeadline(time.Now().Add(c.timeout))
+	_, err := c.netConn.Write(message)
+	return c.messageID, err
+}
+
+// receive membaca pesan berikutnya untuk messageID, mengembalikan protocolOp
+func (c *conn) receive(messageID int64) (tlv, error) {
+	for {
+		c.netConn.SetDeadline(time.Now().Add(c.timeout))
+		message, err := readTLV(c.reader)
+		if err != nil {
+			return tlv{}, err
+		}
+		if message.tag != tagSequence {
+			return tlv{}, errMalformed
+		}
+		children, err := parseChildren(message.content)
+		if err != nil || len(children) < 2 || children[0].tag != tagInteger {
+			return tlv{}, errMalformed
+		}
+		id, err := decodeInteger(children[0].content)
+		if err != nil {
+			return tlv{}, err
+		}
+		if id == 0 {
+			// unsolicited notification (misal notice of disconnection)
+			return tlv{}, errors.New("ldap: server closed the connection")
+		}
+		if id == messageID {
+			return children[1], nil
+		}
+	}
+}
+
+// parseResult membaca LDAPResult (resultCode, matchedDN, diagnosticMessage)
+func parseResult(op tlv) error {
+	children, err := parseChildren(op.content)
+	if err != nil || len(children) < 3 || children[0].tag != tagEnumerated {
+		return errMalformed
+	}
+	code, err := decodeInteger(children[0].content)
+	if err != nil {
+		return err
+	}
+	if code == resultSuccess {
+		return nil
+	}
+	if code == resultInvalidCredentials {
+		return ErrInvalidCredentials
+	}
+	return &ResultError{Code: code, Message: string(children[2].content)}
+}
+
+func (c *conn) startTLS(tlsConfig *tls.Config) error {
+	id, err := c.send(encodeSequence(appExtendedRequest, encodeString(extendedRequestName, startTLSOID)))
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != appExtendedResponse {
+		return errMalformed
+	}
+	if err := parseResult(op); err != nil {
+		return err
+	}
+
+	tlsConn := tls.Client(c.netConn, tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(c.timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+	c.netConn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+func (c *conn) bind(dn, password string) error {
+	request := encodeSequence(appBindRequest,
+		encodeInteger(tagInteger, 3),
+		encodeString(tagOctetString, dn),
+		encodeString(bindSimple, password),
+	)
+	id, err := c.send(request)
+	if err != nil {
+		return err
+	}
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != appBindResponse {
+		return errMalformed
+	}
+	return parseResult(op)
+}
+
+func (c *conn) search(baseDN, filter string, attributes []string, sizeLimit int64) ([]Entry, error) {
+	encodedFilter, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var attributeList [][]byte
+	for _, attribute := range attributes {
+		attributeList = append(attributeList, encodeString(tagOctetString, attribute))
+	}
+
+	request := encodeSequence(appSearchRequest,
+		encodeString(tagOctetString, baseDN),
+		encodeInteger(tagEnumerated, scopeWholeSubtree),
+		encodeInteger(tagEnumerated, derefNever),
+		encodeInteger(tagInteger, sizeLimit),
+		encodeInteger(tagInteger, int64(c.timeout.Seconds())),
+		encodeBoolean(false),
+		encodedFilter,
+		encodeSequence(tagSequence, attributeList...),
+	)
+	id, err := c.send(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.tag {
+		case appSearchResultEntry:
+			entry, err := parseEntry(op)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case appSearchResultRef:
+			// referral tidak diikuti
+		case appSearchResultDone:
+			return entries, parseResult(op)
+		default:
+			return nil, errMalformed
+		}
+	}
+}
+
+func parseEntry(op tlv) (Entry, error) {
+	children, err := parseChildren(op.content)
+	if err != nil || len(children) < 2 {
+		return Entry{}, errMalformed
+	}
+	entry := Entry{DN: string(children[0].content), Attributes: make(map[string][]string)}
+
+	attributes, err := parseChildren(children[1].content)
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, attribute := range attributes {
+		parts, err := parseChildren(attribute.content)
+		if err != nil || len(parts) < 2 {
+			return Entry{}, errMalformed
+		}
+		values, err := parseChildren(parts[1].content)
+		if err != nil {
+			return Entry{}, err
+		}
+		name := strings.ToLower(string(parts[0].content))
+		for _, value := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(value.content))
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,145 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeServer menjawab request LDAP dari conn lewat net.Pipe, handle mengembalikan protocolOp balasan
+func fakeServer(t *testing.T, handle func(op tlv) [][]byte) *conn {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close(); server.Close() })
+
+	go func() {
+		reader := bufio.NewReader(server)
+		for {
+			message, err := readTLV(reader)
+			if err != nil {
+				return
+			}
+			children, err := parseChildren(message.content)
+			if err != nil || len(children) < 2 {
+				return
+			}
+			id, _ := decodeInteger(children[0].content)
+			// pesan lain dengan message id berbeda harus dilewati oleh receive
+			server.Write(encodeSequence(tagSequence, encodeInteger(tagInteger, id+100), encodeSequence(appSearchResultDone)))
+			for _, reply := range handle(children[1]) {
+				server.Write(encodeSequence(tagSequence, encodeInteger(tagInteger, id), reply))
+			}
+		}
+	}()
+
+	return &conn{netConn: client, reader: bufio.NewReader(client), timeout: time.Second}
+}
+
+func ldapResult(tag byte, code int64, message string) []byte {
+	return encodeSequence(tag, encodeInteger(tagEnumerated, code), encodeString(tagOctetString, ""), encodeString(tagOctetString, message))
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		reply    []byte
+		wantErr  error
+	}{
+		{"success", "secret", ldapResult(appBindResponse, resultSuccess, ""), nil},
+		{"invalid credentials", "wrong", ldapResult(appBindResponse, resultInvalidCredentials, ""), ErrInvalidCredentials},
+		{"unexpected operation", "secret", ldapResult(appSearchResultDone, resultSuccess, ""), errMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotDN, gotPassword string
+			c := fakeServer(t, func(op tlv) [][]byte {
+				children, _ := parseChildren(op.content)
+				if op.tag == appBindRequest && len(children) == 3 {
+					gotDN, gotPassword = string(children[1].content), string(children[2].content)
+				}
+				return [][]byte{tt.reply}
+			})
+
+			err := c.bind("uid=jdoe,ou=people,dc=example,dc=com", tt.password)
+			if err != tt.wantErr {
+				t.Errorf("bind() error = %v, want %v", err, tt.wantErr)
+			}
+			if gotDN != "uid=jdoe,ou=people,dc=example,dc=com" || gotPassword != tt.password {
+				t.Errorf("server received dn %q password %q", gotDN, gotPassword)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	entry := func(dn string, attributes ...[]byte) []byte {
+		return encodeSequence(appSearchResultEntry, encodeString(tagOctetString, dn), encodeSequence(tagSequence, attributes...))
+	}
+	attribute := func(name string, values ...string) []byte {
+		var encoded [][]byte
+		for _, value := range values {
+			encoded = append(encoded, encodeString(tagOctetString, value))
+		}
+		return encodeSequence(tagSequence, encodeString(tagOctetString, name), encodeSequence(tagSet, encoded...))
+	}
+
+	var gotFilter []byte
+	c := fakeServer(t, func(op tlv) [][]byte {
+		children, _ := parseChildren(op.content)
+		if len(children) == 8 {
+			gotFilter = encodeTLV(children[6].tag, children[6].content)
+		}
+		return [][]byte{
+			entry("uid=jdoe,ou=people,dc=example,dc=com", attribute("mail", "jdoe@example.com"), attribute("memberOf", "cn=admins,dc=example,dc=com", "cn=staff,dc=example,dc=com")),
+			encodeSequence(appSearchResultRef, encodeString(tagOctetString, "ldap://other.example.com/")),
+			ldapResult(appSearchResultDone, resultSuccess, ""),
+		}
+	})
+
+	entries, err := c.search("dc=example,dc=com", "(uid="+EscapeFilter("jdoe")+")", []string{"mail", "memberOf"}, 2)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	wantFilter, _ := compileFilter("(uid=jdoe)")
+	if string(gotFilter) != string(wantFilter) {
+		t.Errorf("server received filter %x, want %x", gotFilter, wantFilter)
+	}
+	if len(entries) != 1 || entries[0].DN != "uid=jdoe,ou=people,dc=example,dc=com" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[0].First("MAIL") != "jdoe@example.com" || len(entries[0].Attributes["memberof"]) != 2 {
+		t.Errorf("attributes = %v", entries[0].Attributes)
+	}
+	if entries[0].First("cn") != "" {
+		t.Errorf("First(cn) = %q, want empty", entries[0].First("cn"))
+	}
+
+	if _, err := c.search("dc=example,dc=com", "(uid=jdoe", nil, 1); err != errInvalidFilter {
+		t.Errorf("search with invalid filter = %v, want errInvalidFilter", err)
+	}
+}
+
+func TestSearchResultError(t *testing.T) {
+	c := fakeServer(t, func(op tlv) [][]byte {
+		return [][]byte{ldapResult(appSearchResultDone, 32, "no such object")}
+	})
+
+	_, err := c.search("ou=missing,dc=example,dc=com", "(uid=jdoe)", nil, 1)
+	if resultErr, ok := err.(*ResultError); !ok || resultErr.Code != 32 {
+		t.Errorf("search() error = %v, want ResultError 32", err)
+	}
+}
+
+func TestReceiveNoticeOfDisconnection(t *testing.T) {
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close(); server.Close() })
+	go server.Write(encodeSequence(tagSequence, encodeInteger(tagInteger, 0), encodeSequence(appExtendedResponse)))
+
+	c := &conn{netConn: client, reader: bufio.NewReader(client), timeout: time.Second}
+	if _, err := c.receive(1); err == nil {
+		t.Errorf("receive() with unsolicited notification expected error")
+	}
+}
@@ -0,0 +1,182 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Parser filter string RFC 4515 ke BER (RFC 4511 §4.5.1.7).
+// Didukung: & | ! = ~= >= <= =* dan substring (a*b*c).
+
+const (
+	filterAnd            = 0xa0
+	filterOr             = 0xa1
+	filterNot            = 0xa2
+	filterEquality       = 0xa3
+	filterSubstrings     = 0xa4
+	filterGreaterOrEqual = 0xa5
+	filterLessOrEqual    = 0xa6
+	filterPresent        = 0x87
+	filterApprox         = 0xa8
+
+	substringInitial = 0x80
+	substringAny     = 0x81
+	substringFinal   = 0x82
+)
+
+var errInvalidFilter = errors.New("ldap: invalid search filter")
+
+// EscapeFilter meng-escape nilai yang disisipkan ke filter (RFC 4515 §3)
+func EscapeFilter(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '*' || c == '(' || c == ')' || c == '\\' || c == 0 || c >= 0x80:
+			builder.WriteString(`\` + hex.EncodeToString([]byte{c}))
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+func compileFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	encoded, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, errInvalidFilter
+	}
+	return encoded, nil
+}
+
+// parseFilter mem-parse satu "(...)" dan mengembalikan sisa string
+func parseFilter(filter string) ([]byte, string, error) {
+	if len(filter) < 3 || filter[0] != '(' {
+		return nil, "", errInvalidFilter
+	}
+
+	switch filter[1] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if filter[1] == '|' {
+			tag = filterOr
+		}
+		rest := filter[2:]
+		var children [][]byte
+		for len(rest) > 0 && rest[0] == '(' {
+			child, remaining, err := parseFilter(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			children = append(children, child)
+			rest = remaining
+		}
+		if len(children) == 0 || len(rest) == 0 || rest[0] != ')' {
+			return nil, "", errInvalidFilter
+		}
+		return encodeSequence(tag, children...), rest[1:], nil
+	case '!':
+		child, rest, err := parseFilter(filter[2:])
+		if err != nil {
+			return nil, "", err
+		}
+		if len(rest) == 0 || rest[0] != ')' {
+			return nil, "", errInvalidFilter
+		}
+		return encodeSequence(filterNot, child), rest[1:], nil
+	}
+
+	end := strings.IndexByte(filter, ')')
+	if end < 0 {
+		return nil, "", errInvalidFilter
+	}
+	item, err := parseItem(filter[1:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return item, filter[end+1:], nil
+}
+
+func parseItem(item string) ([]byte, error) {
+	eq := strings.IndexByte(item, '=')
+	if eq <= 0 {
+		return nil, errInvalidFilter
+	}
+	attribute, value := item[:eq], item[eq+1:]
+
+	tag := byte(filterEquality)
+	switch attribute[len(attribute)-1] {
+	case '~':
+		tag, attribute = filterApprox, attribute[:len(attribute)-1]
+	case '>':
+		tag, attribute = filterGreaterOrEqual, attribute[:len(attribute)-1]
+	case '<':
+		tag, attribute = filterLessOrEqual, attribute[:len(attribute)-1]
+	}
+	if attribute == "" {
+		return nil, errInvalidFilter
+	}
+
+	if tag == filterEquality && value == "*" {
+		return encodeString(filterPresent, attribute), nil
+	}
+
+	if tag == filterEquality && strings.Contains(value, "*") {
+		parts := strings.Split(value, "*")
+		var substrings [][]byte
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			decoded, err := unescapeFilterValue(part)
+			if err != nil {
+				return nil, err
+			}
+			partTag := byte(substringAny)
+			if i == 0 {
+				partTag = substringInitial
+			} else if i == len(parts)-1 {
+				partTag = substringFinal
+			}
+			substrings = append(substrings, encodeString(partTag, decoded))
+		}
+		return encodeSequence(filterSubstrings, encodeString(tagOctetString, attribute), encodeSequence(tagSequence, substrings...)), nil
+	}
+
+	decoded, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSequence(tag, encodeString(tagOctetString, attribute), encodeString(tagOctetString, decoded)), nil
+}
+
+func unescapeFilterValue(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			builder.WriteByte(value[i])
+			continue
+		}
+		if i+3 > len(value) {
+			return "", errInvalidFilter
+		}
+		decoded, err := hex.DecodeString(value[i+1 : i+3])
+		if err != nil {
+			return "", errInvalidFilter
+		}
+		builder.Write(decoded)
+		i += 2
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,117 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"jdoe", "jdoe"},
+		{"john.doe@example.com", "john.doe@example.com"},
+		{"*", `\2a`},
+		{"admin)(uid=*", `admin\29\28uid=\2a`},
+		{`a\b`, `a\5cb`},
+		{"nul\x00byte", `nul\00byte`},
+		{"Lučić", `Lu\c4\8di\c4\87`},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got := EscapeFilter(tt.value)
+		if got != tt.want {
+			t.Errorf("EscapeFilter(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+		if decoded, err := unescapeFilterValue(got); err != nil || decoded != tt.value {
+			t.Errorf("unescapeFilterValue(%q) = %q (%v), want %q", got, decoded, err, tt.value)
+		}
+	}
+}
+
+func TestEscapedValueStaysLiteral(t *testing.T) {
+	// nilai dari user tidak boleh mengubah struktur filter (injection)
+	encoded, err := compileFilter("(uid=" + EscapeFilter("*)(|(uid=*") + ")")
+	if err != nil {
+		t.Fatalf("compileFilter: %v", err)
+	}
+	want := encodeSequence(filterEquality, encodeString(tagOctetString, "uid"), encodeString(tagOctetString, "*)(|(uid=*"))
+	if hex.EncodeToString(encoded) != hex.EncodeToString(want) {
+		t.Errorf("compileFilter = %x, want equality match %x", encoded, want)
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	str := func(tag byte, value string) []byte { return encodeString(tag, value) }
+	ava := func(tag byte, attribute, value string) []byte {
+		return encodeSequence(tag, str(tagOctetString, attribute), str(tagOctetString, value))
+	}
+	substrings := func(attribute string, parts ...[]byte) []byte {
+		return encodeSequence(filterSubstrings, str(tagOctetString, attribute), encodeSequence(tagSequence, parts...))
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   []byte
+	}{
+		{"equality", "(uid=jdoe)", ava(filterEquality, "uid", "jdoe")},
+		{"without parentheses", "uid=jdoe", ava(filterEquality, "uid", "jdoe")},
+		{"surrounding space", "  (uid=jdoe) ", ava(filterEquality, "uid", "jdoe")},
+		{"escaped value", `(cn=a\2ab)`, ava(filterEquality, "cn", "a*b")},
+		{"approx", "(cn~=john)", ava(filterApprox, "cn", "john")},
+		{"greater or equal", "(uidNumber>=1000)", ava(filterGreaterOrEqual, "uidNumber", "1000")},
+		{"less or equal", "(uidNumber<=2000)", ava(filterLessOrEqual, "uidNumber", "2000")},
+		{"present", "(mail=*)", str(filterPresent, "mail")},
+		{"substring initial", "(cn=jo*)", substrings("cn", str(substringInitial, "jo"))},
+		{"substring final", "(cn=*doe)", substrings("cn", str(substringFinal, "doe"))},
+		{"substring all", "(cn=j*h*e)", substrings("cn", str(substringInitial, "j"), str(substringAny, "h"), str(substringFinal, "e"))},
+		{"substring any", "(cn=*oh*)", substrings("cn", str(substringAny, "oh"))},
+		{"and", "(&(objectClass=person)(uid=jdoe))", encodeSequence(filterAnd, ava(filterEquality, "objectClass", "person"), ava(filterEquality, "uid", "jdoe"))},
+		{"or", "(|(uid=jdoe)(mail=jdoe))", encodeSequence(filterOr, ava(filterEquality, "uid", "jdoe"), ava(filterEquality, "mail", "jdoe"))},
+		{"not", "(!(disabled=TRUE))", encodeSequence(filterNot, ava(filterEquality, "disabled", "TRUE"))},
+		{"nested", "(&(|(uid=a)(uid=b))(!(st=0)))", encodeSequence(filterAnd,
+			encodeSequence(filterOr, ava(filterEquality, "uid", "a"), ava(filterEquality, "uid", "b")),
+			encodeSequence(filterNot, ava(filterEquality, "st", "0")),
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := compileFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("compileFilter(%q): %v", tt.filter, err)
+			}
+			if hex.EncodeToString(got) != hex.EncodeToString(tt.want) {
+				t.Errorf("compileFilter(%q) = %x, want %x", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	filters := []string{
+		"",
+		"()",
+		"(uid)",
+		"(=jdoe)",
+		"(~=jdoe)",
+		"(uid=jdoe",
+		"(uid=jdoe))",
+		"(uid=jdoe)(cn=x)",
+		"(&)",
+		"(&(uid=a)",
+		"(!(uid=a)",
+		`(uid=a\2)`,
+		`(uid=a\zz)`,
+		`(cn=a*\g1)`,
+	}
+
+	for _, filter := range filters {
+		if got, err := compileFilter(filter); err != errInvalidFilter {
+			t.Errorf("compileFilter(%q) = %x (%v), want errInvalidFilter", filter, got, err)
+		}
+	}
+}
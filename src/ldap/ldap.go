@@ -0,0 +1,270 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Autentikasi ke LDAP / Active Directory dengan pola search-then-bind:
+// bind sebagai service account, cari DN user dengan user_filter, lalu bind ulang sebagai DN tersebut.
+// Konfigurasi dibaca dari file JSON, contoh (Active Directory):
+/*
+{
+	"url": "ldaps://dc1.corp.example.com",
+	"start_tls": false,
+	"insecure_skip_verify": false,
+	"ca_cert_file": "/etc/ssl/certs/corp-ca.pem",
+	"bind_dn": "CN=svc-auth,OU=Service Accounts,DC=corp,DC=example,DC=com",
+	"bind_password_env": "LDAPBINDPASSWORD",
+	"base_dn": "DC=corp,DC=example,DC=com",
+	"user_filter": "(&(objectClass=user)(|(sAMAccountName={username})(mail={username})))",
+	"attributes": {"username": "sAMAccountName", "email": "mail", "full_name": "displayName", "groups": "memberOf"},
+	"group_roles": [
+		{"group": "CN=Auth Admins,OU=Groups,DC=corp,DC=example,DC=com", "role": "admin"}
+	],
+	"default_role": "system user",
+	"require_group": false,
+	"timeout_seconds": 10
+}
+*/
+
+type AttributeMapping struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+	Groups   string `json:"groups"`
+}
+
+// GroupRole memetakan DN grup ke nilai kolom role, entri pertama yang cocok dipakai
+type GroupRole struct {
+	Group string `json:"group"`
+	Role  string `json:"role"`
+}
+
+type Config struct {
+	URL                string           `json:"url"`
+	StartTLS           bool             `json:"start_tls"`
+	InsecureSkipVerify bool             `json:"insecure_skip_verify"`
+	CACertFile         string           `json:"ca_cert_file"`
+	BindDN             string           `json:"bind_dn"`
+	BindPassword       string           `json:"bind_password"`
+	BindPasswordEnv    string           `json:"bind_password_env"`
+	BaseDN             string           `json:"base_dn"`
+	UserFilter         string           `json:"user_filter"`
+	Attributes         AttributeMapping `json:"attributes"`
+	GroupRoles         []GroupRole      `json:"group_roles"`
+	DefaultRole        string           `json:"default_role"`
+	RequireGroup       bool             `json:"require_group"` // tolak user yang tidak ada di group_roles
+	TimeoutSeconds     int              `json:"timeout_seconds"`
+
+	rootCAs *x509.CertPool
+}
+
+// User adalah profil directory setelah bind berhasil
+type User struct {
+	DN       string
+	Username string
+	Email    string
+	FullName string
+	Role     string
+}
+
+var (
+	config   *Config
+	configMu sync.RWMutex
+)
+
+// ErrNotFound dikembalikan bila user_filter tidak menemukan tepat satu entri
+var ErrNotFound = errors.New("ldap: user not found")
+
+// ErrNotAllowed dikembalikan bila require_group aktif dan user tidak ada di grup yang dipetakan
+var ErrNotAllowed = errors.New("ldap: user is not a member of an allowed group")
+
+// Init membaca konfigurasi dari file JSON. File kosong berarti LDAP dimatikan.
+func Init(configFile string) error {
+	var loaded *Config
+	if configFile != "" {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+		loaded = &Config{}
+		if err := json.Unmarshal(data, loaded); err != nil {
+			return fmt.Errorf("ldap: invalid config: %w", err)
+		}
+		if err := loaded.prepare(); err != nil {
+			return err
+		}
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+	config = loaded
+	return nil
+}
+
+func (cfg *Config) prepare() error {
+	if cfg.URL == "" || cfg.BaseDN == "" {
+		return errors.New("ldap: url and base_dn are required")
+	}
+	if !strings.HasPrefix(cfg.URL, "ldap://") && !strings.HasPrefix(cfg.URL, "ldaps://") {
+		return errors.New("ldap: url must start with ldap:// or ldaps://")
+	}
+	if cfg.BindPasswordEnv != "" {
+		cfg.BindPassword = os.Getenv(cfg.BindPasswordEnv)
+	}
+	if cfg.BindDN != "" && cfg.BindPassword == "" {
+		return errors.New("ldap: bind_dn is set but bind password is empty")
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(&(objectClass=person)(uid={username}))"
+	}
+	if !strings.Contains(cfg.UserFilter, "{username}") {
+		return errors.New("ldap: user_filter must contain {username}")
+	}
+	if _, err := compileFilter(strings.ReplaceAll(cfg.UserFilter, "{username}", "x")); err != nil {
+		return err
+	}
+
+	if cfg.Attributes.Username == "" {
+		cfg.Attributes.Username = "uid"
+	}
+	if cfg.Attributes.Email == "" {
+		cfg.Attributes.Email = "mail"
+	}
+	if cfg.Attributes.FullName == "" {
+		cfg.Attributes.FullName = "cn"
+	}
+	if cfg.Attributes.Groups == "" {
+		cfg.Attributes.Groups = "memberOf"
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = "system user"
+	}
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 10
+	}
+
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return err
+		}
+		cfg.rootCAs = x509.NewCertPool()
+		if !cfg.rootCAs.AppendCertsFromPEM(pem) {
+			return errors.New("ldap: no certificates found in ca_cert_file")
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) timeout() time.Duration {
+	return time.Duration(cfg.TimeoutSeconds) * time.Second
+}
+
+func (cfg *Config) tlsConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		ServerName:         serverName,
+		RootCAs:            cfg.rootCAs,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+}
+
+// Enabled mengecek apakah LDAP dikonfigurasi
+func Enabled() bool {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config != nil
+}
+
+// Authenticate memverifikasi username / password ke directory dan mengembalikan profil user
+func Authenticate(username, password string) (*User, error) {
+	configMu.RLock()
+	cfg := config
+	configMu.RUnlock()
+	if cfg == nil {
+		return nil, errors.New("ldap: not configured")
+	}
+
+	// password kosong akan menjadi unauthenticated bind yang "berhasil" (RFC 4513 §5.1.2)
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	c, err := dial(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer c.close()
+
+	if cfg.BindDN != "" {
+		if err := c.bind(cfg.BindDN, cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap: service bind failed: %w", err)
+		}
+	}
+
+	filter := strings.ReplaceAll(cfg.UserFilter, "{username}", EscapeFilter(username))
+	attributes := []string{cfg.Attributes.Username, cfg.Attributes.Email, cfg.Attributes.FullName, cfg.Attributes.Groups}
+	entries, err := c.search(cfg.BaseDN, filter, attributes, 2)
+	if err != nil {
+		var resultErr *ResultError
+		// sizeLimitExceeded (4): filter cocok dengan lebih dari satu entri
+		if errors.As(err, &resultErr) && resultErr.Code == 4 {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if len(entries) != 1 {
+		return nil, ErrNotFound
+	}
+	entry := entries[0]
+
+	if err := c.bind(entry.DN, password); err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		DN:       entry.DN,
+		Username: entry.First(cfg.Attributes.Username),
+		Email:    strings.ToLower(entry.First(cfg.Attributes.Email)),
+		FullName: entry.First(cfg.Attributes.FullName),
+	}
+	if user.Username == "" {
+		user.Username = username
+	}
+
+	role, ok := cfg.roleFor(entry.Attributes[strings.ToLower(cfg.Attributes.Groups)])
+	if !ok && cfg.RequireGroup {
+		return nil, ErrNotAllowed
+	}
+	user.Role = role
+	return user, nil
+}
+
+// roleFor memetakan keanggotaan grup ke role (perbandingan DN tidak case-sensitive)
+func (cfg *Config) roleFor(groups []string) (string, bool) {
+	for _, mapping := range cfg.GroupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(normalizeDN(group), normalizeDN(mapping.Group)) {
+				return mapping.Role, true
+			}
+		}
+	}
+	return cfg.DefaultRole, false
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.Join(parts, ",")
+}
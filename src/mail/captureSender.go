@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"bytes"
+	"net/mail"
+	"sync"
+)
+
+// CaptureSender menyimpan email di memori tanpa mengirimnya, untuk test dan local dev
+type CaptureSender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewCaptureSender() *CaptureSender {
+	return &CaptureSender{}
+}
+
+func (s *CaptureSender) Send(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg.To = append([]string(nil), msg.To...)
+	msg.Data = append([]byte(nil), msg.Data...)
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages mengembalikan salinan semua email yang tertangkap
+func (s *CaptureSender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// MessagesTo mengembalikan email yang dikirim ke alamat tertentu
+func (s *CaptureSender) MessagesTo(address string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []Message
+	for _, msg := range s.messages {
+		for _, rcpt := range msg.To {
+			if rcpt == address {
+				found = append(found, msg)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Reset menghapus semua email yang tertangkap
+func (s *CaptureSender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+// Parse mengurai Data menjadi net/mail.Message untuk membaca header dan body
+func (m Message) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
@@ -0,0 +1,63 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileSender menulis setiap email ke disk, sebagai file .eml atau dalam format maildir
+// (tmp/ -> new/) supaya bisa dibuka dengan mail client biasa saat development
+type FileSender struct {
+	dir      string
+	maildir  bool
+	hostname string
+	counter  atomic.Uint64
+}
+
+func NewFileSender(dir string, maildir bool) (*FileSender, error) {
+	if dir == "" {
+		return nil, errors.New("mail directory is required")
+	}
+
+	subDirs := []string{""}
+	if maildir {
+		subDirs = []string{"tmp", "new", "cur"}
+	}
+	for _, sub := range subDirs {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+	// karakter "/" dan ":" tidak boleh ada di nama file maildir
+	hostname = strings.NewReplacer("/", "_", ":", "_").Replace(hostname)
+
+	return &FileSender{dir: dir, maildir: maildir, hostname: hostname}, nil
+}
+
+func (s *FileSender) Send(msg Message) error {
+	now := time.Now()
+	uniq := fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), s.counter.Add(1), s.hostname)
+
+	// header envelope supaya penerima terlihat walau header To berbeda (misal BCC)
+	content := append([]byte("X-Envelope-From: "+msg.From+"\r\nX-Envelope-To: "+strings.Join(msg.To, ", ")+"\r\n"), msg.Data...)
+
+	if !s.maildir {
+		return os.WriteFile(filepath.Join(s.dir, uniq+".eml"), content, 0o644)
+	}
+
+	tmpPath := filepath.Join(s.dir, "tmp", uniq)
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(s.dir, "new", uniq))
+}
@@ -2,47 +2,59 @@ package mail
 
 import (
 	"auth_service/logger"
-	"fmt"
-	"net/smtp"
-	"os"
 )
 
-// SendEmail mengirimkan email dengan SMTP
+// SendEmail mengirimkan email plaintext lewat transport aktif
 func SendEmail(emailDestination, subject, message string) error {
-	// Ambil credential dari environment variable
+	s, from, err := activeSender()
+	if err != nil {
+		logger.Error("SendMail", "Failed to send email:", err)
+		return err
+	}
+
+	logger.Info("SendMail", "EMAIL TO: ", emailDestination)
+	logger.Info("SendMail", "SUBJECT: ", subject)
+	logger.Info("SendMail", "MESSAGE: ", message)
+
+	msg, err := buildMessage(from, emailDestination, subject, message, "")
+	if err != nil {
+		logger.Error("SendMail", "Failed to build email:", err)
+		return err
+	}
 
-	SMTPSERVER := os.Getenv("SMTPSERVER")
-	SMTPPORT := os.Getenv("SMTPPORT")
-	SMTPUSER := os.Getenv("SMTPUSER")
-	SMTPPASS := os.Getenv("SMTPPASS")
-	SMTPFROM := os.Getenv("SMTPFROM")
+	return send(s, from, emailDestination, msg)
+}
 
-	if SMTPUSER == "" || SMTPPASS == "" || SMTPSERVER == "" || SMTPPORT == "" || SMTPFROM == "" {
-		logger.Error("SendMail", "SMTP credentials are missing")
-		return fmt.Errorf("SMTP credentials are missing")
+// SendTemplate merender template bernama dalam locale penerima dan mengirimnya
+// sebagai multipart/alternative (text + HTML)
+func SendTemplate(emailDestination, locale, templateName string, data map[string]any) error {
+	s, from, err := activeSender()
+	if err != nil {
+		logger.Error("SendMail", "Failed to send email:", err)
+		return err
 	}
 
-	// logger.Info("SendMail", "-----------SMTP CONF : ")
-	// logger.Info("SendMail", "SMTPUSER: ", SMTPUSER)
-	// logger.Info("SendMail", "SMTPPASS: ", SMTPPASS)
-	// logger.Info("SendMail", "SMTPSERVER: ", SMTPSERVER)
-	// logger.Info("SendMail", "SMTPPORT: ", SMTPPORT)
+	subject, textBody, htmlBody, err := Render(templateName, locale, data)
+	if err != nil {
+		logger.Error("SendMail", "Failed to render template ", templateName, ":", err)
+		return err
+	}
 
 	logger.Info("SendMail", "EMAIL TO: ", emailDestination)
+	logger.Info("SendMail", "TEMPLATE: ", templateName, " (", NormalizeLocale(locale), ")")
 	logger.Info("SendMail", "SUBJECT: ", subject)
-	logger.Info("SendMail", "MESSAGE: ", message)
 
-	// Konfigurasi autentikasi SMTP
-	auth := smtp.PlainAuth("", SMTPUSER, SMTPPASS, SMTPSERVER)
+	msg, err := buildMessage(from, emailDestination, subject, textBody, htmlBody)
+	if err != nil {
+		logger.Error("SendMail", "Failed to build email:", err)
+		return err
+	}
 
-	// Format pesan email
-	msg := []byte("To: " + emailDestination + "\r\n" +
-		"Subject: " + subject + "\r\n" +
-		"\r\n" +
-		message + "\r\n")
+	return send(s, from, emailDestination, msg)
+}
 
-	// Kirim email
-	err := smtp.SendMail(SMTPSERVER+":"+SMTPPORT, auth, SMTPFROM, []string{emailDestination}, msg)
+func send(s Sender, from, emailDestination string, data []byte) error {
+	err := s.Send(Message{From: from, To: []string{emailDestination}, Data: data})
 	if err != nil {
 		logger.Error("SendMail", "Failed to send email:", err)
 		return err
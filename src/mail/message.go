@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// buildMessage menyusun email MIME lengkap. Bila htmlBody kosong hasilnya text/plain tunggal,
+// selain itu multipart/alternative dengan part text lalu HTML.
+func buildMessage(from, to, subject, textBody, htmlBody string) ([]byte, error) {
+	var msg bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", to)
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", generateMessageID(from))
+	header.Set("MIME-Version", "1.0")
+
+	if htmlBody == "" {
+		header.Set("Content-Type", "text/plain; charset=UTF-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&msg, header)
+		if err := writeQuotedPrintable(&msg, textBody); err != nil {
+			return nil, err
+		}
+		return msg.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	header.Set("Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+	writeHeader(&msg, header)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	}
+
+	for _, part := range parts {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(partWriter, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	// urutan tetap supaya mudah dibaca saat debugging
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+// writeQuotedPrintable menulis body dengan line ending CRLF sesuai RFC 5322
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if _, err := qp.Write([]byte(strings.ReplaceAll(content, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// generateMessageID membuat Message-ID unik dengan domain pengirim
+func generateMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(buf), domain)
+}
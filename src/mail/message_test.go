@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		subject     string
+		textBody    string
+		htmlBody    string
+		contentType string
+		parts       []string
+	}{
+		{
+			name:        "plaintext only",
+			subject:     "Reset password",
+			textBody:    "line one\nline two",
+			contentType: "text/plain",
+		},
+		{
+			name:        "utf-8 subject",
+			subject:     "Kode OTP — pendaftaran",
+			textBody:    "kode: 123456",
+			contentType: "text/plain",
+		},
+		{
+			name:        "text and html",
+			subject:     "Welcome",
+			textBody:    "hello",
+			htmlBody:    "<p>hello</p>",
+			contentType: "multipart/alternative",
+			parts:       []string{"text/plain", "text/html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := buildMessage("SIMPEL <noreply@example.com>", "user@example.com", tt.subject, tt.textBody, tt.htmlBody)
+			if err != nil {
+				t.Fatalf("buildMessage: %v", err)
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+			if err != nil {
+				t.Fatalf("ReadMessage: %v", err)
+			}
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil || subject != tt.subject {
+				t.Errorf("subject = %q (%v), want %q", subject, err, tt.subject)
+			}
+			if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+				t.Errorf("Message-ID = %q, want domain of sender", msg.Header.Get("Message-ID"))
+			}
+			if msg.Header.Get("Date") == "" || msg.Header.Get("MIME-Version") != "1.0" {
+				t.Errorf("missing Date or MIME-Version header")
+			}
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil || mediaType != tt.contentType {
+				t.Fatalf("content type = %q (%v), want %q", mediaType, err, tt.contentType)
+			}
+
+			if tt.parts == nil {
+				body, _ := io.ReadAll(msg.Body)
+				if strings.Contains(tt.textBody, "\n") && !strings.Contains(string(body), "\r\n") {
+					t.Errorf("body line endings are not CRLF: %q", body)
+				}
+				return
+			}
+
+			reader := multipart.NewReader(msg.Body, params["boundary"])
+			for _, want := range tt.parts {
+				part, err := reader.NextPart()
+				if err != nil {
+					t.Fatalf("NextPart: %v", err)
+				}
+				partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+				if partType != want {
+					t.Errorf("part type = %q, want %q", partType, want)
+				}
+			}
+			if _, err := reader.NextPart(); err != io.EOF {
+				t.Errorf("unexpected extra part: %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"id", LocaleIndonesian},
+		{"id-ID", LocaleIndonesian},
+		{"en_US", LocaleEnglish},
+		{"fr-FR,id;q=0.9,en;q=0.8", LocaleIndonesian},
+		{"fr", DefaultLocale},
+		{"", DefaultLocale},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeLocale(tt.input); got != tt.want {
+			t.Errorf("NormalizeLocale(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package mail
+
+import (
+	"auth_service/configs"
+	"auth_service/logger"
+	"auth_service/rds"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+Antrian email keluar di Redis:
+
+	mail_queue                     LIST  id pesan yang siap dikirim
+	mail_processing:<instance_id>  LIST  id yang sedang dikirim oleh satu proses
+	mail_instance:<instance_id>    STRING heartbeat proses, TTL instanceTTL
+	mail_retry                     ZSET  id yang menunggu retry, score = waktu retry (unix ms)
+	mail_dead                      ZSET  dead-letter, score = waktu gagal terakhir (unix ms)
+	mail_job:<id>                  HASH  from, to, data, status, attempts, last_error, create_tstamp, update_tstamp
+
+Handler cukup memanggil Enqueue* lalu langsung merespon, worker yang mengirim lewat Sender aktif.
+
+instance_id unik per proses (hostname, pid, acak), jadi replika di host yang sama atau container yang
+di-restart tidak berbagi processing list. Processing list milik proses yang heartbeat-nya sudah hilang
+dikembalikan ke mail_queue oleh proses lain (reclaimStale).
+
+Isi pesan berisi token (OTP, link reset, magic link), sehingga job yang sudah terkirim langsung dihapus
+isinya dan job dead-letter hanya disimpan selama mailQueueDeadRetention.
+*/
+
+// Status pesan di antrian
+const (
+	StatusQueued   = "queued"
+	StatusSending  = "sending"
+	StatusRetrying = "retrying"
+	StatusSent     = "sent"
+	StatusDead     = "dead"
+)
+
+const (
+	queueKey            = "mail_queue"
+	retryKey            = "mail_retry"
+	deadKey             = "mail_dead"
+	jobKeyPrefix        = "mail_job:"
+	processingKeyPrefix = "mail_processing:"
+	instanceKeyPrefix   = "mail_instance:"
+	popTimeout          = 5 * time.Second
+	schedulerTick       = time.Second
+	instanceTTL         = 30 * time.Second
+	reclaimInterval     = 30 * time.Second
+)
+
+// JobStatus adalah status pengiriman satu pesan
+type JobStatus struct {
+	ID           string   `json:"id"`
+	To           []string `json:"to"`
+	Status       string   `json:"status"`
+	Attempts     int      `json:"attempts"`
+	LastError    string   `json:"last_error"`
+	CreateTstamp int64    `json:"create_tstamp"`
+	UpdateTstamp int64    `json:"update_tstamp"`
+}
+
+// ErrJobNotFound dikembalikan bila id tidak dikenal, sudah kedaluwarsa, atau tidak ada di dead-letter
+var ErrJobNotFound = errors.New("mail: job not found")
+
+var (
+	queueStarted   bool
+	queueStartedMu sync.Mutex
+	instanceID     = newInstanceID()
+)
+
+func jobKey(id string) string {
+	return jobKeyPrefix + id
+}
+
+// newInstanceID membuat id unik proses: <hostname>-<pid>-<acak>
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(buf))
+}
+
+func processingKey() string {
+	return processingKeyPrefix + instanceID
+}
+
+func instanceKey(id string) string {
+	return instanceKeyPrefix + id
+}
+
+func redisClient() (*redis.Client, error) {
+	client := rds.GetRedisClient()
+	if client == nil {
+		return nil, errors.New("redis client is not initialized")
+	}
+	return client, nil
+}
+
+// Enqueue menyimpan pesan ke antrian dan mengembalikan id untuk tracking status
+func Enqueue(msg Message) (string, error) {
+	client, err := redisClient()
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+
+	to, _ := json.Marshal(msg.To)
+	now := time.Now().Unix()
+
+	ctx := context.Background()
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, jobKey(id), map[string]any{
+			"from":          msg.From,
+			"to":            string(to),
+			"data":          msg.Data,
+			"status":        StatusQueued,
+			"attempts":      0,
+			"last_error":    "",
+			"create_tstamp": now,
+			"update_tstamp": now,
+		})
+		pipe.LPush(ctx, queueKey, id)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("MailQueue", "Queued message ", id, " to: ", msg.To)
+	return id, nil
+}
+
+// EnqueueEmail menyusun email plaintext lalu memasukkannya ke antrian
+func EnqueueEmail(emailDestination, subject, message string) (string, error) {
+	_, from, err := activeSender()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := buildMessage(from, emailDestination, subject, message, "")
+	if err != nil {
+		return "", err
+	}
+
+	return Enqueue(Message{From: from, To: []string{emailDestination}, Data: data})
+}
+
+// EnqueueTemplate merender template lalu memasukkannya ke antrian
+func EnqueueTemplate(emailDestination, locale, templateName string, data map[string]any) (string, error) {
+	_, from, err := activeSender()
+	if err != nil {
+		return "", err
+	}
+
+	subject, textBody, htmlBody, err := Render(templateName, locale, data)
+	if err != nil {
+		return "", err
+	}
+
+	msgData, err := buildMessage(from, emailDestination, subject, textBody, htmlBody)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("MailQueue", "Queueing template ", templateName, " (", NormalizeLocale(locale), ") to: ", emailDestination)
+	return Enqueue(Message{From: from, To: []string{emailDestination}, Data: msgData})
+}
+
+// Status mengembalikan status pengiriman pesan berdasarkan id
+func Status(id string) (JobStatus, error) {
+	client, err := redisClient()
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	fields, err := client.HMGet(context.Background(), jobKey(id), "to", "status", "attempts", "last_error", "create_tstamp", "update_tstamp").Result()
+	if err != nil {
+		return JobStatus{}, err
+	}
+	if fields[1] == nil {
+		return JobStatus{}, ErrJobNotFound
+	}
+
+	status := JobStatus{ID: id}
+	json.Unmarshal([]byte(toString(fields[0])), &status.To)
+	status.Status = toString(fields[1])
+	status.Attempts, _ = strconv.Atoi(toString(fields[2]))
+	status.LastError = toString(fields[3])
+	status.CreateTstamp, _ = strconv.ParseInt(toString(fields[4]), 10, 64)
+	status.UpdateTstamp, _ = strconv.ParseInt(toString(fields[5]), 10, 64)
+	return status, nil
+}
+
+func toString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// StartQueue menjalankan worker pengirim dan scheduler retry sampai ctx selesai
+func StartQueue(ctx context.Context, workers int) error {
+	queueStartedMu.Lock()
+	defer queueStartedMu.Unlock()
+
+	if queueStarted {
+		return nil
+	}
+
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Set(ctx, instanceKey(instanceID), time.Now().Unix(), instanceTTL).Err(); err != nil {
+		return err
+	}
+	// pesan yang tertinggal di processing list proses lain yang mati di tengah pengiriman
+	if err := reclaimStale(ctx, client); err != nil {
+		return err
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go runWorker(ctx, i)
+	}
+	go runRetryScheduler(ctx)
+
+	queueStarted = true
+	logger.Info("MailQueue", "Started ", workers, " mail queue workers, instance ", instanceID)
+	return nil
+}
+
+// reclaimStale mengembalikan isi processing list milik proses yang heartbeat-nya sudah hilang ke mail_queue
+func reclaimStale(ctx context.Context, client *redis.Client) error {
+	iter := client.Scan(ctx, 0, processingKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		owner := strings.TrimPrefix(key, processingKeyPrefix)
+		if owner == instanceID {
+			continue
+		}
+
+		alive, err := client.Exists(ctx, instanceKey(owner)).Result()
+		if err != nil {
+			return err
+		}
+		if alive > 0 {
+			continue
+		}
+
+		for {
+			id, err := client.LMove(ctx, key, queueKey, "RIGHT", "LEFT").Result()
+			if err == redis.Nil {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			logger.Warning("MailQueue", "Recovered in-flight message ", id, " from stale instance ", owner)
+		}
+	}
+	return iter.Err()
+}
+
+func runWorker(ctx context.Context, workerID int) {
+	for ctx.Err() == nil {
+		client, err := redisClient()
+		if err != nil {
+			logger.Error("MailQueue", "Worker ", workerID, " - ", err)
+			sleepCtx(ctx, popTimeout)
+			continue
+		}
+
+		id, err := client.BLMove(ctx, queueKey, processingKey(), "RIGHT", "LEFT", popTimeout).Result()
+		if err == redis.Nil || ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			logger.Error("MailQueue", "Worker ", workerID, " - Failed to pop queue: ", err)
+			sleepCtx(ctx, popTimeout)
+			continue
+		}
+
+		processJob(ctx, client, id)
+		client.LRem(ctx, processingKey(), 1, id)
+	}
+}
+
+func processJob(ctx context.Context, client *redis.Client, id string) {
+	fields, err := client.HMGet(ctx, jobKey(id), "from", "to", "data", "attempts").Result()
+	if err != nil || fields[0] == nil {
+		logger.Error("MailQueue", "Message ", id, " not found, dropped: ", err)
+		return
+	}
+
+	msg := Message{From: toString(fields[0]), Data: []byte(toString(fields[2]))}
+	json.Unmarshal([]byte(toString(fields[1])), &msg.To)
+	attempts, _ := strconv.Atoi(toString(fields[3]))
+	attempts++
+
+	client.HSet(ctx, jobKey(id), "status", StatusSending, "attempts", attempts, "update_tstamp", time.Now().Unix())
+
+	s, _, err := activeSender()
+	if err == nil {
+		err = s.Send(msg)
+	}
+
+	now := time.Now()
+	status, delay := nextJobState(attempts, err)
+	switch status {
+	case StatusSent:
+		client.HSet(ctx, jobKey(id), "status", StatusSent, "last_error", "", "update_tstamp", now.Unix())
+		// isi pesan tidak perlu disimpan lagi, cukup status
+		client.HDel(ctx, jobKey(id), "data")
+		client.Expire(ctx, jobKey(id), time.Duration(configs.GetMailQueueStatusRetention())*time.Second)
+		logger.Info("MailQueue", "Message ", id, " sent to: ", msg.To, " (attempt ", attempts, ")")
+
+	case StatusDead:
+		client.HSet(ctx, jobKey(id), "status", StatusDead, "last_error", err.Error(), "update_tstamp", now.Unix())
+		// isi pesan (berisi token) ikut kedaluwarsa, RequeueDead hanya bisa dalam masa retensi ini
+		client.Expire(ctx, jobKey(id), time.Duration(configs.GetMailQueueDeadRetention())*time.Second)
+		client.ZAdd(ctx, deadKey, redis.Z{Score: float64(now.UnixMilli()), Member: id})
+		logger.Error("MailQueue", "Message ", id, " moved to dead-letter after ", attempts, " attempts: ", err)
+
+	default:
+		client.HSet(ctx, jobKey(id), "status", StatusRetrying, "last_error", err.Error(), "update_tstamp", now.Unix())
+		client.ZAdd(ctx, retryKey, redis.Z{Score: float64(now.Add(delay).UnixMilli()), Member: id})
+		logger.Warning("MailQueue", "Message ", id, " failed (attempt ", attempts, "), retry in ", delay, ": ", err)
+	}
+}
+
+// nextJobState menentukan status job setelah percobaan ke-attempts: sent, retrying (dengan jeda) atau dead
+func nextJobState(attempts int, sendErr error) (string, time.Duration) {
+	if sendErr == nil {
+		return StatusSent, 0
+	}
+	if attempts >= configs.GetMailQueueMaxAttempts() {
+		return StatusDead, 0
+	}
+	return StatusRetrying, retryBackoff(attempts)
+}
+
+// retryBackoff menghitung jeda exponential: base * 2^(attempts-1), dibatasi max
+func retryBackoff(attempts int) time.Duration {
+	base := time.Duration(configs.GetMailQueueBaseBackoff()) * time.Second
+	maxBackoff := time.Duration(configs.GetMailQueueMaxBackoff()) * time.Second
+
+	delay := base
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// runRetryScheduler memindahkan job retry yang jatuh tempo, memperbarui heartbeat proses,
+// dan secara berkala memulihkan processing list proses mati serta membersihkan dead-letter kedaluwarsa
+func runRetryScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	lastReclaim := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		client, err := redisClient()
+		if err != nil {
+			continue
+		}
+
+		client.Set(ctx, instanceKey(instanceID), time.Now().Unix(), instanceTTL)
+
+		if time.Since(lastReclaim) >= reclaimInterval {
+			lastReclaim = time.Now()
+			if err := reclaimStale(ctx, client); err != nil {
+				logger.Error("MailQueue", "Failed to reclaim stale in-flight messages: ", err)
+			}
+			// job dead-letter sudah kedaluwarsa (lihat processJob), id-nya ikut dibuang
+			deadBefore := time.Now().Add(-time.Duration(configs.GetMailQueueDeadRetention()) * time.Second).UnixMilli()
+			client.ZRemRangeByScore(ctx, deadKey, "-inf", fmt.Sprint(deadBefore))
+		}
+
+		due, err := client.ZRangeByScore(ctx, retryKey, &redis.ZRangeBy{Min: "-inf", Max: fmt.Sprint(time.Now().UnixMilli())}).Result()
+		if err != nil {
+			logger.Error("MailQueue", "Retry scheduler failed: ", err)
+			continue
+		}
+
+		for _, id := range due {
+			// ZREM memastikan hanya satu instance yang memindahkan id ini
+			removed, err := client.ZRem(ctx, retryKey, id).Result()
+			if err != nil || removed == 0 {
+				continue
+			}
+			client.LPush(ctx, queueKey, id)
+		}
+	}
+}
+
+// RequeueDead memindahkan pesan dari dead-letter kembali ke antrian (attempts direset)
+func RequeueDead(id string) error {
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	removed, err := client.ZRem(ctx, deadKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrJobNotFound
+	}
+
+	// job yang sudah melewati masa retensi tidak bisa dikirim ulang
+	exists, err := client.HExists(ctx, jobKey(id), "data").Result()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrJobNotFound
+	}
+
+	client.Persist(ctx, jobKey(id))
+	client.HSet(ctx, jobKey(id), "status", StatusQueued, "attempts", 0, "update_tstamp", time.Now().Unix())
+	return client.LPush(ctx, queueKey, id).Err()
+}
+
+// DeadList mengembalikan status job di dead-letter, terbaru lebih dulu
+func DeadList(limit int64) ([]JobStatus, error) {
+	client, err := redisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := client.ZRevRange(context.Background(), deadKey, 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]JobStatus, 0, len(ids))
+	for _, id := range ids {
+		status, err := Status(id)
+		if err == ErrJobNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
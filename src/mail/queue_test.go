@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	// default: base 5 detik, maksimal 600 detik
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{5, 80 * time.Second},
+		{7, 320 * time.Second},
+		{8, 600 * time.Second},
+		{50, 600 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := retryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("retryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestNextJobState(t *testing.T) {
+	sendErr := errors.New("421 service not available")
+
+	// default: maksimal 6 percobaan
+	tests := []struct {
+		name      string
+		attempts  int
+		err       error
+		wantState string
+		wantDelay time.Duration
+	}{
+		{"sent on first attempt", 1, nil, StatusSent, 0},
+		{"sent after retries", 6, nil, StatusSent, 0},
+		{"first failure retries", 1, sendErr, StatusRetrying, 5 * time.Second},
+		{"later failure backs off", 4, sendErr, StatusRetrying, 40 * time.Second},
+		{"last attempt goes dead", 6, sendErr, StatusDead, 0},
+		{"attempts over max goes dead", 9, sendErr, StatusDead, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, delay := nextJobState(tt.attempts, tt.err)
+			if state != tt.wantState || delay != tt.wantDelay {
+				t.Errorf("nextJobState(%d, %v) = %s, %v, want %s, %v", tt.attempts, tt.err, state, delay, tt.wantState, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestInstanceID(t *testing.T) {
+	first, second := newInstanceID(), newInstanceID()
+	if first == second {
+		t.Errorf("newInstanceID returned the same id twice: %s", first)
+	}
+	if !strings.HasPrefix(processingKey(), processingKeyPrefix) || strings.TrimPrefix(processingKey(), processingKeyPrefix) != instanceID {
+		t.Errorf("processingKey() = %q, want %s<instance_id>", processingKey(), processingKeyPrefix)
+	}
+	if instanceKey(first) != instanceKeyPrefix+first {
+		t.Errorf("instanceKey(%q) = %q", first, instanceKey(first))
+	}
+}
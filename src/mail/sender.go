@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Message adalah email yang sudah tersusun lengkap (header + body RFC 5322)
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Sender adalah transport pengiriman email
+type Sender interface {
+	Send(msg Message) error
+}
+
+// Backend yang bisa dipilih lewat MAILBACKEND
+const (
+	BackendSMTP    = "smtp"
+	BackendFile    = "file"
+	BackendMaildir = "maildir"
+	BackendCapture = "capture"
+)
+
+// Config berisi konfigurasi transport, diisi dari environment di main
+type Config struct {
+	Backend string
+
+	SMTPServer string
+	SMTPPort   string
+	SMTPUser   string
+	SMTPPass   string
+	SMTPTLS    string // auto (default) | none | starttls | implicit
+
+	From string
+
+	Dir string // direktori tujuan untuk backend file / maildir
+}
+
+var (
+	sender      Sender
+	fromAddress string
+	senderMu    sync.RWMutex
+)
+
+// InitSender membuat Sender sesuai backend yang dipilih dan menjadikannya transport aktif
+func InitSender(conf Config) error {
+	if conf.From == "" {
+		return errors.New("mail from address is required")
+	}
+
+	var s Sender
+	switch strings.ToLower(conf.Backend) {
+	case "", BackendSMTP:
+		smtpSender, err := NewSMTPSender(conf.SMTPServer, conf.SMTPPort, conf.SMTPUser, conf.SMTPPass, conf.SMTPTLS)
+		if err != nil {
+			return err
+		}
+		s = smtpSender
+	case BackendFile:
+		fileSender, err := NewFileSender(conf.Dir, false)
+		if err != nil {
+			return err
+		}
+		s = fileSender
+	case BackendMaildir:
+		fileSender, err := NewFileSender(conf.Dir, true)
+		if err != nil {
+			return err
+		}
+		s = fileSender
+	case BackendCapture:
+		s = NewCaptureSender()
+	default:
+		return fmt.Errorf("unknown mail backend %q", conf.Backend)
+	}
+
+	SetSender(s, conf.From)
+	return nil
+}
+
+// SetSender mengganti transport aktif, dipakai juga oleh test dan local dev
+func SetSender(s Sender, from string) {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+
+	if closer, ok := sender.(interface{ Close() error }); ok && sender != s {
+		closer.Close()
+	}
+
+	sender = s
+	fromAddress = from
+}
+
+// GetSender mengembalikan transport aktif (nil bila belum diinisialisasi)
+func GetSender() Sender {
+	senderMu.RLock()
+	defer senderMu.RUnlock()
+	return sender
+}
+
+func activeSender() (Sender, string, error) {
+	senderMu.RLock()
+	defer senderMu.RUnlock()
+
+	if sender == nil {
+		return nil, "", errors.New("mail sender is not initialized")
+	}
+	return sender, fromAddress, nil
+}
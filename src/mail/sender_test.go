@@ -0,0 +1,236 @@
+package mail
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTPServer adalah server SMTP minimal tanpa STARTTLS, menyimpan isi DATA yang diterima
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	received []string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Fields(line + " x")[0])
+		switch command {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.received = append(s.received, data.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+func TestNewSMTPSenderTLSMode(t *testing.T) {
+	tests := []struct {
+		tlsMode string
+		want    string
+		wantErr bool
+	}{
+		{"", TLSAuto, false},
+		{"auto", TLSAuto, false},
+		{"STARTTLS", TLSStartTLS, false},
+		{"implicit", TLSImplicit, false},
+		{"none", TLSNone, false},
+		{"ssl", "", true},
+	}
+
+	for _, tt := range tests {
+		sender, err := NewSMTPSender("localhost", "25", "", "", tt.tlsMode)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewSMTPSender(%q) expected error", tt.tlsMode)
+			}
+			continue
+		}
+		if err != nil || sender.tlsMode != tt.want {
+			t.Errorf("NewSMTPSender(%q) = %v (%v), want %q", tt.tlsMode, sender, err, tt.want)
+		}
+	}
+
+	if _, err := NewSMTPSender("", "25", "", "", ""); err == nil {
+		t.Errorf("NewSMTPSender without host expected error")
+	}
+}
+
+func TestSMTPSenderWithoutSTARTTLS(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+
+	tests := []struct {
+		tlsMode string
+		wantErr bool
+	}{
+		{TLSAuto, false},
+		{TLSNone, false},
+		{TLSStartTLS, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tlsMode, func(t *testing.T) {
+			sender, err := NewSMTPSender(host, port, "", "", tt.tlsMode)
+			if err != nil {
+				t.Fatalf("NewSMTPSender: %v", err)
+			}
+			defer sender.Close()
+
+			before := len(server.messages())
+			err = sender.Send(Message{From: "noreply@example.com", To: []string{"user@example.com"}, Data: []byte("Subject: test\r\n\r\nhello\r\n")})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error when server lacks STARTTLS")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if got := server.messages(); len(got) != before+1 || !strings.Contains(got[len(got)-1], "hello") {
+				t.Errorf("server received %q", got)
+			}
+		})
+	}
+}
+
+func TestCaptureSenderTemplate(t *testing.T) {
+	capture := NewCaptureSender()
+	SetSender(capture, "noreply@example.com")
+	defer SetSender(nil, "")
+
+	err := SendTemplate("user@example.com", "id", TemplateRegistrationOTP, map[string]any{
+		"FullName":      "Budi",
+		"OTP":           "123456",
+		"ExpireSeconds": 180,
+	})
+	if err != nil {
+		t.Fatalf("SendTemplate: %v", err)
+	}
+
+	messages := capture.MessagesTo("user@example.com")
+	if len(messages) != 1 {
+		t.Fatalf("captured %d messages, want 1", len(messages))
+	}
+	if messages[0].From != "noreply@example.com" {
+		t.Errorf("From = %q", messages[0].From)
+	}
+
+	parsed, err := messages[0].Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !strings.HasPrefix(parsed.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("Content-Type = %q", parsed.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(messages[0].Data), "123456") {
+		t.Errorf("OTP missing from message body")
+	}
+
+	if got := capture.MessagesTo("other@example.com"); len(got) != 0 {
+		t.Errorf("MessagesTo other = %d messages", len(got))
+	}
+	capture.Reset()
+	if got := capture.Messages(); len(got) != 0 {
+		t.Errorf("Messages after Reset = %d", len(got))
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		maildir bool
+		glob    string
+	}{
+		{"eml", false, "*.eml"},
+		{"maildir", true, "new/*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sender, err := NewFileSender(dir, tt.maildir)
+			if err != nil {
+				t.Fatalf("NewFileSender: %v", err)
+			}
+
+			for i := 0; i < 2; i++ {
+				if err := sender.Send(Message{From: "noreply@example.com", To: []string{"user@example.com"}, Data: []byte("Subject: test\r\n\r\nhello\r\n")}); err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, tt.glob))
+			if len(files) != 2 {
+				t.Fatalf("found %d files, want 2", len(files))
+			}
+			content, _ := os.ReadFile(files[0])
+			if !strings.HasPrefix(string(content), "X-Envelope-From: noreply@example.com\r\nX-Envelope-To: user@example.com\r\n") {
+				t.Errorf("missing envelope headers: %q", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package mail
+
+import (
+	"auth_service/logger"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode TLS untuk SMTPSender
+const (
+	TLSAuto     = "auto" // STARTTLS bila server mendukung, selain itu plaintext (perilaku smtp.SendMail)
+	TLSNone     = "none"
+	TLSStartTLS = "starttls" // wajib STARTTLS, gagal bila server tidak mendukung
+	TLSImplicit = "implicit"
+)
+
+const smtpDialTimeout = 10 * time.Second
+
+// SMTPSender mengirim lewat SMTP dan memakai ulang satu koneksi selama masih hidup
+type SMTPSender struct {
+	host     string
+	port     string
+	user     string
+	pass     string
+	tlsMode  string
+	mu       sync.Mutex
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// koneksi idle lebih lama dari ini ditutup dan dibuka ulang, kebanyakan server memutus sendiri
+const smtpMaxIdle = 60 * time.Second
+
+func NewSMTPSender(host, port, user, pass, tlsMode string) (*SMTPSender, error) {
+	if host == "" || port == "" {
+		return nil, errors.New("SMTP server and port are required")
+	}
+
+	tlsMode = strings.ToLower(tlsMode)
+	if tlsMode == "" {
+		// perilaku lama: net/smtp otomatis STARTTLS bila server mendukung, STARTTLS wajib harus diminta eksplisit
+		tlsMode = TLSAuto
+	}
+	if tlsMode != TLSAuto && tlsMode != TLSNone && tlsMode != TLSStartTLS && tlsMode != TLSImplicit {
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", tlsMode)
+	}
+
+	return &SMTPSender{host: host, port: port, user: user, pass: pass, tlsMode: tlsMode}, nil
+}
+
+func (s *SMTPSender) Send(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.sendLocked(msg)
+	if err == nil {
+		return nil
+	}
+
+	// koneksi lama mungkin sudah diputus server, coba sekali lagi dengan koneksi baru
+	logger.Warning("SendMail", "SMTP send failed, retrying with new connection: ", err)
+	s.closeLocked()
+	return s.sendLocked(msg)
+}
+
+func (s *SMTPSender) sendLocked(msg Message) error {
+	if err := s.ensureConnLocked(); err != nil {
+		return err
+	}
+
+	if err := s.client.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range msg.To {
+		if err := s.client.Rcpt(rcpt); err != nil {
+			s.client.Reset()
+			return err
+		}
+	}
+
+	w, err := s.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Data); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	s.lastUsed = time.Now()
+	return nil
+}
+
+func (s *SMTPSender) ensureConnLocked() error {
+	if s.client != nil {
+		if time.Since(s.lastUsed) < smtpMaxIdle && s.client.Noop() == nil {
+			return nil
+		}
+		s.closeLocked()
+	}
+
+	addr := net.JoinHostPort(s.host, s.port)
+	tlsConfig := &tls.Config{ServerName: s.host}
+
+	var conn net.Conn
+	var err error
+	if s.tlsMode == TLSImplicit {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: smtpDialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, smtpDialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if s.tlsMode == TLSStartTLS || s.tlsMode == TLSAuto {
+		ok, _ := client.Extension("STARTTLS")
+		if !ok && s.tlsMode == TLSStartTLS {
+			client.Close()
+			return errors.New("SMTP server does not support STARTTLS")
+		}
+		if ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+
+	if s.user != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.user, s.pass, s.host)); err != nil {
+			client.Close()
+			return err
+		}
+	}
+
+	s.client = client
+	s.lastUsed = time.Now()
+	return nil
+}
+
+func (s *SMTPSender) closeLocked() {
+	if s.client != nil {
+		s.client.Quit()
+		s.client.Close()
+		s.client = nil
+	}
+}
+
+// Close menutup koneksi yang sedang dipakai ulang
+func (s *SMTPSender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+	return nil
+}
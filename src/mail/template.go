@@ -0,0 +1,175 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+/*
+Template email disimpan per locale dengan 3 file per nama template:
+
+	templates/<locale>/<name>.subject   -> subject (text/template)
+	templates/<locale>/<name>.txt       -> body plaintext (text/template)
+	templates/<locale>/<name>.html      -> body HTML (html/template)
+
+Template bawaan di-embed ke binary. Bila MAILTEMPLATEDIR di-set, file di direktori
+tersebut dipakai lebih dulu dan dibaca ulang setiap kirim, jadi copy bisa diubah tanpa build.
+*/
+
+// Nama template yang tersedia
+const (
+	TemplateRegistrationOTP         = "registration_otp"
+	TemplateRegisterExistingAccount = "register_existing_account"
+	TemplateRegisterUnavailable     = "register_unavailable"
+	TemplateResetLink               = "reset_link"
+	TemplateResetUnknownAccount     = "reset_unknown_account"
+	TemplatePasswordChanged         = "password_changed"
+	TemplateLockoutNotice           = "lockout_notice"
+	TemplateNewDeviceLogin          = "new_device_login"
+	TemplateRecoveryCodesLow        = "recovery_codes_low"
+	TemplateMagicLink               = "magic_link"
+)
+
+const (
+	LocaleEnglish    = "en"
+	LocaleIndonesian = "id"
+)
+
+var DefaultLocale = LocaleEnglish
+
+var supportedLocales = map[string]bool{
+	LocaleEnglish:    true,
+	LocaleIndonesian: true,
+}
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+var (
+	templateDir   string
+	templateDirMu sync.RWMutex
+)
+
+// InitTemplates mengatur direktori template di disk. Direktori kosong berarti hanya template bawaan.
+func InitTemplates(dir string) error {
+	templateDirMu.Lock()
+	defer templateDirMu.Unlock()
+
+	if dir == "" {
+		templateDir = ""
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("mail template path is not a directory")
+	}
+
+	templateDir = dir
+	return nil
+}
+
+// NormalizeLocale mengubah nilai seperti "id-ID" atau header Accept-Language
+// ("id-ID,id;q=0.9,en;q=0.8") menjadi locale yang didukung, fallback ke DefaultLocale
+func NormalizeLocale(locale string) string {
+	for _, tag := range strings.Split(locale, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		tag, _, _ = strings.Cut(tag, "-")
+		tag, _, _ = strings.Cut(tag, "_")
+		if supportedLocales[tag] {
+			return tag
+		}
+	}
+	return DefaultLocale
+}
+
+// readTemplateFile membaca file dari direktori disk lebih dulu, lalu dari template bawaan
+func readTemplateFile(locale, fileName string) (string, error) {
+	templateDirMu.RLock()
+	dir := templateDir
+	templateDirMu.RUnlock()
+
+	if dir != "" {
+		content, err := os.ReadFile(path.Join(dir, locale, fileName))
+		if err == nil {
+			return string(content), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	content, err := embeddedTemplates.ReadFile(path.Join("templates", locale, fileName))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Render menghasilkan subject, body text dan body HTML untuk template dan locale tertentu.
+// Locale yang tidak punya template tersebut jatuh ke DefaultLocale.
+func Render(name, locale string, data any) (subject, text, html string, err error) {
+	locale = NormalizeLocale(locale)
+	if _, errCheck := readTemplateFile(locale, name+".subject"); errCheck != nil {
+		locale = DefaultLocale
+	}
+
+	subjectSource, err := readTemplateFile(locale, name+".subject")
+	if err != nil {
+		return "", "", "", err
+	}
+	textSource, err := readTemplateFile(locale, name+".txt")
+	if err != nil {
+		return "", "", "", err
+	}
+	htmlSource, err := readTemplateFile(locale, name+".html")
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var buf bytes.Buffer
+
+	subjectTmpl, err := texttemplate.New(name + ".subject").Parse(subjectSource)
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := subjectTmpl.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	// subject harus satu baris
+	subject = strings.Join(strings.Fields(buf.String()), " ")
+
+	buf.Reset()
+	textTmpl, err := texttemplate.New(name + ".txt").Parse(textSource)
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := textTmpl.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	text = buf.String()
+
+	buf.Reset()
+	htmlTmpl, err := htmltemplate.New(name + ".html").Parse(htmlSource)
+	if err != nil {
+		return "", "", "", err
+	}
+	if err := htmlTmpl.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	html = buf.String()
+
+	return subject, text, html, nil
+}
@@ -1,21 +1,33 @@
 package main
 
 import (
-	//"auth_service/configs"
+	"auth_service/configs"
+	"auth_service/crypto"
 
 	"auth_service/db"
 	"auth_service/handlers"
+	"auth_service/idp"
+	"auth_service/ldap"
 	"auth_service/logger"
 
-	//	"auth_service/mail"
+	"auth_service/mail"
 	"auth_service/middlewares"
+	"auth_service/migrations"
+	"auth_service/mqtt"
+	"auth_service/oidc"
+	"auth_service/phone"
+	"auth_service/policy"
 	"auth_service/rds"
+	"auth_service/webauthn"
 
 	//"fmt"
+	"context"
 
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // func generateReferenceID(timer int64) string {
@@ -124,6 +136,35 @@ func main() {
 		logger.Info("MAIN", "Database Connection Pool Initated.")
 	}
 
+	///////////////////////////////// MIGRATION ///////////////////////////////
+	// "auth_service migrate <up|down|status>" hanya menjalankan migrasi lalu keluar
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		conn, err := db.GetConnection()
+		if err != nil {
+			logger.Error("MAIN", "ERROR - Failed to get DB connection for migration:", err)
+			os.Exit(1)
+		}
+		os.Exit(runMigrate(conn, os.Args[2:]))
+	}
+
+	// MIGRATEONSTART=true menjalankan migrasi saat start, aman untuk beberapa replika (advisory lock)
+	MIGRATEONSTART := os.Getenv("MIGRATEONSTART")
+	logger.Info("MAIN", "MIGRATEONSTART : ", MIGRATEONSTART)
+
+	if conn, err := db.GetConnection(); err != nil {
+		logger.Error("MAIN", "ERROR - Failed to get DB connection for migration:", err)
+		os.Exit(1)
+	} else if MIGRATEONSTART == "true" {
+		if _, err := migrations.Up(conn); err != nil {
+			logger.Error("MAIN", "ERROR - Schema migration failed:", err)
+			os.Exit(1)
+		}
+	} else if pending, err := migrations.Pending(conn); err != nil {
+		logger.Warning("MAIN", "Failed to check schema migrations: ", err)
+	} else if pending > 0 {
+		logger.Warning("MAIN", pending, " schema migration(s) pending, run \"auth_service migrate up\" or set MIGRATEONSTART=true")
+	}
+
 	///////////////////////////////// REDIS ///////////////////////////////
 	// Inisialisasi Redis hanya di main
 
@@ -135,61 +176,273 @@ func main() {
 	///////////////////////////////// SMTP ///////////////////////////////
 	logger.Info("MAIN", "-----------SMTP CONF : ")
 
+	MAILBACKEND := os.Getenv("MAILBACKEND")
+	if len(MAILBACKEND) == 0 {
+		MAILBACKEND = mail.BackendSMTP
+	}
+	MAILDIR := os.Getenv("MAILDIR")
+
 	SMTPSERVER := os.Getenv("SMTPSERVER")
 	SMTPPORT := os.Getenv("SMTPPORT")
 	SMTPUSER := os.Getenv("SMTPUSER")
 	SMTPPASS := os.Getenv("SMTPPASS")
 	SMTPFROM := os.Getenv("SMTPFROM")
+	SMTPTLS := os.Getenv("SMTPTLS")
 
+	logger.Info("MAIN", "MAILBACKEND : ", MAILBACKEND)
+	logger.Info("MAIN", "MAILDIR : ", MAILDIR)
 	logger.Info("MAIN", "SMTPSERVER : ", SMTPSERVER)
 	logger.Info("MAIN", "SMTPPORT : ", SMTPPORT)
 	logger.Info("MAIN", "SMTPUSER : ", SMTPUSER)
-	logger.Info("MAIN", "SMTPPASS : ", SMTPPASS)
+	logger.Debug("MAIN", "SMTPPASS : ", SMTPPASS)
 	logger.Info("MAIN", "SMTPFROM : ", SMTPFROM)
+	logger.Info("MAIN", "SMTPTLS : ", SMTPTLS)
 
-	if len(SMTPSERVER) == 0 {
-		logger.Error("SMTPSERVER environment variable is required")
-	}
+	if MAILBACKEND == mail.BackendSMTP {
+		if len(SMTPSERVER) == 0 {
+			logger.Error("SMTPSERVER environment variable is required")
+		}
 
-	if len(SMTPPORT) == 0 {
-		logger.Error("SMTPPORT environment variable is required")
-	}
+		if len(SMTPPORT) == 0 {
+			logger.Error("SMTPPORT environment variable is required")
+		}
 
-	if len(SMTPUSER) == 0 {
-		logger.Error("SMPTPUSER environment variable is required")
-	}
+		if len(SMTPUSER) == 0 {
+			logger.Error("SMPTPUSER environment variable is required")
+		}
 
-	if len(SMTPPASS) == 0 {
-		logger.Error("SMPTPPASS environment variable is required")
+		if len(SMTPPASS) == 0 {
+			logger.Error("SMPTPPASS environment variable is required")
+		}
 	}
 
 	if len(SMTPFROM) == 0 {
 		logger.Error("SMTPFROM environment variable is required")
 	}
 
-	// Ambil email dari environment
-	testEmail := SMTPUSER
-
-	if testEmail == "" {
-		logger.Error("MAIN", "SMTPUSER is not set in environment variables")
+	err = mail.InitSender(mail.Config{
+		Backend:    MAILBACKEND,
+		SMTPServer: SMTPSERVER,
+		SMTPPort:   SMTPPORT,
+		SMTPUser:   SMTPUSER,
+		SMTPPass:   SMTPPASS,
+		SMTPTLS:    SMTPTLS,
+		From:       SMTPFROM,
+		Dir:        MAILDIR,
+	})
+	if err != nil {
+		logger.Error("MAIN", "ERROR - Failed to initialize mail sender:", err)
 		os.Exit(1)
 	}
 
 	// Uji kirim email ke diri sendiri
 
 	// testMessage := fmt.Sprintf("This is a test SMTP email \n service: %s  \n version: %s", configs.GetAppName(), configs.GetVersion())
-	// err = mail.SendEmail(testEmail, "Test Email", testMessage)
+	// err = mail.SendEmail(SMTPUSER, "Test Email", testMessage)
 	// if err != nil {
 	// 	logger.Error("MAIN", "ERROR - Failed to send test email:", err)
 	// 	os.Exit(1)
 	// }
 
-	// if err != nil {
+	if err := mail.StartQueue(context.Background(), configs.GetMailQueueWorkers()); err != nil {
+		logger.Error("MAIN", "ERROR - Failed to start mail queue:", err)
+		os.Exit(1)
+	}
 
-	// 	logger.Error("MAIN", "ERROR - Failed to send email:", err)
-	// 	os.Exit(1)
+	MAILTEMPLATEDIR := os.Getenv("MAILTEMPLATEDIR")
+	logger.Info("MAIN", "MAILTEMPLATEDIR : ", MAILTEMPLATEDIR)
 
-	// }
+	if err := mail.InitTemplates(MAILTEMPLATEDIR); err != nil {
+		logger.Error("MAIN", "ERROR - Failed to load mail templates:", err)
+		os.Exit(1)
+	}
+
+	///////////////////////////////// PHONE GATEWAY ///////////////////////////////
+	logger.Info("MAIN", "-----------PHONE GATEWAY CONF : ")
+
+	PHONEGWURL := os.Getenv("PHONEGWURL")
+	PHONEGWMETHOD := os.Getenv("PHONEGWMETHOD")
+	PHONEGWBODY := os.Getenv("PHONEGWBODY")
+	PHONEGWCONTENTTYPE := os.Getenv("PHONEGWCONTENTTYPE")
+	PHONEGWAUTH := os.Getenv("PHONEGWAUTH")
+
+	logger.Info("MAIN", "PHONEGWURL : ", PHONEGWURL)
+	logger.Info("MAIN", "PHONEGWMETHOD : ", PHONEGWMETHOD)
+	logger.Info("MAIN", "PHONEGWBODY : ", PHONEGWBODY)
+	logger.Info("MAIN", "PHONEGWCONTENTTYPE : ", PHONEGWCONTENTTYPE)
+	logger.Debug("MAIN", "PHONEGWAUTH : ", PHONEGWAUTH)
+
+	if len(PHONEGWURL) == 0 {
+		logger.Warning("MAIN", "PHONEGWURL is not set, SMS / WhatsApp OTP delivery disabled")
+	} else {
+		gateway, err := phone.NewHTTPGateway(phone.GatewayConfig{
+			URL:           PHONEGWURL,
+			Method:        PHONEGWMETHOD,
+			Body:          PHONEGWBODY,
+			ContentType:   PHONEGWCONTENTTYPE,
+			Authorization: PHONEGWAUTH,
+		})
+		if err != nil {
+			logger.Error("MAIN", "ERROR - Failed to initialize phone gateway:", err)
+			os.Exit(1)
+		}
+		phone.SetSender(gateway)
+
+		if err := phone.StartQueue(context.Background(), configs.GetPhoneQueueWorkers()); err != nil {
+			logger.Error("MAIN", "ERROR - Failed to start phone queue:", err)
+			os.Exit(1)
+		}
+	}
+
+	///////////////////////////////// SECRET KEY ///////////////////////////////
+	// kunci AES-256 (hex) untuk secret yang disimpan terenkripsi, misal secret TOTP
+	SECRETKEY := os.Getenv("SECRETKEY")
+
+	if len(SECRETKEY) == 0 {
+		logger.Warning("MAIN", "SECRETKEY is not set, TOTP two-factor authentication is unavailable")
+	} else if err := crypto.InitSecretKey(SECRETKEY); err != nil {
+		logger.Error("MAIN", "ERROR - Invalid SECRETKEY:", err)
+		os.Exit(1)
+	}
+
+	///////////////////////////////// WEBAUTHN ///////////////////////////////
+	logger.Info("MAIN", "-----------WEBAUTHN CONF : ")
+
+	// default mengikuti clientURL, RP ID harus domain (tanpa port) dari origin frontend
+	WEBAUTHNRPID := os.Getenv("WEBAUTHNRPID")
+	WEBAUTHNRPNAME := os.Getenv("WEBAUTHNRPNAME")
+	WEBAUTHNORIGINS := os.Getenv("WEBAUTHNORIGINS")
+	WEBAUTHNREQUIREUV := os.Getenv("WEBAUTHNREQUIREUV")
+
+	if len(WEBAUTHNORIGINS) == 0 {
+		WEBAUTHNORIGINS = configs.GetClientURL()
+	}
+	if len(WEBAUTHNRPID) == 0 {
+		if clientURL, err := url.Parse(configs.GetClientURL()); err == nil {
+			WEBAUTHNRPID = clientURL.Hostname()
+		}
+	}
+	if len(WEBAUTHNRPNAME) == 0 {
+		WEBAUTHNRPNAME = configs.GetTOTPIssuer()
+	}
+
+	logger.Info("MAIN", "WEBAUTHNRPID : ", WEBAUTHNRPID)
+	logger.Info("MAIN", "WEBAUTHNRPNAME : ", WEBAUTHNRPNAME)
+	logger.Info("MAIN", "WEBAUTHNORIGINS : ", WEBAUTHNORIGINS)
+	logger.Info("MAIN", "WEBAUTHNREQUIREUV : ", WEBAUTHNREQUIREUV)
+
+	var webauthnOrigins []string
+	for _, origin := range strings.Split(WEBAUTHNORIGINS, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			webauthnOrigins = append(webauthnOrigins, strings.TrimRight(origin, "/"))
+		}
+	}
+
+	err = webauthn.Init(webauthn.Config{
+		RPID:                    WEBAUTHNRPID,
+		RPName:                  WEBAUTHNRPNAME,
+		Origins:                 webauthnOrigins,
+		RequireUserVerification: WEBAUTHNREQUIREUV == "true",
+	})
+	if err != nil {
+		logger.Error("MAIN", "ERROR - Failed to initialize WebAuthn:", err)
+		os.Exit(1)
+	}
+
+	///////////////////////////////// OPENID CONNECT ///////////////////////////////
+	logger.Info("MAIN", "-----------OIDC CONF : ")
+
+	// issuer harus URL publik service ini, dipakai di claim iss dan discovery
+	OIDCISSUER := os.Getenv("OIDCISSUER")
+	OIDCSIGNINGKEY := os.Getenv("OIDCSIGNINGKEY")
+
+	if len(OIDCISSUER) == 0 {
+		OIDCISSUER = "http://localhost:5000"
+	}
+
+	logger.Info("MAIN", "OIDCISSUER : ", OIDCISSUER)
+	logger.Info("MAIN", "OIDCSIGNINGKEY : ", OIDCSIGNINGKEY)
+
+	ephemeralKey, err := oidc.Init(OIDCISSUER, OIDCSIGNINGKEY)
+	if err != nil {
+		logger.Error("MAIN", "ERROR - Failed to initialize OIDC signing key:", err)
+		os.Exit(1)
+	}
+	if ephemeralKey {
+		logger.Warning("MAIN", "OIDCSIGNINGKEY is not set, using ephemeral signing key (ID tokens are invalidated on restart)")
+	}
+
+	///////////////////////////////// IDENTITY PROVIDER ///////////////////////////////
+	logger.Info("MAIN", "-----------IDP CONF : ")
+
+	// file JSON daftar provider (lihat package idp), callback yang didaftarkan di provider: <OIDCISSUER>/idp/callback
+	IDPCONFIG := os.Getenv("IDPCONFIG")
+	logger.Info("MAIN", "IDPCONFIG : ", IDPCONFIG)
+
+	if len(IDPCONFIG) == 0 {
+		logger.Warning("MAIN", "IDPCONFIG is not set, login with external identity providers disabled")
+	}
+
+	if err := idp.Init(IDPCONFIG); err != nil {
+		logger.Error("MAIN", "ERROR - Failed to load identity provider config:", err)
+		os.Exit(1)
+	}
+
+	///////////////////////////////// LDAP ///////////////////////////////
+	logger.Info("MAIN", "-----------LDAP CONF : ")
+
+	// file JSON konfigurasi directory (lihat package ldap)
+	LDAPCONFIG := os.Getenv("LDAPCONFIG")
+	logger.Info("MAIN", "LDAPCONFIG : ", LDAPCONFIG)
+
+	if len(LDAPCONFIG) == 0 {
+		logger.Warning("MAIN", "LDAPCONFIG is not set, LDAP / Active Directory login disabled")
+	}
+
+	if err := ldap.Init(LDAPCONFIG); err != nil {
+		logger.Error("MAIN", "ERROR - Failed to load LDAP config:", err)
+		os.Exit(1)
+	}
+
+	///////////////////////////////// MQTT ///////////////////////////////
+	logger.Info("MAIN", "-----------MQTT HOOK CONF : ")
+
+	// secret yang dikirim broker di header X-Hook-Secret, lihat package mqtt
+	MQTTHOOKSECRET := os.Getenv("MQTTHOOKSECRET")
+	MQTTTOPICPREFIX := os.Getenv("MQTTTOPICPREFIX")
+	MQTTRESPONSEMODE := os.Getenv("MQTTRESPONSEMODE")
+
+	logger.Info("MAIN", "MQTTTOPICPREFIX : ", MQTTTOPICPREFIX)
+	logger.Info("MAIN", "MQTTRESPONSEMODE : ", MQTTRESPONSEMODE)
+
+	if len(MQTTHOOKSECRET) == 0 {
+		logger.Warning("MAIN", "MQTTHOOKSECRET is not set, /mqtt/auth/* hooks are disabled")
+	}
+
+	err = mqtt.Init(mqtt.Config{
+		HookSecret:   MQTTHOOKSECRET,
+		TopicPrefix:  MQTTTOPICPREFIX,
+		ResponseMode: MQTTRESPONSEMODE,
+	})
+	if err != nil {
+		logger.Error("MAIN", "ERROR - Invalid MQTT hook config:", err)
+		os.Exit(1)
+	}
+
+	///////////////////////////////// PASSWORD POLICY ///////////////////////////////
+	logger.Info("MAIN", "-----------PASSWORD POLICY CONF : ")
+
+	PWBREACHDIR := os.Getenv("PWBREACHDIR")
+	logger.Info("MAIN", "PWBREACHDIR : ", PWBREACHDIR)
+
+	if len(PWBREACHDIR) == 0 {
+		logger.Warning("MAIN", "PWBREACHDIR is not set, breached password check disabled")
+	}
+
+	if err := policy.InitBreachedLookup(PWBREACHDIR); err != nil {
+		logger.Error("MAIN", "ERROR - Failed to initialize breached password lookup:", err)
+		os.Exit(1)
+	}
 
 	paths["/"] = handlers.Greeting
 	// send requestID and db conn as parameter
@@ -200,6 +453,78 @@ func main() {
 	paths["/register/verify-otp"] = handlers.Register_Verify_OTP
 	paths["/reset-password"] = handlers.Reset_Password
 	paths["/reset-password/verify-url"] = handlers.Reset_Password_Verify_URL
+	paths["/reset-password/validate"] = handlers.Reset_Password_Validate
+	paths["/account/change-password"] = middlewares.AuthMiddleware(handlers.Change_Password)
+	paths["/account/phone/verify/request"] = middlewares.AuthMiddleware(handlers.Phone_Verify_Request)
+	paths["/account/phone/verify/confirm"] = middlewares.AuthMiddleware(handlers.Phone_Verify_Confirm)
+	paths["/verify-token/totp"] = handlers.Verify_TOTP
+	paths["/account/2fa/totp/enroll"] = middlewares.AuthMiddleware(handlers.TOTP_Enroll)
+	paths["/account/2fa/totp/confirm"] = middlewares.AuthMiddleware(handlers.TOTP_Confirm)
+	paths["/account/2fa/totp/disable"] = middlewares.AuthMiddleware(handlers.TOTP_Disable)
+	paths["/account/2fa/recovery-codes"] = middlewares.AllowAPIKey("account:read", middlewares.AuthMiddleware(handlers.Recovery_Codes_Status))
+	paths["/account/2fa/recovery-codes/regenerate"] = middlewares.AuthMiddleware(handlers.Recovery_Codes_Regenerate)
+	paths["/account/webauthn/register/options"] = middlewares.AuthMiddleware(handlers.WebAuthn_Register_Options)
+	paths["/account/webauthn/register/verify"] = middlewares.AuthMiddleware(handlers.WebAuthn_Register_Verify)
+	paths["/account/webauthn/credentials"] = middlewares.AllowAPIKey("account:read", middlewares.AuthMiddleware(handlers.WebAuthn_Credentials))
+	paths["/account/webauthn/credentials/delete"] = middlewares.AuthMiddleware(handlers.WebAuthn_Credential_Delete)
+	paths["/webauthn/login/options"] = handlers.WebAuthn_Login_Options
+	paths["/webauthn/login/verify"] = handlers.WebAuthn_Login_Verify
+	paths["/magic-link"] = handlers.Magic_Link_Request
+	paths["/magic-link/consume"] = handlers.Magic_Link_Consume
+	paths["/oauth/authorize"] = handlers.OAuth_Authorize
+	paths["/oauth/authorize/details"] = middlewares.AuthMiddleware(handlers.OAuth_Authorize_Details)
+	paths["/oauth/authorize/decision"] = middlewares.AuthMiddleware(handlers.OAuth_Authorize_Decision)
+	paths["/oauth/token"] = handlers.OAuth_Token
+	paths["/oauth/revoke"] = handlers.OAuth_Revoke
+	paths["/oauth/introspect"] = handlers.OAuth_Introspect
+	paths["/admin/oauth/clients"] = middlewares.AllowAPIKey("admin", middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.OAuth_Client_List)))
+	paths["/admin/oauth/clients/create"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.OAuth_Client_Create))
+	paths["/admin/oauth/clients/delete"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.OAuth_Client_Delete))
+	paths["/admin/oauth/clients/rotate-secret"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.OAuth_Client_Rotate_Secret))
+	paths["/admin/mail/status"] = middlewares.AllowAPIKey("admin", middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Mail_Status)))
+	paths["/admin/mail/dead"] = middlewares.AllowAPIKey("admin", middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Mail_Dead_List)))
+	paths["/admin/mail/dead/requeue"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Mail_Dead_Requeue))
+	paths["/.well-known/openid-configuration"] = handlers.OIDC_Discovery
+	paths["/.well-known/jwks.json"] = handlers.OIDC_JWKS
+	paths["/userinfo"] = handlers.OIDC_UserInfo
+	paths["/oauth/logout"] = handlers.OIDC_Logout
+	paths["/oauth/logout/decision"] = middlewares.AuthMiddleware(handlers.OIDC_Logout_Decision)
+	paths["/idp/providers"] = handlers.IdP_Providers
+	paths["/idp/login"] = handlers.IdP_Login
+	paths["/idp/callback"] = handlers.IdP_Callback
+	paths["/idp/exchange"] = handlers.IdP_Exchange
+	paths["/account/identities"] = middlewares.AllowAPIKey("account:read", middlewares.AuthMiddleware(handlers.IdP_Identities))
+	paths["/account/identities/link"] = middlewares.AuthMiddleware(handlers.IdP_Link)
+	paths["/account/identities/unlink"] = middlewares.AuthMiddleware(handlers.IdP_Unlink)
+	paths["/account/api-keys"] = middlewares.AuthMiddleware(handlers.API_Key_List)
+	paths["/account/api-keys/create"] = middlewares.AuthMiddleware(handlers.API_Key_Create)
+	paths["/account/api-keys/rename"] = middlewares.AuthMiddleware(handlers.API_Key_Rename)
+	paths["/account/api-keys/revoke"] = middlewares.AuthMiddleware(handlers.API_Key_Revoke)
+	paths["/device/login"] = handlers.Device_Login
+	paths["/device/verify-token"] = handlers.Device_Verify_Token
+	paths["/device/logout"] = middlewares.DeviceAuthMiddleware(handlers.Device_Logout)
+	paths["/device/data"] = middlewares.DeviceAuthMiddleware(handlers.Device_Data_Ingest)
+	paths["/admin/devices"] = middlewares.AllowAPIKey("admin", middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Device_List)))
+	paths["/admin/devices/provision"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Device_Provision))
+	paths["/admin/devices/rotate-secret"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Device_Rotate_Secret))
+	paths["/admin/devices/status"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Device_Set_Status))
+	paths["/admin/devices/grant"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Device_Grant))
+	paths["/admin/devices/grant/revoke"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Device_Grant_Revoke))
+	paths["/device/data/query"] = middlewares.AllowAPIKey("device:read", middlewares.AuthMiddleware(handlers.Device_Data_Query))
+	paths["/admin/devices/pairing-code"] = middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.Device_Pairing_Code))
+	paths["/device/claim"] = middlewares.AuthMiddleware(handlers.Device_Claim)
+	paths["/device/units"] = middlewares.AllowAPIKey("device:read", middlewares.AuthMiddleware(handlers.Device_Unit_List))
+	paths["/device/share"] = middlewares.AuthMiddleware(handlers.Device_Share)
+	paths["/device/shares"] = middlewares.AuthMiddleware(handlers.Device_Share_List)
+	paths["/device/share/revoke"] = middlewares.AuthMiddleware(handlers.Device_Share_Revoke)
+	paths["/service/users"] = middlewares.RequireScope("users.read", handlers.Service_User_Lookup)
+	paths["/service/mail/status"] = middlewares.RequireScope("mail.read", handlers.Mail_Status)
+	// hook broker hanya dipasang bila MQTTHOOKSECRET diisi
+	if mqtt.Enabled() {
+		paths["/mqtt/auth/user"] = handlers.MQTT_Auth_User
+		paths["/mqtt/auth/superuser"] = handlers.MQTT_Auth_Superuser
+		paths["/mqtt/auth/acl"] = handlers.MQTT_Auth_ACL
+	}
 
 	// Register endpoints with a multiplexer
 	mux := http.NewServeMux()
@@ -0,0 +1,196 @@
+package middlewares
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/handlers"
+	"auth_service/logger"
+	"auth_service/utils"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AllowAPIKey mengizinkan endpoint diakses dengan personal API key yang memiliki scope tertentu.
+// Dipasang di luar AuthMiddleware, endpoint tanpa AllowAPIKey menolak API key:
+//
+//	middlewares.AllowAPIKey("account:read", middlewares.AuthMiddleware(handlers.X))
+func AllowAPIKey(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), handlers.HTTPContextKey("apiKeyScope"), scope)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// AuthMiddleware memastikan request membawa sesi yang valid lewat header
+// X-Session-Id dan X-Session-Hash (nilai dari payload Verify_Token),
+// atau personal API key lewat header "Authorization: Bearer ak_..." (lihat AllowAPIKey).
+// user_id dan session_id sesi disimpan di context untuk dipakai handler,
+// request dengan API key mendapat session_id kosong serta apiKeyID dan scope.
+func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		referenceID, ok := r.Context().Value(handlers.HTTPContextKey("requestID")).(string)
+		if !ok {
+			referenceID = "unknown"
+		}
+
+		result := utils.ResultFormat{
+			ErrorCode:    "000000",
+			ErrorMessage: "",
+			Payload:      make(map[string]any),
+		}
+
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			key, found := strings.CutPrefix(authorization, "Bearer ")
+			if _, ok := handlers.ParseAPIKey(key); found && ok {
+				apiKeyAuth(w, r, referenceID, key, next)
+				return
+			}
+		}
+
+		sessionID := r.Header.Get("X-Session-Id")
+		sessionHash := r.Header.Get("X-Session-Hash")
+		if sessionID == "" || sessionHash == "" {
+			logger.Error(referenceID, "ERROR - AuthMiddleware - Missing session headers")
+			result.ErrorCode = "401100"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		conn, err := db.GetConnection()
+		if err != nil {
+			logger.Error(referenceID, "ERROR - AuthMiddleware - DB connection failed: ", err)
+			result.ErrorCode = "500100"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		var userID int64
+		var storedHash string
+		var sessionTstamp int64
+		querySession := `SELECT user_id, session_hash, tstamp FROM sysuser.session WHERE session_id = $1 AND st = 1`
+		if err := conn.QueryRow(querySession, sessionID).Scan(&userID, &storedHash, &sessionTstamp); err != nil {
+			logger.Error(referenceID, "ERROR - AuthMiddleware - Session not found: ", err)
+			result.ErrorCode = "401101"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(storedHash), []byte(sessionHash)) != 1 {
+			logger.Error(referenceID, "ERROR - AuthMiddleware - Session hash mismatch")
+			result.ErrorCode = "401102"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		// tstamp adalah waktu login, sesi harus login ulang setelah umur maksimum walaupun terus dipakai
+		if time.Now().Unix()-sessionTstamp > int64(configs.GetSessionMaxAge()) {
+			logger.Error(referenceID, "ERROR - AuthMiddleware - Session expired: ", sessionID)
+			if _, err := conn.Exec(`DELETE FROM sysuser.session WHERE session_id = $1`, sessionID); err != nil {
+				logger.Warning(referenceID, "WARNING - AuthMiddleware - Failed to delete expired session: ", err)
+			}
+			result.ErrorCode = "401107"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.HTTPContextKey("userID"), userID)
+		ctx = context.WithValue(ctx, handlers.HTTPContextKey("sessionID"), sessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// apiKeyAuth memvalidasi personal API key, mencatat waktu / IP pemakaian terakhir lalu meneruskan request
+func apiKeyAuth(w http.ResponseWriter, r *http.Request, referenceID, key string, next http.HandlerFunc) {
+	result := utils.ResultFormat{
+		ErrorCode:    "000000",
+		ErrorMessage: "",
+		Payload:      make(map[string]any),
+	}
+
+	requiredScope, _ := r.Context().Value(handlers.HTTPContextKey("apiKeyScope")).(string)
+	if requiredScope == "" {
+		logger.Error(referenceID, "ERROR - AuthMiddleware - API key is not accepted on this endpoint")
+		result.ErrorCode = "403101"
+		result.ErrorMessage = "Forbidden"
+		utils.Response(w, result)
+		return
+	}
+
+	prefix, _ := handlers.ParseAPIKey(key)
+
+	conn, err := db.GetConnection()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - AuthMiddleware - DB connection failed: ", err)
+		result.ErrorCode = "500100"
+		result.ErrorMessage = "Internal server error"
+		utils.Response(w, result)
+		return
+	}
+
+	now := time.Now().Unix()
+	var stored struct {
+		ID      int64  `db:"id"`
+		UserID  int64  `db:"user_id"`
+		KeyHash string `db:"key_hash"`
+		Scopes  string `db:"scopes"`
+	}
+	queryKey := `
+		SELECT k.id, k.user_id, k.key_hash, k.scopes
+		FROM sysuser.api_key k
+		JOIN sysuser."user" u ON u.id = k.user_id AND u.st = 1
+		WHERE k.prefix = $1 AND k.revoked_tstamp IS NULL AND (k.expire_tstamp IS NULL OR k.expire_tstamp > $2)`
+	if err := conn.Get(&stored, queryKey, prefix, now); err != nil {
+		logger.Error(referenceID, "ERROR - AuthMiddleware - API key not found or inactive: ", err)
+		result.ErrorCode = "401105"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored.KeyHash), []byte(crypto.HashSHA256(key))) != 1 {
+		logger.Error(referenceID, "ERROR - AuthMiddleware - API key hash mismatch: ", prefix)
+		result.ErrorCode = "401106"
+		result.ErrorMessage = "Unauthorized"
+		utils.Response(w, result)
+		return
+	}
+
+	hasScope := false
+	for _, scope := range strings.Fields(stored.Scopes) {
+		if scope == requiredScope {
+			hasScope = true
+			break
+		}
+	}
+	if !hasScope {
+		logger.Error(referenceID, "ERROR - AuthMiddleware - API key lacks scope: ", requiredScope)
+		result.ErrorCode = "403102"
+		result.ErrorMessage = "Forbidden"
+		utils.Response(w, result)
+		return
+	}
+
+	// ditulis paling sering sekali per interval supaya script yang sering memanggil API tidak membebani DB
+	queryLastUsed := `
+		UPDATE sysuser.api_key SET last_used_tstamp = $1, last_used_ip = $2
+		WHERE id = $3 AND (last_used_tstamp IS NULL OR last_used_tstamp <= $4)`
+	clientIP := utils.ClientIP(r, configs.GetTrustProxyHeaders())
+	if _, err := conn.Exec(queryLastUsed, now, clientIP, stored.ID, now-int64(configs.GetAPIKeyLastUsedInterval())); err != nil {
+		logger.Warning(referenceID, "WARNING - AuthMiddleware - Failed to record API key usage: ", err)
+	}
+
+	ctx := context.WithValue(r.Context(), handlers.HTTPContextKey("userID"), stored.UserID)
+	ctx = context.WithValue(ctx, handlers.HTTPContextKey("sessionID"), "")
+	ctx = context.WithValue(ctx, handlers.HTTPContextKey("apiKeyID"), stored.ID)
+	ctx = context.WithValue(ctx, handlers.HTTPContextKey("scope"), stored.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
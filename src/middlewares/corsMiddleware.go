@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"auth_service/configs"
 	"auth_service/handlers"
 	"auth_service/logger"
 	"auth_service/utils"
@@ -22,12 +23,18 @@ func generateReferenceID(timer int64) string {
 
 func CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow all origins
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		// Allow all origins, frontend utama boleh mengirim cookie (binding browser magic link)
+		if origin := r.Header.Get("Origin"); origin != "" && origin == configs.GetClientURL() {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Add("Vary", "Origin")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		}
 		// Allow only GET and POST methods
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		// Allow only JSON content
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		// Allow JSON content and session headers
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-Id, X-Session-Hash")
 		if r.Method == http.MethodOptions {
 			// If preflight request, return 204 No Content
 			w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"auth_service/configs"
+	"auth_service/db"
+	"auth_service/handlers"
+	"auth_service/logger"
+	"auth_service/utils"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"time"
+)
+
+// DeviceAuthMiddleware memastikan request membawa sesi device yang valid lewat header
+// X-Session-Id dan X-Session-Hash (nilai dari payload Device_Verify_Token).
+// Unit yang dinonaktifkan atau sesi yang kedaluwarsa langsung ditolak.
+// unit_id dan session_id disimpan di context (unitID, deviceSessionID).
+func DeviceAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		referenceID, ok := r.Context().Value(handlers.HTTPContextKey("requestID")).(string)
+		if !ok {
+			referenceID = "unknown"
+		}
+
+		result := utils.ResultFormat{
+			ErrorCode:    "000000",
+			ErrorMessage: "",
+			Payload:      make(map[string]any),
+		}
+
+		sessionID := r.Header.Get("X-Session-Id")
+		sessionHash := r.Header.Get("X-Session-Hash")
+		if sessionID == "" || sessionHash == "" {
+			logger.Error(referenceID, "ERROR - DeviceAuthMiddleware - Missing session headers")
+			result.ErrorCode = "401120"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		conn, err := db.GetConnection()
+		if err != nil {
+			logger.Error(referenceID, "ERROR - DeviceAuthMiddleware - DB connection failed: ", err)
+			result.ErrorCode = "500120"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		var unitID int64
+		var storedHash string
+		querySession := `
+			SELECT s.unit_id, s.session_hash
+			FROM device.session s
+			JOIN device.unit u ON u.id = s.unit_id AND u.status = 1
+			WHERE s.session_id = $1 AND s.st = 1 AND s.tstamp > $2`
+		minTstamp := time.Now().Unix() - int64(configs.GetDeviceSessionExpireTime())
+		if err := conn.QueryRow(querySession, sessionID, minTstamp).Scan(&unitID, &storedHash); err != nil {
+			logger.Error(referenceID, "ERROR - DeviceAuthMiddleware - Session not found: ", err)
+			result.ErrorCode = "401121"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(storedHash), []byte(sessionHash)) != 1 {
+			logger.Error(referenceID, "ERROR - DeviceAuthMiddleware - Session hash mismatch")
+			result.ErrorCode = "401122"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.HTTPContextKey("unitID"), unitID)
+		ctx = context.WithValue(ctx, handlers.HTTPContextKey("deviceSessionID"), sessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"auth_service/db"
+	"auth_service/handlers"
+	"auth_service/logger"
+	"auth_service/utils"
+	"net/http"
+)
+
+// RequireRole membatasi endpoint untuk role tertentu (misal "admin").
+// Dipasang di dalam AuthMiddleware karena membaca userID dari context:
+//
+//	middlewares.AuthMiddleware(middlewares.RequireRole("admin", handlers.X))
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		referenceID, ok := r.Context().Value(handlers.HTTPContextKey("requestID")).(string)
+		if !ok {
+			referenceID = "unknown"
+		}
+
+		result := utils.ResultFormat{
+			ErrorCode:    "000000",
+			ErrorMessage: "",
+			Payload:      make(map[string]any),
+		}
+
+		userID, _ := r.Context().Value(handlers.HTTPContextKey("userID")).(int64)
+		if userID == 0 {
+			logger.Error(referenceID, "ERROR - RequireRole - Missing session context")
+			result.ErrorCode = "401103"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		conn, err := db.GetConnection()
+		if err != nil {
+			logger.Error(referenceID, "ERROR - RequireRole - DB connection failed: ", err)
+			result.ErrorCode = "500101"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		var userRole string
+		if err := conn.Get(&userRole, `SELECT role FROM sysuser."user" WHERE id = $1`, userID); err != nil {
+			logger.Error(referenceID, "ERROR - RequireRole - User not found: ", err)
+			result.ErrorCode = "401104"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		if userRole != role {
+			logger.Error(referenceID, "ERROR - RequireRole - Role ", userRole, " is not allowed, required: ", role)
+			result.ErrorCode = "403100"
+			result.ErrorMessage = "Forbidden"
+			utils.Response(w, result)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,115 @@
+package middlewares
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"auth_service/db"
+	"auth_service/handlers"
+	"auth_service/logger"
+	"auth_service/rds"
+	"auth_service/utils"
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequireScope melindungi endpoint service-to-service dengan access token OAuth
+// (header Authorization: Bearer), token harus aktif dan memiliki scope yang diminta.
+// client_id dan scope token disimpan di context, request per client dibatasi rate limit.
+//
+//	middlewares.RequireScope("users.read", handlers.X)
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		referenceID, ok := r.Context().Value(handlers.HTTPContextKey("requestID")).(string)
+		if !ok {
+			referenceID = "unknown"
+		}
+
+		result := utils.ResultFormat{
+			ErrorCode:    "000000",
+			ErrorMessage: "",
+			Payload:      make(map[string]any),
+		}
+
+		authorization := r.Header.Get("Authorization")
+		if len(authorization) <= 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+			logger.Error(referenceID, "ERROR - RequireScope - Missing bearer token")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			result.ErrorCode = "401110"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+		accessToken := strings.TrimSpace(authorization[7:])
+
+		conn, err := db.GetConnection()
+		if err != nil {
+			logger.Error(referenceID, "ERROR - RequireScope - DB connection failed: ", err)
+			result.ErrorCode = "500110"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		var token struct {
+			ClientID  string        `db:"client_id"`
+			Scope     string        `db:"scope"`
+			RateLimit sql.NullInt64 `db:"rate_limit"`
+		}
+		queryToken := `
+			SELECT t.client_id, t.scope, c.rate_limit
+			FROM sysuser.oauth_token t
+			JOIN sysuser.oauth_client c ON c.client_id = t.client_id AND c.st = 1
+			WHERE t.token_hash = $1 AND t.token_type = 'access_token' AND NOT t.revoked AND t.expire_tstamp > $2`
+		if err := conn.Get(&token, queryToken, crypto.HashSHA256(accessToken), time.Now().Unix()); err != nil {
+			logger.Error(referenceID, "ERROR - RequireScope - Invalid access token: ", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			result.ErrorCode = "401111"
+			result.ErrorMessage = "Unauthorized"
+			utils.Response(w, result)
+			return
+		}
+
+		granted := false
+		for _, s := range strings.Fields(token.Scope) {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			logger.Error(referenceID, "ERROR - RequireScope - Scope ", scope, " not granted to client: ", token.ClientID)
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+			result.ErrorCode = "403110"
+			result.ErrorMessage = "Forbidden"
+			utils.Response(w, result)
+			return
+		}
+
+		limit := configs.GetOAuthClientRateLimit()
+		if token.RateLimit.Valid && token.RateLimit.Int64 > 0 {
+			limit = token.RateLimit.Int64
+		}
+		window := time.Duration(configs.GetOAuthClientRateWindow()) * time.Second
+		if ttl, err := utils.ClientRateLimiter(rds.GetRedisClient(), referenceID, token.ClientID, limit, window); err != nil {
+			if ttl > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(ttl.Seconds())+1))
+				result.ErrorCode = "429110"
+				result.ErrorMessage = "Too many requests"
+				utils.Response(w, result)
+				return
+			}
+			result.ErrorCode = "500111"
+			result.ErrorMessage = "Internal server error"
+			utils.Response(w, result)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), handlers.HTTPContextKey("clientID"), token.ClientID)
+		ctx = context.WithValue(ctx, handlers.HTTPContextKey("scope"), token.Scope)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"auth_service/migrations"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const migrateUsage = `usage: auth_service migrate <command>
+
+commands:
+  up          jalankan semua migrasi yang belum dijalankan
+  down [n]    batalkan n migrasi terakhir (default 1), berhenti di migrasi adopsi 0001 / 0002
+  status      tampilkan status setiap migrasi`
+
+// runMigrate menjalankan subcommand "migrate" lalu mengembalikan exit code
+func runMigrate(database *sqlx.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrations.Up(database)
+		for _, migration := range done {
+			fmt.Printf("applied  %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR -", err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				fmt.Fprintln(os.Stderr, "ERROR - invalid number of steps:", args[1])
+				return 2
+			}
+		}
+		done, err := migrations.Down(database, steps)
+		for _, migration := range done {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR -", err)
+			return 1
+		}
+
+	case "status":
+		statuses, err := migrations.StatusList(database)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ERROR -", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Missing {
+				state = "unknown"
+			} else if status.Applied {
+				state = "applied"
+			}
+			appliedAt := ""
+			if status.Applied {
+				appliedAt = time.Unix(status.AppliedTstamp, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%-8s %04d_%-32s %s\n", state, status.Version, status.Name, appliedAt)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,350 @@
+package migrations
+
+import (
+	"auth_service/logger"
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrasi skema database (PostgreSQL). File SQL di-embed ke binary dari folder sql/ dengan format
+// <versi>_<nama>.up.sql dan <versi>_<nama>.down.sql, dijalankan berurutan menurut versi.
+// Versi yang sudah dijalankan dicatat di public.schema_migrations.
+//
+// Migrasi 0001 - 0018 ditulis dengan IF NOT EXISTS karena database yang sudah berjalan dibuat manual
+// dari simpel_backup.sql dan komentar DDL di kode, sehingga bisa diadopsi tanpa error.
+// 0001 dan 0002 hanya mengadopsi skema lama dan sengaja tidak punya file down, Down berhenti di sana.
+// Migrasi baru cukup menambah file dengan versi berikutnya.
+
+//go:embed sql/*.sql
+var files embed.FS
+
+/*
+CREATE TABLE public.schema_migrations (
+	version int8 NOT NULL,
+	name character varying(255) NOT NULL,
+	applied_tstamp int8 NOT NULL,
+	CONSTRAINT schema_migrations_pkey PRIMARY KEY (version)
+);
+*/
+
+const queryCreateTable = `
+	CREATE TABLE IF NOT EXISTS public.schema_migrations (
+		version int8 NOT NULL,
+		name character varying(255) NOT NULL,
+		applied_tstamp int8 NOT NULL,
+		CONSTRAINT schema_migrations_pkey PRIMARY KEY (version)
+	)`
+
+// kunci pg_advisory_lock, nilai tetap supaya replika yang start bersamaan saling menunggu
+const lockKey int64 = 7240183019
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version       int64
+	Name          string
+	Applied       bool
+	AppliedTstamp int64
+	Missing       bool // tercatat di schema_migrations tetapi tidak ada di binary (binary lebih lama)
+}
+
+// Load membaca semua migrasi yang di-embed, diurutkan menurut versi
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+// load membaca pasangan file up / down dari folder sql/ pada fsys
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, isUp := strings.CutSuffix(fileName, ".up.sql")
+		if !isUp {
+			var isDown bool
+			if base, isDown = strings.CutSuffix(fileName, ".down.sql"); !isDown {
+				return nil, fmt.Errorf("migrations: unexpected file %s", fileName)
+			}
+		}
+
+		versionText, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionText, 10, 64)
+		if !ok || err != nil || version <= 0 || name == "" {
+			return nil, fmt.Errorf("migrations: invalid file name %s", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, "sql/"+fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("migrations: duplicate version %d (%s, %s)", version, migration.Name, name)
+		}
+		if isUp {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migrations: version %d has no up file", migration.Version)
+		}
+		list = append(list, *migration)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// withLock menjalankan fn di satu koneksi yang memegang advisory lock.
+// Lock level sesi ikut terlepas bila proses mati di tengah jalan.
+func withLock(database *sqlx.DB, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	if err := checkDriver(database); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("migrations: failed to acquire lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, queryCreateTable); err != nil {
+		return fmt.Errorf("migrations: failed to create schema_migrations: %w", err)
+	}
+
+	return fn(ctx, conn)
+}
+
+func checkDriver(database *sqlx.DB) error {
+	if database.DriverName() != "postgres" {
+		return fmt.Errorf("migrations: driver %s is not supported, only postgres", database.DriverName())
+	}
+	return nil
+}
+
+type appliedMigration struct {
+	Name   string
+	Tstamp int64
+}
+
+// appliedVersions mengembalikan versi yang sudah dijalankan beserta nama dan waktunya
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_tstamp FROM public.schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var migration appliedMigration
+		if err := rows.Scan(&version, &migration.Name, &migration.Tstamp); err != nil {
+			return nil, err
+		}
+		applied[version] = migration
+	}
+	return applied, rows.Err()
+}
+
+// run menjalankan satu file migrasi dan mencatat/menghapus versinya dalam satu transaksi
+func run(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script := migration.Down
+	if up {
+		script = migration.Up
+	}
+	// tanpa parameter, lib/pq memakai simple query sehingga satu file boleh berisi banyak statement
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migrations: %04d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO public.schema_migrations (version, name, applied_tstamp) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, time.Now().Unix())
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM public.schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Up menjalankan semua migrasi yang belum tercatat, berurutan menurut versi
+func Up(database *sqlx.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = withLock(database, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, exists := applied[migration.Version]; exists {
+				continue
+			}
+			if err := run(ctx, conn, migration, true); err != nil {
+				return err
+			}
+			logger.Info("MIGRATE", fmt.Sprintf("INFO - Applied %04d_%s", migration.Version, migration.Name))
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down membatalkan steps migrasi terakhir yang sudah dijalankan
+func Down(database *sqlx.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("migrations: steps must be greater than 0")
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]Migration{}
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var done []Migration
+	err = withLock(database, func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			migration, exists := byVersion[version]
+			if !exists {
+				return fmt.Errorf("migrations: version %d is applied but not known to this binary", version)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migrations: %04d_%s has no down file", migration.Version, migration.Name)
+			}
+			if err := run(ctx, conn, migration, false); err != nil {
+				return err
+			}
+			logger.Info("MIGRATE", fmt.Sprintf("INFO - Reverted %04d_%s", migration.Version, migration.Name))
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Pending mengembalikan jumlah migrasi yang belum dijalankan, tanpa menjalankannya (read-only, lihat StatusList)
+func Pending(database *sqlx.DB) (int, error) {
+	statuses, err := StatusList(database)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// StatusList menggabungkan migrasi di binary dengan yang tercatat di database.
+// Hanya membaca: tanpa advisory lock dan tanpa membuat schema_migrations, sehingga aman dipanggil
+// saat start meskipun replika lain sedang menjalankan migrate up. Tabel yang belum ada berarti
+// semua migrasi masih pending.
+func StatusList(database *sqlx.DB) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDriver(database); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var tableExists bool
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('public.schema_migrations') IS NOT NULL`).Scan(&tableExists); err != nil {
+		return nil, err
+	}
+	applied := map[int64]appliedMigration{}
+	if tableExists {
+		if applied, err = appliedVersions(ctx, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []Status
+	for _, migration := range migrations {
+		record, exists := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Version:       migration.Version,
+			Name:          migration.Name,
+			Applied:       exists,
+			AppliedTstamp: record.Tstamp,
+		})
+		delete(applied, migration.Version)
+	}
+	for version, record := range applied {
+		statuses = append(statuses, Status{Version: version, Name: record.Name, Applied: true, AppliedTstamp: record.Tstamp, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Load returned no migrations")
+	}
+
+	for i, migration := range migrations {
+		// versi berurutan tanpa celah, dimulai dari 1
+		if migration.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d, want %d", i, migration.Version, i+1)
+		}
+		if strings.TrimSpace(migration.Up) == "" {
+			t.Errorf("%04d_%s has an empty up file", migration.Version, migration.Name)
+		}
+		// migrasi adopsi tidak boleh punya down yang menghapus skema lama
+		adoption := migration.Version <= 2
+		if adoption && migration.Down != "" {
+			t.Errorf("%04d_%s is an adoption migration but has a down file", migration.Version, migration.Name)
+		}
+		if !adoption && strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("%04d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestLoadPairing(t *testing.T) {
+	file := func(content string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(content)} }
+
+	tests := []struct {
+		name    string
+		files   fstest.MapFS
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "pairs up and down, sorted by version",
+			files: fstest.MapFS{
+				"sql/0010_later.up.sql":    file("up 10"),
+				"sql/0002_second.up.sql":   file("up 2"),
+				"sql/0002_second.down.sql": file("down 2"),
+				"sql/0001_first.up.sql":    file("up 1"),
+			},
+			want: []Migration{
+				{Version: 1, Name: "first", Up: "up 1"},
+				{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+				{Version: 10, Name: "later", Up: "up 10"},
+			},
+		},
+		{
+			name:  "name with underscores",
+			files: fstest.MapFS{"sql/0003_user_phone_pending.up.sql": file("up")},
+			want:  []Migration{{Version: 3, Name: "user_phone_pending", Up: "up"}},
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"sql/0001_first.down.sql": file("down")},
+			wantErr: "has no up file",
+		},
+		{
+			name: "same version with different names",
+			files: fstest.MapFS{
+				"sql/0001_first.up.sql": file("up"),
+				"sql/0001_other.up.sql": file("up"),
+			},
+			wantErr: "duplicate version 1",
+		},
+		{
+			name: "down paired by version and name",
+			files: fstest.MapFS{
+				"sql/0001_first.up.sql":   file("up"),
+				"sql/0001_other.down.sql": file("down"),
+			},
+			wantErr: "duplicate version 1",
+		},
+		{
+			name:    "unexpected extension",
+			files:   fstest.MapFS{"sql/0001_first.sql": file("up")},
+			wantErr: "unexpected file",
+		},
+		{
+			name:    "missing name",
+			files:   fstest.MapFS{"sql/0001.up.sql": file("up")},
+			wantErr: "invalid file name",
+		},
+		{
+			name:    "non numeric version",
+			files:   fstest.MapFS{"sql/abc_first.up.sql": file("up")},
+			wantErr: "invalid file name",
+		},
+		{
+			name:    "zero version",
+			files:   fstest.MapFS{"sql/0000_first.up.sql": file("up")},
+			wantErr: "invalid file name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load(): %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("load() returned %d migrations, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("migration %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+-- skema awal sesuai simpel_backup.sql, IF NOT EXISTS supaya database lama bisa langsung diadopsi
+CREATE SCHEMA IF NOT EXISTS device;
+CREATE SCHEMA IF NOT EXISTS sysuser;
+
+CREATE SEQUENCE IF NOT EXISTS public.device_id_sq;
+CREATE SEQUENCE IF NOT EXISTS sysuser.user_id_seq;
+CREATE SEQUENCE IF NOT EXISTS device.data_id_seq;
+
+CREATE TABLE IF NOT EXISTS sysuser."user" (
+	username character varying(30) NOT NULL,
+	full_name character varying(128) NOT NULL,
+	st integer NOT NULL,
+	salt character varying(64) NOT NULL,
+	saltedpassword character varying(128) NOT NULL,
+	data jsonb NOT NULL,
+	id bigint DEFAULT nextval('sysuser.user_id_seq'::regclass) NOT NULL,
+	role character varying(128) NOT NULL,
+	CONSTRAINT user_pkey PRIMARY KEY (id),
+	CONSTRAINT user_unique_name UNIQUE (username)
+);
+
+CREATE TABLE IF NOT EXISTS sysuser.session (
+	session_id character varying(16) NOT NULL,
+	user_id bigint NOT NULL,
+	session_hash character varying(128) NOT NULL,
+	tstamp bigint NOT NULL,
+	st integer NOT NULL,
+	last_ms_tstamp bigint,
+	last_sequence bigint,
+	CONSTRAINT session_pkey PRIMARY KEY (session_id),
+	CONSTRAINT session_user_id_key UNIQUE (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS sysuser.token (
+	user_id bigint NOT NULL,
+	token character varying(128) NOT NULL,
+	tstamp bigint NOT NULL,
+	CONSTRAINT token_pkey PRIMARY KEY (user_id, token),
+	CONSTRAINT unique_user_id UNIQUE (user_id),
+	CONSTRAINT fk_user_id FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS device.unit (
+	id bigint DEFAULT nextval('public.device_id_sq'::regclass) NOT NULL,
+	name character varying(255) NOT NULL,
+	status integer NOT NULL,
+	salt character varying(64) NOT NULL,
+	salted_password character varying(128) NOT NULL,
+	data jsonb NOT NULL,
+	create_tstamp bigint DEFAULT (EXTRACT(epoch FROM now()))::bigint,
+	CONSTRAINT unit_pkey PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS device.data (
+	id bigint DEFAULT nextval('device.data_id_seq'::regclass) NOT NULL,
+	unit_id bigint NOT NULL,
+	tstamp bigint DEFAULT (EXTRACT(epoch FROM now()))::bigint NOT NULL,
+	voltage double precision NOT NULL,
+	current double precision NOT NULL,
+	power double precision NOT NULL,
+	energy double precision NOT NULL,
+	frequency double precision NOT NULL,
+	power_factor double precision NOT NULL,
+	CONSTRAINT data_new_pkey1 PRIMARY KEY (id),
+	CONSTRAINT fk_unit FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+);
+ALTER SEQUENCE device.data_id_seq OWNED BY device.data.id;
@@ -0,0 +1,3 @@
+-- kolom email sudah dipakai kode tetapi tidak ada di simpel_backup.sql
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS email character varying(255);
+CREATE UNIQUE INDEX IF NOT EXISTS user_unique_email ON sysuser."user" (email);
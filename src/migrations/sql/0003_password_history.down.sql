@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sysuser.password_history;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS sysuser.password_history (
+	id bigserial PRIMARY KEY,
+	user_id bigint NOT NULL REFERENCES sysuser."user"(id) ON DELETE CASCADE,
+	salt character varying(64) NOT NULL,
+	saltedpassword character varying(128) NOT NULL,
+	tstamp bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS password_history_user_id_idx ON sysuser.password_history (user_id, tstamp DESC);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS sysuser.password_reset (
+	token_hash character varying(64) PRIMARY KEY,
+	user_id bigint NOT NULL REFERENCES sysuser."user"(id) ON DELETE CASCADE,
+	expire_tstamp bigint NOT NULL,
+	used boolean NOT NULL DEFAULT false,
+	used_tstamp bigint,
+	create_tstamp bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS password_reset_user_id_idx ON sysuser.password_reset (user_id) WHERE NOT used;
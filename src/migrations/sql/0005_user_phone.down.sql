@@ -0,0 +1,3 @@
+ALTER TABLE sysuser."user" DROP CONSTRAINT IF EXISTS user_unique_phone;
+DROP INDEX IF EXISTS sysuser.user_unique_phone;
+ALTER TABLE sysuser."user" DROP COLUMN IF EXISTS phone;
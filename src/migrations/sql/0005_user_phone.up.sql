@@ -0,0 +1,3 @@
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS phone character varying(16);
+-- index unik dengan nama yang sama dengan constraint lama, dilewati bila constraint sudah dibuat manual
+CREATE UNIQUE INDEX IF NOT EXISTS user_unique_phone ON sysuser."user" (phone);
@@ -0,0 +1,4 @@
+ALTER TABLE sysuser."user" DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE sysuser."user" DROP COLUMN IF EXISTS totp_enabled;
+ALTER TABLE sysuser."user" DROP COLUMN IF EXISTS totp_pending_secret;
+ALTER TABLE sysuser."user" DROP COLUMN IF EXISTS totp_secret;
@@ -0,0 +1,4 @@
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS totp_secret character varying(255);          -- AES-GCM (crypto.EncryptSecret)
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS totp_pending_secret character varying(255);  -- secret enrollment yang belum dikonfirmasi
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false;
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS totp_last_step bigint;                       -- time-step terakhir yang dipakai (anti replay)
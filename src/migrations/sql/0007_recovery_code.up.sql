@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS sysuser.recovery_code (
+	id serial4 NOT NULL,
+	user_id int8 NOT NULL,
+	code_hash character varying(64) NOT NULL, -- SHA-256 hex dari kode yang sudah dinormalisasi
+	used boolean NOT NULL DEFAULT false,
+	used_tstamp int8 NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT recovery_code_pkey PRIMARY KEY (id),
+	CONSTRAINT recovery_code_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS recovery_code_user_idx ON sysuser.recovery_code (user_id) WHERE NOT used;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS sysuser.webauthn_credential (
+	id serial4 NOT NULL,
+	user_id int8 NOT NULL,
+	credential_id character varying(1400) NOT NULL, -- base64url tanpa padding
+	public_key bytea NOT NULL,                      -- COSE key
+	sign_count int8 NOT NULL DEFAULT 0,
+	aaguid character varying(36) NULL,
+	attestation_format character varying(16) NOT NULL,
+	transports character varying(128) NULL,         -- dipisah koma, dikirim ulang di allowCredentials
+	name character varying(64) NULL,
+	create_tstamp int8 NOT NULL,
+	last_used_tstamp int8 NULL,
+	CONSTRAINT webauthn_credential_pkey PRIMARY KEY (id),
+	CONSTRAINT webauthn_credential_id_unique UNIQUE (credential_id),
+	CONSTRAINT webauthn_credential_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS webauthn_credential_user_idx ON sysuser.webauthn_credential (user_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sysuser.oauth_consent;
+DROP TABLE IF EXISTS sysuser.oauth_token;
+DROP TABLE IF EXISTS sysuser.oauth_client;
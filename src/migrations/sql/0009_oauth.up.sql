@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS sysuser.oauth_client (
+	client_id character varying(64) NOT NULL,
+	client_secret_hash character varying(64) NULL, -- SHA-256 hex, NULL untuk public client (SPA / mobile)
+	name character varying(128) NOT NULL,
+	redirect_uris text NOT NULL,                    -- dipisah spasi, dicocokkan persis
+	scopes character varying(512) NOT NULL,         -- scope yang boleh diminta, dipisah spasi
+	is_public boolean NOT NULL DEFAULT false,
+	skip_consent boolean NOT NULL DEFAULT false,    -- aplikasi internal tanpa layar consent
+	create_tstamp int8 NOT NULL,
+	st int4 NOT NULL DEFAULT 1,
+	CONSTRAINT oauth_client_pkey PRIMARY KEY (client_id)
+);
+
+CREATE TABLE IF NOT EXISTS sysuser.oauth_token (
+	token_hash character varying(64) NOT NULL,      -- SHA-256 hex
+	token_type character varying(16) NOT NULL,      -- access_token | refresh_token
+	client_id character varying(64) NOT NULL,
+	user_id int8 NULL,
+	scope character varying(512) NOT NULL,
+	grant_id character varying(64) NOT NULL,        -- sama untuk semua token dari satu otorisasi
+	expire_tstamp int8 NOT NULL,
+	revoked boolean NOT NULL DEFAULT false,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT oauth_token_pkey PRIMARY KEY (token_hash),
+	CONSTRAINT oauth_token_client_fkey FOREIGN KEY (client_id) REFERENCES sysuser.oauth_client(client_id) ON DELETE CASCADE,
+	CONSTRAINT oauth_token_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS oauth_token_grant_idx ON sysuser.oauth_token (grant_id);
+
+CREATE TABLE IF NOT EXISTS sysuser.oauth_consent (
+	user_id int8 NOT NULL,
+	client_id character varying(64) NOT NULL,
+	scope character varying(512) NOT NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT oauth_consent_pkey PRIMARY KEY (user_id, client_id),
+	CONSTRAINT oauth_consent_client_fkey FOREIGN KEY (client_id) REFERENCES sysuser.oauth_client(client_id) ON DELETE CASCADE,
+	CONSTRAINT oauth_consent_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
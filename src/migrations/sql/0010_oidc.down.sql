@@ -0,0 +1,3 @@
+ALTER TABLE sysuser.oauth_client DROP COLUMN IF EXISTS post_logout_redirect_uris;
+DROP INDEX IF EXISTS sysuser.oauth_token_session_idx;
+ALTER TABLE sysuser.oauth_token DROP COLUMN IF EXISTS session_id;
@@ -0,0 +1,4 @@
+ALTER TABLE sysuser.oauth_token ADD COLUMN IF NOT EXISTS session_id character varying(16) NULL; -- sesi login asal otorisasi
+CREATE INDEX IF NOT EXISTS oauth_token_session_idx ON sysuser.oauth_token (session_id);
+
+ALTER TABLE sysuser.oauth_client ADD COLUMN IF NOT EXISTS post_logout_redirect_uris text NULL; -- dipisah spasi, dicocokkan persis
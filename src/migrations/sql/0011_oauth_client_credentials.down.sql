@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sysuser.oauth_client_secret;
+ALTER TABLE sysuser.oauth_client DROP COLUMN IF EXISTS rate_limit;
+ALTER TABLE sysuser.oauth_client DROP COLUMN IF EXISTS grant_types;
@@ -0,0 +1,13 @@
+ALTER TABLE sysuser.oauth_client ADD COLUMN IF NOT EXISTS grant_types character varying(128) NOT NULL DEFAULT 'authorization_code refresh_token';
+ALTER TABLE sysuser.oauth_client ADD COLUMN IF NOT EXISTS rate_limit int4 NULL; -- request per window, NULL = default config
+
+CREATE TABLE IF NOT EXISTS sysuser.oauth_client_secret (
+	id bigserial NOT NULL,
+	client_id character varying(64) NOT NULL,
+	secret_hash character varying(64) NOT NULL,     -- SHA-256 hex, secret sebelum rotasi
+	expire_tstamp int8 NOT NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT oauth_client_secret_pkey PRIMARY KEY (id),
+	CONSTRAINT oauth_client_secret_client_fkey FOREIGN KEY (client_id) REFERENCES sysuser.oauth_client(client_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS oauth_client_secret_client_idx ON sysuser.oauth_client_secret (client_id);
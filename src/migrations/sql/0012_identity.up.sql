@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sysuser.identity (
+	id bigserial NOT NULL,
+	provider character varying(64) NOT NULL,
+	subject character varying(255) NOT NULL,
+	user_id int8 NOT NULL,
+	email character varying(255) NULL,      -- email dari provider saat terakhir login
+	create_tstamp int8 NOT NULL,
+	last_login_tstamp int8 NULL,
+	CONSTRAINT identity_pkey PRIMARY KEY (id),
+	CONSTRAINT identity_provider_subject_key UNIQUE (provider, subject),
+	CONSTRAINT identity_user_provider_key UNIQUE (user_id, provider),
+	CONSTRAINT identity_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
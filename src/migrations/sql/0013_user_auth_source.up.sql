@@ -0,0 +1 @@
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS auth_source character varying(16) NOT NULL DEFAULT 'local'; -- local | ldap
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS sysuser.api_key (
+	id bigserial NOT NULL,
+	user_id int8 NOT NULL,
+	name character varying(64) NOT NULL,
+	prefix character varying(16) NOT NULL,  -- bagian publik key, dipakai untuk lookup
+	key_hash character varying(64) NOT NULL, -- sha256 hex dari key lengkap
+	scopes character varying(255) NOT NULL,  -- dipisah spasi
+	expire_tstamp int8 NULL,                 -- NULL = tidak kedaluwarsa
+	create_tstamp int8 NOT NULL,
+	last_used_tstamp int8 NULL,
+	last_used_ip character varying(45) NULL,
+	revoked_tstamp int8 NULL,
+	CONSTRAINT api_key_pkey PRIMARY KEY (id),
+	CONSTRAINT api_key_prefix_unique UNIQUE (prefix),
+	CONSTRAINT api_key_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS api_key_user_idx ON sysuser.api_key (user_id);
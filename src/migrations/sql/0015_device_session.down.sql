@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS device.session;
+DROP TABLE IF EXISTS device.token;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS device.token (
+	unit_id int8 NOT NULL,
+	token character varying(64) NOT NULL,
+	tstamp int8 NOT NULL,
+	CONSTRAINT device_token_pkey PRIMARY KEY (unit_id),
+	CONSTRAINT device_token_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS device_token_token_idx ON device.token (token);
+
+CREATE TABLE IF NOT EXISTS device.session (
+	session_id character varying(16) NOT NULL,
+	unit_id int8 NOT NULL,
+	session_hash character varying(128) NOT NULL,
+	tstamp int8 NOT NULL,
+	st int4 NOT NULL,
+	CONSTRAINT device_session_pkey PRIMARY KEY (session_id),
+	CONSTRAINT device_session_unit_key UNIQUE (unit_id),
+	CONSTRAINT device_session_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS device.data_unit_tstamp_idx;
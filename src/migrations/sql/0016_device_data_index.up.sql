@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS data_unit_tstamp_idx ON device.data (unit_id, tstamp);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS device.unit_grant (
+	unit_id int8 NOT NULL,
+	user_id int8 NOT NULL,
+	role character varying(16) NOT NULL DEFAULT 'viewer', -- owner | operator | viewer
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT unit_grant_pkey PRIMARY KEY (unit_id, user_id),
+	CONSTRAINT unit_grant_role_check CHECK (role IN ('owner', 'operator', 'viewer')),
+	CONSTRAINT unit_grant_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE,
+	CONSTRAINT unit_grant_user_fkey FOREIGN KEY (user_id) REFERENCES sysuser."user"(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS unit_grant_user_idx ON device.unit_grant (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS unit_grant_owner_idx ON device.unit_grant (unit_id) WHERE role = 'owner';
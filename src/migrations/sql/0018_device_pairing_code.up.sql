@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS device.pairing_code (
+	unit_id int8 NOT NULL,
+	code_hash character varying(64) NOT NULL, -- SHA-256 hex dari kode yang sudah dinormalisasi
+	expire_tstamp int8 NOT NULL,
+	create_tstamp int8 NOT NULL,
+	CONSTRAINT pairing_code_pkey PRIMARY KEY (unit_id),
+	CONSTRAINT pairing_code_hash_key UNIQUE (code_hash),
+	CONSTRAINT pairing_code_unit_fkey FOREIGN KEY (unit_id) REFERENCES device.unit(id) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE sysuser."user" DROP COLUMN IF EXISTS phone_pending;
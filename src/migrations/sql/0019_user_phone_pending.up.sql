@@ -0,0 +1,2 @@
+-- nomor dari registrasi lewat email belum terbukti milik pendaftar, disimpan terpisah dari kolom phone yang unik
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS phone_pending character varying(16);
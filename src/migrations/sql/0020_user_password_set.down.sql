@@ -0,0 +1 @@
+ALTER TABLE sysuser."user" DROP COLUMN IF EXISTS password_set;
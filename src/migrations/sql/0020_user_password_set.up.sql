@@ -0,0 +1,2 @@
+-- false untuk akun yang dibuat dari identity provider (password acak), menjadi true setelah reset / ganti password
+ALTER TABLE sysuser."user" ADD COLUMN IF NOT EXISTS password_set boolean NOT NULL DEFAULT true;
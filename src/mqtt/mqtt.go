@@ -0,0 +1,226 @@
+package mqtt
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Hook autentikasi broker MQTT lewat HTTP (mosquitto-go-auth backend "http", EMQX HTTP authn/authz).
+// Broker memanggil service ini saat client connect (user), cek superuser, dan setiap publish/subscribe (acl).
+//
+// Contoh mosquitto-go-auth:
+/*
+	auth_opt_backends http
+	auth_opt_http_host auth-service
+	auth_opt_http_port 5000
+	auth_opt_http_getuser_uri /mqtt/auth/user
+	auth_opt_http_superuser_uri /mqtt/auth/superuser
+	auth_opt_http_aclcheck_uri /mqtt/auth/acl
+	auth_opt_http_params_mode form
+	auth_opt_http_response_mode status
+*/
+// EMQX cukup diarahkan ke URL yang sama dengan body JSON {"username","password","clientid","peerhost"} untuk authn
+// dan {"username","clientid","topic","action"} untuk authz.
+//
+// Broker wajib mengirim header X-Hook-Secret (mosquitto-go-auth: auth_opt_http_header_x-hook-secret,
+// EMQX: headers pada konfigurasi HTTP), tanpa MQTTHOOKSECRET endpoint hook tidak dipasang.
+//
+// Skema topic: <prefix>/<unit_id>/<subtopic...>, contoh device/12/telemetry dan device/12/command/relay.
+
+type Config struct {
+	HookSecret   string // dicek dari header X-Hook-Secret, kosong berarti hook dimatikan
+	TopicPrefix  string
+	ResponseMode string // "status" (default) atau "json", lihat Respond
+}
+
+// Access adalah jenis akses yang diminta broker pada pengecekan ACL
+type Access int
+
+const (
+	AccessRead  Access = 1 // subscribe / menerima pesan
+	AccessWrite Access = 2 // publish
+)
+
+// subtopic perintah ke unit, hanya device ini yang subscribe dan hanya operator/owner yang publish
+const CommandTopic = "command"
+
+var config = Config{TopicPrefix: "device", ResponseMode: "status"}
+
+func Init(cfg Config) error {
+	cfg.TopicPrefix = strings.Trim(cfg.TopicPrefix, "/")
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = "device"
+	}
+	if strings.ContainsAny(cfg.TopicPrefix, "+#") {
+		return errors.New("mqtt: topic prefix must not contain wildcards")
+	}
+	if cfg.ResponseMode == "" {
+		cfg.ResponseMode = "status"
+	}
+	if cfg.ResponseMode != "status" && cfg.ResponseMode != "json" {
+		return errors.New("mqtt: response mode must be status or json")
+	}
+	config = cfg
+	return nil
+}
+
+// Enabled mengecek apakah hook aktif (MQTTHOOKSECRET diisi)
+func Enabled() bool {
+	return config.HookSecret != ""
+}
+
+// CheckHookSecret memastikan request datang dari broker, selalu gagal bila secret tidak dikonfigurasi
+func CheckHookSecret(r *http.Request) bool {
+	if config.HookSecret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Hook-Secret")), []byte(config.HookSecret)) == 1
+}
+
+// ReadParams membaca parameter hook, broker bisa mengirim form (go-auth) atau JSON (EMQX, go-auth params_mode json)
+func ReadParams(r *http.Request) (map[string]string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{}
+	if mediaType == "application/json" {
+		var data map[string]any
+		if err := json.Unmarshal(body, &data); err != nil {
+			return nil, err
+		}
+		for key, value := range data {
+			switch v := value.(type) {
+			case string:
+				params[key] = v
+			case float64:
+				params[key] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		return params, nil
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	for key := range values {
+		params[key] = values.Get(key)
+	}
+	return params, nil
+}
+
+// ParseAccess menerjemahkan "acc" go-auth (1 read, 2 write, 3 readwrite, 4 subscribe)
+// atau "action" EMQX (publish, subscribe, all). readwrite/all diperlakukan sebagai write.
+func ParseAccess(params map[string]string) (Access, bool) {
+	if action, exists := params["action"]; exists {
+		switch action {
+		case "subscribe":
+			return AccessRead, true
+		case "publish", "all":
+			return AccessWrite, true
+		}
+		return 0, false
+	}
+
+	switch params["acc"] {
+	case "1", "4":
+		return AccessRead, true
+	case "2", "3":
+		return AccessWrite, true
+	}
+	return 0, false
+}
+
+// ParseTopic memecah topic menjadi unit_id dan subtopic.
+// Segmen unit_id harus angka, wildcard hanya boleh setelahnya (misal device/12/#).
+func ParseTopic(topic string) (unitID int64, subtopic []string, ok bool) {
+	rest, found := strings.CutPrefix(topic, config.TopicPrefix+"/")
+	if !found {
+		return 0, nil, false
+	}
+	parts := strings.Split(rest, "/")
+	unitID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || unitID <= 0 {
+		return 0, nil, false
+	}
+	return unitID, parts[1:], true
+}
+
+// IsCommandTopic mengecek apakah subtopic seluruhnya berada di bawah <prefix>/<unit_id>/command.
+// Filter seperti device/12/# mencakup command tetapi juga topic lain, sehingga tidak dihitung.
+func IsCommandTopic(subtopic []string) bool {
+	return len(subtopic) > 0 && subtopic[0] == CommandTopic
+}
+
+// UnitAllowed memutuskan ACL client unit: hanya topic unit itu sendiri, publish ke selain command
+// dan subscribe hanya ke command. active false bila unit dinonaktifkan setelah connect.
+func UnitAllowed(clientUnitID, unitID int64, active bool, access Access, subtopic []string) bool {
+	if clientUnitID != unitID || !active {
+		return false
+	}
+	if access == AccessWrite {
+		return len(subtopic) > 0 && !IsCommandTopic(subtopic)
+	}
+	return IsCommandTopic(subtopic)
+}
+
+// UserAllowed memutuskan ACL client user: canRead untuk grant role apa pun (atau admin) dengan scope device:read,
+// canControl untuk role operator / owner dengan scope device:control yang boleh publish ke command
+func UserAllowed(canRead, canControl bool, access Access, subtopic []string) bool {
+	if access == AccessWrite {
+		return canControl && IsCommandTopic(subtopic)
+	}
+	return canRead || canControl
+}
+
+// DeviceUsername adalah username MQTT untuk unit: unit-<id>
+func DeviceUsername(unitID int64) string {
+	return "unit-" + strconv.FormatInt(unitID, 10)
+}
+
+// ParseDeviceUsername mengembalikan unit_id dari username unit-<id>
+func ParseDeviceUsername(username string) (int64, bool) {
+	idText, found := strings.CutPrefix(username, "unit-")
+	if !found {
+		return 0, false
+	}
+	unitID, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil || unitID <= 0 || DeviceUsername(unitID) != username {
+		return 0, false
+	}
+	return unitID, true
+}
+
+// Respond menulis keputusan dalam bentuk yang dimengerti kedua broker, body berisi
+// {"ok", "error"} untuk go-auth response_mode json dan {"result", "is_superuser"} untuk EMQX.
+// Mode "status" menolak dengan 403 (go-auth response_mode status), mode "json" selalu 200
+// karena EMQX menganggap status selain 2xx sebagai "ignore" dan lanjut ke authorizer berikutnya.
+func Respond(w http.ResponseWriter, allow bool, reason string) {
+	body := map[string]any{
+		"ok":           allow,
+		"error":        reason,
+		"result":       "deny",
+		"is_superuser": false,
+	}
+	status := http.StatusOK
+	if allow {
+		body["result"] = "allow"
+	} else if config.ResponseMode == "status" {
+		status = http.StatusForbidden
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
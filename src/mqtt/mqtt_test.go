@@ -0,0 +1,285 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setTestConfig(t *testing.T, cfg Config) {
+	t.Helper()
+	previous := config
+	if err := Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config = previous })
+}
+
+func TestInit(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantPrefix string
+		wantErr    bool
+	}{
+		{"defaults", Config{}, "device", false},
+		{"trimmed prefix", Config{TopicPrefix: "/site/a/"}, "site/a", false},
+		{"json mode", Config{ResponseMode: "json"}, "device", false},
+		{"wildcard prefix", Config{TopicPrefix: "device/+"}, "", true},
+		{"unknown mode", Config{ResponseMode: "xml"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Init(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Init() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && config.TopicPrefix != tt.wantPrefix {
+				t.Errorf("TopicPrefix = %q, want %q", config.TopicPrefix, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestCheckHookSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   bool
+	}{
+		{"matching secret", "s3cret", "s3cret", true},
+		{"wrong secret", "s3cret", "other", false},
+		{"missing header", "s3cret", "", false},
+		{"secret not configured", "", "", false},
+		{"secret not configured with header", "", "anything", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, Config{HookSecret: tt.secret})
+			r := httptest.NewRequest(http.MethodPost, "/mqtt/auth/user", nil)
+			if tt.header != "" {
+				r.Header.Set("X-Hook-Secret", tt.header)
+			}
+			if got := CheckHookSecret(r); got != tt.want {
+				t.Errorf("CheckHookSecret() = %v, want %v", got, tt.want)
+			}
+			if Enabled() != (tt.secret != "") {
+				t.Errorf("Enabled() = %v with secret %q", Enabled(), tt.secret)
+			}
+		})
+	}
+}
+
+func TestReadParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        map[string]string
+		wantErr     bool
+	}{
+		{"form", "application/x-www-form-urlencoded", "username=unit-12&password=p%26w&acc=2", map[string]string{"username": "unit-12", "password": "p&w", "acc": "2"}, false},
+		{"json", "application/json; charset=utf-8", `{"username":"a@example.com","action":"publish","qos":1,"retain":true}`, map[string]string{"username": "a@example.com", "action": "publish", "qos": "1"}, false},
+		{"invalid json", "application/json", `{"username":`, nil, true},
+		{"invalid form", "", "username=%zz", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/mqtt/auth/acl", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			got, err := ReadParams(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAccess(t *testing.T) {
+	tests := []struct {
+		params map[string]string
+		want   Access
+		ok     bool
+	}{
+		{map[string]string{"acc": "1"}, AccessRead, true},
+		{map[string]string{"acc": "4"}, AccessRead, true},
+		{map[string]string{"acc": "2"}, AccessWrite, true},
+		{map[string]string{"acc": "3"}, AccessWrite, true},
+		{map[string]string{"acc": "5"}, 0, false},
+		{map[string]string{"action": "subscribe"}, AccessRead, true},
+		{map[string]string{"action": "publish"}, AccessWrite, true},
+		{map[string]string{"action": "all"}, AccessWrite, true},
+		// action dari EMQX menang atas acc
+		{map[string]string{"action": "unsubscribe", "acc": "1"}, 0, false},
+		{map[string]string{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseAccess(tt.params)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseAccess(%v) = %d, %v, want %d, %v", tt.params, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseTopic(t *testing.T) {
+	setTestConfig(t, Config{TopicPrefix: "device"})
+
+	tests := []struct {
+		topic        string
+		wantUnit     int64
+		wantSubtopic []string
+		ok           bool
+	}{
+		{"device/12/telemetry", 12, []string{"telemetry"}, true},
+		{"device/12/command/relay", 12, []string{"command", "relay"}, true},
+		{"device/12/#", 12, []string{"#"}, true},
+		{"device/12", 12, []string{}, true},
+		{"device/+/telemetry", 0, nil, false},
+		{"device/#", 0, nil, false},
+		{"device/0/telemetry", 0, nil, false},
+		{"device/-3/telemetry", 0, nil, false},
+		{"other/12/telemetry", 0, nil, false},
+		{"devices/12/telemetry", 0, nil, false},
+		{"#", 0, nil, false},
+	}
+
+	for _, tt := range tests {
+		unitID, subtopic, ok := ParseTopic(tt.topic)
+		if ok != tt.ok || unitID != tt.wantUnit || (ok && !reflect.DeepEqual(subtopic, tt.wantSubtopic)) {
+			t.Errorf("ParseTopic(%q) = %d, %v, %v, want %d, %v, %v", tt.topic, unitID, subtopic, ok, tt.wantUnit, tt.wantSubtopic, tt.ok)
+		}
+	}
+}
+
+func TestParseDeviceUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     int64
+		ok       bool
+	}{
+		{"unit-12", 12, true},
+		{DeviceUsername(9000000000), 9000000000, true},
+		{"unit-012", 0, false},
+		{"unit-+12", 0, false},
+		{"unit-0", 0, false},
+		{"unit-", 0, false},
+		{"Unit-12", 0, false},
+		{"user@example.com", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseDeviceUsername(tt.username)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseDeviceUsername(%q) = %d, %v, want %d, %v", tt.username, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUnitAllowed(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientUnitID int64
+		active       bool
+		access       Access
+		topic        []string
+		want         bool
+	}{
+		{"publish telemetry", 12, true, AccessWrite, []string{"telemetry"}, true},
+		{"publish nested status", 12, true, AccessWrite, []string{"status", "relay"}, true},
+		{"publish to own command", 12, true, AccessWrite, []string{"command", "relay"}, false},
+		{"publish to unit root", 12, true, AccessWrite, []string{}, false},
+		{"subscribe own command", 12, true, AccessRead, []string{"command", "#"}, true},
+		{"subscribe own telemetry", 12, true, AccessRead, []string{"telemetry"}, false},
+		{"subscribe wildcard covering command", 12, true, AccessRead, []string{"#"}, false},
+		{"other unit publish", 13, true, AccessWrite, []string{"telemetry"}, false},
+		{"other unit subscribe command", 13, true, AccessRead, []string{"command"}, false},
+		{"disabled unit publish", 12, false, AccessWrite, []string{"telemetry"}, false},
+		{"disabled unit subscribe", 12, false, AccessRead, []string{"command"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnitAllowed(tt.clientUnitID, 12, tt.active, tt.access, tt.topic); got != tt.want {
+				t.Errorf("UnitAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserAllowed(t *testing.T) {
+	tests := []struct {
+		name       string
+		canRead    bool
+		canControl bool
+		access     Access
+		topic      []string
+		want       bool
+	}{
+		{"viewer subscribes telemetry", true, false, AccessRead, []string{"telemetry"}, true},
+		{"viewer subscribes everything", true, false, AccessRead, []string{"#"}, true},
+		{"viewer publishes command", true, false, AccessWrite, []string{"command", "relay"}, false},
+		{"operator publishes command", true, true, AccessWrite, []string{"command", "relay"}, true},
+		{"operator publishes telemetry", true, true, AccessWrite, []string{"telemetry"}, false},
+		{"operator publishes wildcard", true, true, AccessWrite, []string{"#"}, false},
+		{"admin without grant subscribes", true, false, AccessRead, []string{"telemetry"}, true},
+		{"no grant subscribes", false, false, AccessRead, []string{"telemetry"}, false},
+		{"no grant publishes command", false, false, AccessWrite, []string{"command"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UserAllowed(tt.canRead, tt.canControl, tt.access, tt.topic); got != tt.want {
+				t.Errorf("UserAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespond(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		allow      bool
+		wantStatus int
+		wantResult string
+	}{
+		{"status mode allow", "status", true, http.StatusOK, "allow"},
+		{"status mode deny", "status", false, http.StatusForbidden, "deny"},
+		{"json mode allow", "json", true, http.StatusOK, "allow"},
+		{"json mode deny", "json", false, http.StatusOK, "deny"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTestConfig(t, Config{ResponseMode: tt.mode})
+			w := httptest.NewRecorder()
+			Respond(w, tt.allow, "reason")
+
+			var body struct {
+				OK          bool   `json:"ok"`
+				Result      string `json:"result"`
+				IsSuperuser bool   `json:"is_superuser"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.wantStatus || body.OK != tt.allow || body.Result != tt.wantResult || body.IsSuperuser {
+				t.Errorf("Respond() = %d %+v", w.Code, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Penandatanganan ID token (JWT RS256) dan JWKS untuk OpenID Connect.
+// Kunci dibaca dari file PEM (PKCS#1 / PKCS#8). Tanpa file, kunci sementara dibuat saat start,
+// ID token lama tidak bisa diverifikasi lagi setelah restart.
+
+var (
+	issuer     string
+	signingKey *rsa.PrivateKey
+	keyID      string
+	mu         sync.RWMutex
+)
+
+var ErrInvalidToken = errors.New("oidc: invalid token")
+
+// Init mengatur issuer dan kunci tanda tangan. Mengembalikan true bila kunci dibuat sementara.
+func Init(issuerURL, keyFile string) (bool, error) {
+	if issuerURL == "" {
+		return false, errors.New("oidc: issuer must not be empty")
+	}
+
+	var key *rsa.PrivateKey
+	ephemeral := keyFile == ""
+	if ephemeral {
+		generated, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return false, err
+		}
+		key = generated
+	} else {
+		loaded, err := loadPrivateKey(keyFile)
+		if err != nil {
+			return false, err
+		}
+		key = loaded
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return false, err
+	}
+	thumbprint := sha256.Sum256(der)
+
+	mu.Lock()
+	defer mu.Unlock()
+	issuer = strings.TrimRight(issuerURL, "/")
+	signingKey = key
+	keyID = base64.RawURLEncoding.EncodeToString(thumbprint[:12])
+	return ephemeral, nil
+}
+
+func loadPrivateKey(keyFile string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("oidc: signing key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return checkKeySize(key)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("oidc: signing key must be RSA")
+	}
+	return checkKeySize(key)
+}
+
+func checkKeySize(key *rsa.PrivateKey) (*rsa.PrivateKey, error) {
+	if key.N.BitLen() < 2048 {
+		return nil, errors.New("oidc: signing key must be at least 2048 bits")
+	}
+	return key, nil
+}
+
+func Issuer() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return issuer
+}
+
+// Sign membuat JWT RS256 dari claims
+func Sign(claims map[string]any) (string, error) {
+	mu.RLock()
+	key, kid := signingKey, keyID
+	mu.RUnlock()
+	if key == nil {
+		return "", errors.New("oidc: signing key is not initialized")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify memeriksa tanda tangan dan issuer JWT yang diterbitkan server ini.
+// allowExpired dipakai untuk id_token_hint saat logout (token boleh sudah kedaluwarsa).
+func Verify(token string, allowExpired bool) (map[string]any, error) {
+	mu.RLock()
+	key, kid, iss := signingKey, keyID, issuer
+	mu.RUnlock()
+	if key == nil {
+		return nil, errors.New("oidc: signing key is not initialized")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header map[string]any
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header["alg"] != "RS256" || header["kid"] != kid {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims["iss"] != iss {
+		return nil, ErrInvalidToken
+	}
+	if !allowExpired {
+		exp, _ := claims["exp"].(float64)
+		if int64(exp) < time.Now().Unix() {
+			return nil, errors.New("oidc: token expired")
+		}
+	}
+
+	return claims, nil
+}
+
+// JWKS mengembalikan public key dalam format JSON Web Key Set
+func JWKS() map[string]any {
+	mu.RLock()
+	defer mu.RUnlock()
+	if signingKey == nil {
+		return map[string]any{"keys": []any{}}
+	}
+
+	return map[string]any{
+		"keys": []map[string]any{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(signingKey.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signingKey.E)).Bytes()),
+		}},
+	}
+}
+
+// AccessTokenHash menghitung at_hash (OIDC Core §3.1.3.6): setengah kiri SHA-256, base64url
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
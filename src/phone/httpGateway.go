@@ -0,0 +1,130 @@
+package phone
+
+import (
+	"auth_service/logger"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+/*
+HTTPGateway mengirim pesan lewat HTTP API provider SMS / WhatsApp. URL dan body berupa
+text/template dengan field {{.Channel}}, {{.To}} dan {{.Message}}, jadi bisa diarahkan
+ke provider mana saja atau ke stub server lokal. Fungsi "json" tersedia untuk escape nilai
+di body JSON, contoh body default:
+
+	{"channel":{{json .Channel}},"to":{{json .To}},"message":{{json .Message}}}
+*/
+
+const DefaultGatewayBody = `{"channel":{{json .Channel}},"to":{{json .To}},"message":{{json .Message}}}`
+
+const gatewayTimeout = 10 * time.Second
+
+// GatewayConfig diisi dari environment di main
+type GatewayConfig struct {
+	URL           string // boleh berisi template, misal https://gw.local/{{.Channel}}/send
+	Method        string
+	Body          string
+	ContentType   string
+	Authorization string
+}
+
+type gatewayData struct {
+	Channel string
+	To      string
+	Message string
+}
+
+type HTTPGateway struct {
+	urlTmpl       *template.Template
+	bodyTmpl      *template.Template
+	method        string
+	contentType   string
+	authorization string
+	client        *http.Client
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v string) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+func NewHTTPGateway(conf GatewayConfig) (*HTTPGateway, error) {
+	if conf.URL == "" {
+		return nil, errors.New("phone gateway URL is required")
+	}
+	if conf.Method == "" {
+		conf.Method = http.MethodPost
+	}
+	if conf.Body == "" {
+		conf.Body = DefaultGatewayBody
+	}
+	if conf.ContentType == "" {
+		conf.ContentType = "application/json"
+	}
+
+	urlTmpl, err := template.New("url").Funcs(templateFuncs).Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid phone gateway URL template: %w", err)
+	}
+	bodyTmpl, err := template.New("body").Funcs(templateFuncs).Parse(conf.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid phone gateway body template: %w", err)
+	}
+
+	return &HTTPGateway{
+		urlTmpl:       urlTmpl,
+		bodyTmpl:      bodyTmpl,
+		method:        strings.ToUpper(conf.Method),
+		contentType:   conf.ContentType,
+		authorization: conf.Authorization,
+		client:        &http.Client{Timeout: gatewayTimeout},
+	}, nil
+}
+
+func (g *HTTPGateway) Send(channel, to, message string) error {
+	data := gatewayData{Channel: channel, To: to, Message: message}
+
+	var urlBuf, bodyBuf bytes.Buffer
+	if err := g.urlTmpl.Execute(&urlBuf, data); err != nil {
+		return err
+	}
+	if err := g.bodyTmpl.Execute(&bodyBuf, data); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(g.method, urlBuf.String(), &bodyBuf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", g.contentType)
+	if g.authorization != "" {
+		req.Header.Set("Authorization", g.authorization)
+	}
+
+	logger.Info("SendPhone", "CHANNEL: ", channel, " TO: ", to)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		logger.Error("SendPhone", "Failed to call gateway:", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logger.Error("SendPhone", "Gateway rejected message, status: ", resp.StatusCode, " body: ", string(respBody))
+		return fmt.Errorf("phone gateway returned status %d", resp.StatusCode)
+	}
+
+	logger.Info("SendPhone", "Message successfully sent to:", to)
+	return nil
+}
@@ -0,0 +1,30 @@
+package phone
+
+import "fmt"
+
+// teks pesan singkat per locale, SMS sebaiknya tetap di bawah 160 karakter
+var messages = map[string]map[string]string{
+	"en": {
+		"registration_otp": "Your verification code is %s. It expires in %d seconds. Do not share this code with anyone.",
+		"reset_link":       "Reset your password: %s (expires in %d minutes). Ignore this if you did not request it.",
+		"phone_verify":     "Your phone verification code is %s. It expires in %d seconds. Do not share this code with anyone.",
+	},
+	"id": {
+		"registration_otp": "Kode verifikasi Anda %s. Berlaku %d detik. Jangan berikan kode ini kepada siapa pun.",
+		"reset_link":       "Atur ulang password Anda: %s (berlaku %d menit). Abaikan jika Anda tidak memintanya.",
+		"phone_verify":     "Kode verifikasi nomor telepon Anda %s. Berlaku %d detik. Jangan berikan kode ini kepada siapa pun.",
+	},
+}
+
+// Format menyusun teks pesan untuk locale tertentu, fallback ke bahasa Inggris
+func Format(locale, name string, args ...any) string {
+	localized, ok := messages[locale]
+	if !ok {
+		localized = messages["en"]
+	}
+	format, ok := localized[name]
+	if !ok {
+		format = messages["en"][name]
+	}
+	return fmt.Sprintf(format, args...)
+}
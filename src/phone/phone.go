@@ -0,0 +1,69 @@
+package phone
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+/*
+ALTER TABLE sysuser."user" ADD COLUMN phone character varying(16);
+ALTER TABLE sysuser."user" ADD CONSTRAINT user_unique_phone UNIQUE (phone);
+ALTER TABLE sysuser."user" ADD COLUMN phone_pending character varying(16);
+*/
+
+// Channel pengiriman OTP lewat nomor telepon
+const (
+	ChannelSMS      = "sms"
+	ChannelWhatsApp = "whatsapp"
+)
+
+// Sender adalah transport pengiriman pesan ke nomor telepon
+type Sender interface {
+	Send(channel, to, message string) error
+}
+
+// format E.164: "+" diikuti kode negara (tidak diawali 0) dan total maksimal 15 digit
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// NormalizeE164 membuang spasi, tanda hubung dan kurung lalu memvalidasi format E.164
+func NormalizeE164(number string) (string, error) {
+	number = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "").Replace(strings.TrimSpace(number))
+	if !e164Pattern.MatchString(number) {
+		return "", errors.New("phone number must be in E.164 format, e.g. +6281234567890")
+	}
+	return number, nil
+}
+
+// IsChannel mengecek apakah nilai adalah channel telepon yang didukung
+func IsChannel(channel string) bool {
+	return channel == ChannelSMS || channel == ChannelWhatsApp
+}
+
+var (
+	sender   Sender
+	senderMu sync.RWMutex
+)
+
+// SetSender mengganti transport aktif
+func SetSender(s Sender) {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	sender = s
+}
+
+// Send mengirim pesan lewat transport aktif
+func Send(channel, to, message string) error {
+	senderMu.RLock()
+	s := sender
+	senderMu.RUnlock()
+
+	if s == nil {
+		return errors.New("phone sender is not initialized")
+	}
+	if !IsChannel(channel) {
+		return errors.New("unsupported phone channel " + channel)
+	}
+	return s.Send(channel, to, message)
+}
@@ -0,0 +1,342 @@
+package phone
+
+import (
+	"auth_service/configs"
+	"auth_service/logger"
+	"auth_service/rds"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/*
+Antrian SMS / WhatsApp keluar di Redis, sama seperti antrian email tetapi tanpa dead-letter:
+isi pesan berupa OTP / link reset yang cepat kedaluwarsa, jadi job yang gagal terus cukup dibuang.
+
+	phone_queue                     LIST   id pesan yang siap dikirim
+	phone_processing:<instance_id>  LIST   id yang sedang dikirim oleh satu proses
+	phone_instance:<instance_id>    STRING heartbeat proses, TTL instanceTTL
+	phone_retry                     ZSET   id yang menunggu retry, score = waktu retry (unix ms)
+	phone_job:<id>                  HASH   channel, to, message, status, attempts, last_error, TTL phoneQueueJobTTL
+
+Handler cukup memanggil Enqueue lalu langsung merespon, request tidak menunggu gateway.
+*/
+
+const (
+	queueKey            = "phone_queue"
+	retryKey            = "phone_retry"
+	jobKeyPrefix        = "phone_job:"
+	processingKeyPrefix = "phone_processing:"
+	instanceKeyPrefix   = "phone_instance:"
+	popTimeout          = 5 * time.Second
+	schedulerTick       = time.Second
+	instanceTTL         = 30 * time.Second
+	reclaimInterval     = 30 * time.Second
+)
+
+// Status pesan di antrian
+const (
+	StatusQueued   = "queued"
+	StatusSending  = "sending"
+	StatusRetrying = "retrying"
+	StatusSent     = "sent"
+	StatusFailed   = "failed"
+)
+
+var (
+	queueStarted   bool
+	queueStartedMu sync.Mutex
+	instanceID     = newInstanceID()
+)
+
+func jobKey(id string) string {
+	return jobKeyPrefix + id
+}
+
+// newInstanceID membuat id unik proses: <hostname>-<pid>-<acak>
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(buf))
+}
+
+func processingKey() string {
+	return processingKeyPrefix + instanceID
+}
+
+func instanceKey(id string) string {
+	return instanceKeyPrefix + id
+}
+
+func redisClient() (*redis.Client, error) {
+	client := rds.GetRedisClient()
+	if client == nil {
+		return nil, errors.New("redis client is not initialized")
+	}
+	return client, nil
+}
+
+// Enqueue menyimpan pesan ke antrian dan mengembalikan id untuk log.
+// Transport dan channel dicek di sini supaya handler tetap bisa menolak request bila gateway tidak aktif.
+func Enqueue(channel, to, message string) (string, error) {
+	senderMu.RLock()
+	s := sender
+	senderMu.RUnlock()
+	if s == nil {
+		return "", errors.New("phone sender is not initialized")
+	}
+	if !IsChannel(channel) {
+		return "", errors.New("unsupported phone channel " + channel)
+	}
+
+	client, err := redisClient()
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+
+	ctx := context.Background()
+	now := time.Now().Unix()
+	pipe := client.TxPipeline()
+	pipe.HSet(ctx, jobKey(id), map[string]any{
+		"channel":       channel,
+		"to":            to,
+		"message":       message,
+		"status":        StatusQueued,
+		"attempts":      0,
+		"last_error":    "",
+		"create_tstamp": now,
+		"update_tstamp": now,
+	})
+	pipe.Expire(ctx, jobKey(id), time.Duration(configs.GetPhoneQueueJobTTL())*time.Second)
+	pipe.LPush(ctx, queueKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// StartQueue menjalankan worker pengirim dan scheduler retry sampai ctx selesai
+func StartQueue(ctx context.Context, workers int) error {
+	queueStartedMu.Lock()
+	defer queueStartedMu.Unlock()
+	if queueStarted {
+		return nil
+	}
+
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Set(ctx, instanceKey(instanceID), time.Now().Unix(), instanceTTL).Err(); err != nil {
+		return err
+	}
+	if err := reclaimStale(ctx, client); err != nil {
+		return err
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go runWorker(ctx, i)
+	}
+	go runRetryScheduler(ctx)
+
+	queueStarted = true
+	logger.Info("PhoneQueue", "Started ", workers, " phone queue workers, instance ", instanceID)
+	return nil
+}
+
+// reclaimStale mengembalikan isi processing list milik proses yang heartbeat-nya sudah hilang ke phone_queue
+func reclaimStale(ctx context.Context, client *redis.Client) error {
+	iter := client.Scan(ctx, 0, processingKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		owner := strings.TrimPrefix(key, processingKeyPrefix)
+		if owner == instanceID {
+			continue
+		}
+
+		alive, err := client.Exists(ctx, instanceKey(owner)).Result()
+		if err != nil {
+			return err
+		}
+		if alive > 0 {
+			continue
+		}
+
+		for {
+			id, err := client.LMove(ctx, key, queueKey, "RIGHT", "LEFT").Result()
+			if err == redis.Nil {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			logger.Warning("PhoneQueue", "Recovered in-flight message ", id, " from stale instance ", owner)
+		}
+	}
+	return iter.Err()
+}
+
+func runWorker(ctx context.Context, workerID int) {
+	for ctx.Err() == nil {
+		client, err := redisClient()
+		if err != nil {
+			logger.Error("PhoneQueue", "Worker ", workerID, " - ", err)
+			sleepCtx(ctx, popTimeout)
+			continue
+		}
+
+		id, err := client.BLMove(ctx, queueKey, processingKey(), "RIGHT", "LEFT", popTimeout).Result()
+		if err == redis.Nil || ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			logger.Error("PhoneQueue", "Worker ", workerID, " - Failed to pop queue: ", err)
+			sleepCtx(ctx, popTimeout)
+			continue
+		}
+
+		processJob(ctx, client, id)
+		client.LRem(ctx, processingKey(), 1, id)
+	}
+}
+
+func processJob(ctx context.Context, client *redis.Client, id string) {
+	fields, err := client.HMGet(ctx, jobKey(id), "channel", "to", "message", "attempts").Result()
+	if err != nil || fields[0] == nil {
+		// job sudah kedaluwarsa (phoneQueueJobTTL), isinya tidak berguna lagi
+		logger.Warning("PhoneQueue", "Message ", id, " not found or expired, dropped: ", err)
+		return
+	}
+
+	channel, _ := fields[0].(string)
+	to, _ := fields[1].(string)
+	message, _ := fields[2].(string)
+	attemptsText, _ := fields[3].(string)
+	attempts, _ := strconv.Atoi(attemptsText)
+	attempts++
+
+	client.HSet(ctx, jobKey(id), "status", StatusSending, "attempts", attempts, "update_tstamp", time.Now().Unix())
+
+	err = Send(channel, to, message)
+
+	now := time.Now()
+	status, delay := nextJobState(attempts, err)
+	switch status {
+	case StatusSent:
+		// isi pesan (OTP / link) tidak perlu disimpan lagi, cukup status sampai TTL habis
+		client.HSet(ctx, jobKey(id), "status", StatusSent, "last_error", "", "update_tstamp", now.Unix())
+		client.HDel(ctx, jobKey(id), "message")
+		logger.Info("PhoneQueue", "Message ", id, " sent via ", channel, " (attempt ", attempts, ")")
+
+	case StatusFailed:
+		client.HSet(ctx, jobKey(id), "status", StatusFailed, "last_error", err.Error(), "update_tstamp", now.Unix())
+		client.HDel(ctx, jobKey(id), "message")
+		logger.Error("PhoneQueue", "Message ", id, " dropped after ", attempts, " attempts: ", err)
+
+	default:
+		client.HSet(ctx, jobKey(id), "status", StatusRetrying, "last_error", err.Error(), "update_tstamp", now.Unix())
+		client.ZAdd(ctx, retryKey, redis.Z{Score: float64(now.Add(delay).UnixMilli()), Member: id})
+		logger.Warning("PhoneQueue", "Message ", id, " failed (attempt ", attempts, "), retry in ", delay, ": ", err)
+	}
+}
+
+// nextJobState menentukan status job setelah percobaan ke-attempts: sent, retrying (dengan jeda) atau failed
+func nextJobState(attempts int, sendErr error) (string, time.Duration) {
+	if sendErr == nil {
+		return StatusSent, 0
+	}
+	if attempts >= configs.GetPhoneQueueMaxAttempts() {
+		return StatusFailed, 0
+	}
+	return StatusRetrying, retryBackoff(attempts)
+}
+
+// retryBackoff menghitung jeda exponential: base * 2^(attempts-1), dibatasi max
+func retryBackoff(attempts int) time.Duration {
+	base := time.Duration(configs.GetPhoneQueueBaseBackoff()) * time.Second
+	maxBackoff := time.Duration(configs.GetPhoneQueueMaxBackoff()) * time.Second
+
+	delay := base
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// runRetryScheduler memindahkan job retry yang jatuh tempo ke phone_queue, memperbarui heartbeat proses,
+// dan secara berkala memulihkan processing list proses mati
+func runRetryScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+
+	lastReclaim := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		client, err := redisClient()
+		if err != nil {
+			continue
+		}
+
+		client.Set(ctx, instanceKey(instanceID), time.Now().Unix(), instanceTTL)
+
+		if time.Since(lastReclaim) >= reclaimInterval {
+			lastReclaim = time.Now()
+			if err := reclaimStale(ctx, client); err != nil {
+				logger.Error("PhoneQueue", "Failed to reclaim stale in-flight messages: ", err)
+			}
+		}
+
+		due, err := client.ZRangeByScore(ctx, retryKey, &redis.ZRangeBy{Min: "-inf", Max: fmt.Sprint(time.Now().UnixMilli())}).Result()
+		if err != nil {
+			logger.Error("PhoneQueue", "Retry scheduler failed: ", err)
+			continue
+		}
+
+		for _, id := range due {
+			// ZREM memastikan hanya satu instance yang memindahkan id ini
+			removed, err := client.ZRem(ctx, retryKey, id).Result()
+			if err != nil || removed == 0 {
+				continue
+			}
+			client.LPush(ctx, queueKey, id)
+		}
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,45 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextJobState(t *testing.T) {
+	sendErr := errors.New("gateway returned HTTP 503")
+
+	// default: maksimal 4 percobaan, base 5 detik, maksimal 60 detik
+	tests := []struct {
+		name      string
+		attempts  int
+		err       error
+		wantState string
+		wantDelay time.Duration
+	}{
+		{"sent", 1, nil, StatusSent, 0},
+		{"first failure retries", 1, sendErr, StatusRetrying, 5 * time.Second},
+		{"third failure backs off", 3, sendErr, StatusRetrying, 20 * time.Second},
+		{"last attempt fails", 4, sendErr, StatusFailed, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, delay := nextJobState(tt.attempts, tt.err)
+			if state != tt.wantState || delay != tt.wantDelay {
+				t.Errorf("nextJobState(%d, %v) = %s, %v, want %s, %v", tt.attempts, tt.err, state, delay, tt.wantState, tt.wantDelay)
+			}
+		})
+	}
+
+	if got := retryBackoff(10); got != 60*time.Second {
+		t.Errorf("retryBackoff(10) = %v, want capped at 60s", got)
+	}
+}
+
+func TestEnqueueWithoutSender(t *testing.T) {
+	SetSender(nil)
+	if _, err := Enqueue(ChannelSMS, "+6281234567890", "kode 123456"); err == nil {
+		t.Errorf("Enqueue without sender expected error")
+	}
+}
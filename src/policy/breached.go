@@ -0,0 +1,93 @@
+package policy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+/*
+Lookup password bocor secara offline dengan model k-anonymity (format range HIBP).
+Direktori berisi satu file per prefix SHA-1 5 karakter hex (uppercase), misal:
+
+	<dir>/5BAA6.txt   (atau <dir>/5BAA6)
+
+dengan isi per baris "<SUFFIX 35 char>:<COUNT>":
+
+	1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493
+
+Yang dibaca hanya file milik prefix, password / hash lengkap tidak pernah keluar dari proses.
+*/
+
+const breachedPrefixLength = 5
+
+var (
+	breachedDir   string
+	breachedDirMu sync.RWMutex
+)
+
+// InitBreachedLookup mengatur direktori file prefix SHA-1. Direktori kosong menonaktifkan pengecekan.
+func InitBreachedLookup(dir string) error {
+	breachedDirMu.Lock()
+	defer breachedDirMu.Unlock()
+
+	if dir == "" {
+		breachedDir = ""
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("breached password path is not a directory")
+	}
+
+	breachedDir = dir
+	return nil
+}
+
+// IsBreached mengembalikan true bila hash password ada di file prefix-nya
+func IsBreached(password string) (bool, error) {
+	breachedDirMu.RLock()
+	dir := breachedDir
+	breachedDirMu.RUnlock()
+
+	if dir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+
+	file, err := os.Open(filepath.Join(dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(filepath.Join(dir, prefix))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		// tidak ada file untuk prefix ini berarti tidak ada hash yang bocor
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, _, _ := strings.Cut(line, ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
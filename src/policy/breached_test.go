@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	passwordPrefix = "5BAA6"
+	passwordSuffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func setBreachedDir(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := InitBreachedLookup(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitBreachedLookup("") })
+}
+
+func TestIsBreached(t *testing.T) {
+	otherSuffix := "0000000000000000000000000000000000A"
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		password string
+		want     bool
+	}{
+		{"suffix in .txt file", map[string]string{passwordPrefix + ".txt": otherSuffix + ":1\n" + passwordSuffix + ":3861493\n"}, "password", true},
+		{"suffix in file without extension", map[string]string{passwordPrefix: passwordSuffix + ":10\r\n"}, "password", true},
+		{"lowercase suffix", map[string]string{passwordPrefix + ".txt": strings.ToLower(passwordSuffix) + ":10"}, "password", true},
+		{"suffix without count", map[string]string{passwordPrefix + ".txt": passwordSuffix}, "password", true},
+		{"prefix file without suffix", map[string]string{passwordPrefix + ".txt": otherSuffix + ":1"}, "password", false},
+		{"no file for prefix", map[string]string{"00000.txt": passwordSuffix + ":1"}, "password", false},
+		// suffix yang sama di file prefix lain tidak boleh cocok
+		{"suffix under other prefix", map[string]string{"5BAA7.txt": passwordSuffix + ":1"}, "password", false},
+		{"other password same file", map[string]string{passwordPrefix + ".txt": passwordSuffix + ":1"}, "Password", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setBreachedDir(t, tt.files)
+			got, err := IsBreached(tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBreached(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBreachedDisabled(t *testing.T) {
+	if err := InitBreachedLookup(""); err != nil {
+		t.Fatal(err)
+	}
+	if breached, err := IsBreached("password"); breached || err != nil {
+		t.Errorf("IsBreached with lookup disabled = %v (%v), want false", breached, err)
+	}
+}
+
+func TestInitBreachedLookupErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { InitBreachedLookup("") })
+
+	for _, dir := range []string{file, filepath.Join(t.TempDir(), "missing")} {
+		if err := InitBreachedLookup(dir); err == nil {
+			t.Errorf("InitBreachedLookup(%q) expected error", dir)
+		}
+	}
+}
+
+func TestValidateBreached(t *testing.T) {
+	// SHA-1("password123") = CBFDAC6008F9CAB4083784CBD1874F76618D2A97
+	setBreachedDir(t, map[string]string{"CBFDA.txt": "C6008F9CAB4083784CBD1874F76618D2A97:2"})
+
+	violations := Validate("test", "password123", Subject{})
+	if len(violations) != 1 || violations[0] != Breached {
+		t.Errorf("Validate(breached) = %v, want [%s]", violations, Breached)
+	}
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"crypto/subtle"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+/*
+CREATE TABLE sysuser.password_history (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES sysuser."user"(id) ON DELETE CASCADE,
+    salt character varying(64) NOT NULL,
+    saltedpassword character varying(128) NOT NULL,
+    tstamp bigint NOT NULL
+);
+CREATE INDEX password_history_user_id_idx ON sysuser.password_history (user_id, tstamp DESC);
+*/
+
+type historyEntry struct {
+	Salt           string `db:"salt"`
+	SaltedPassword string `db:"saltedpassword"`
+}
+
+// IsReused mengecek apakah password sama dengan password saat ini
+// atau salah satu dari N hash terakhir milik user
+func IsReused(conn *sqlx.DB, userID int64, password string) (bool, error) {
+	var entries []historyEntry
+	query := `
+		SELECT salt, saltedpassword FROM sysuser."user" WHERE id = $1
+		UNION ALL
+		(SELECT salt, saltedpassword FROM sysuser.password_history
+		 WHERE user_id = $1 ORDER BY tstamp DESC, id DESC LIMIT $2)`
+	if err := conn.Select(&entries, query, userID, configs.GetPasswordHistorySize()); err != nil {
+		return false, err
+	}
+	return matchesHistory(password, entries)
+}
+
+// matchesHistory membandingkan password dengan setiap hash PBKDF2 (salt masing-masing entry)
+func matchesHistory(password string, entries []historyEntry) (bool, error) {
+	for _, entry := range entries {
+		hashed, err := crypto.GeneratePBKDF2(password, entry.Salt, 32, configs.GetPBKDF2Iterations())
+		if err != nil {
+			return false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(hashed), []byte(entry.SaltedPassword)) == 1 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RecordHistory menyimpan hash password baru dan membuang entry di luar N terakhir
+func RecordHistory(conn *sqlx.DB, userID int64, salt, saltedPassword string) error {
+	queryInsert := `INSERT INTO sysuser.password_history (user_id, salt, saltedpassword, tstamp) VALUES ($1, $2, $3, $4)`
+	if _, err := conn.Exec(queryInsert, userID, salt, saltedPassword, time.Now().Unix()); err != nil {
+		return err
+	}
+
+	queryPrune := `
+		DELETE FROM sysuser.password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM sysuser.password_history
+			WHERE user_id = $1 ORDER BY tstamp DESC, id DESC LIMIT $2)`
+	_, err := conn.Exec(queryPrune, userID, configs.GetPasswordHistorySize())
+	return err
+}
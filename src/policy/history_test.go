@@ -0,0 +1,51 @@
+package policy
+
+import (
+	"auth_service/configs"
+	"auth_service/crypto"
+	"testing"
+)
+
+func historyEntryFor(t *testing.T, password, salt string) historyEntry {
+	t.Helper()
+	hashed, err := crypto.GeneratePBKDF2(password, salt, 32, configs.GetPBKDF2Iterations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return historyEntry{Salt: salt, SaltedPassword: hashed}
+}
+
+func TestMatchesHistory(t *testing.T) {
+	current := historyEntryFor(t, "current pass 1", "salt-current")
+	older := historyEntryFor(t, "older pass 2", "salt-older")
+	entries := []historyEntry{current, older}
+
+	tests := []struct {
+		name     string
+		password string
+		entries  []historyEntry
+		want     bool
+		wantErr  bool
+	}{
+		{"current password", "current pass 1", entries, true, false},
+		{"password from history", "older pass 2", entries, true, false},
+		{"new password", "brand new pass 3", entries, false, false},
+		{"case differs", "Current pass 1", entries, false, false},
+		// hash yang sama dengan salt lain tidak boleh dianggap cocok
+		{"hash under other salt", "older pass 2", []historyEntry{{Salt: "salt-current", SaltedPassword: older.SaltedPassword}}, false, false},
+		{"no history", "current pass 1", nil, false, false},
+		{"entry without salt", "current pass 1", []historyEntry{{SaltedPassword: current.SaltedPassword}}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchesHistory(tt.password, tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("matchesHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("matchesHistory(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package policy
+
+import (
+	"auth_service/configs"
+	"auth_service/logger"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kode pelanggaran yang dikirim ke client di payload "violations"
+const (
+	TooShort         = "too_short"
+	TooLong          = "too_long"
+	MissingLowercase = "missing_lowercase"
+	MissingUppercase = "missing_uppercase"
+	MissingDigit     = "missing_digit"
+	MissingSymbol    = "missing_symbol"
+	ContainsUsername = "contains_username"
+	ContainsEmail    = "contains_email"
+	ContainsFullName = "contains_full_name"
+	Breached         = "breached"
+	Reused           = "reused"
+)
+
+// minimal panjang bagian identitas yang dicek sebagai substring,
+// supaya potongan pendek (misal "a" dari nama) tidak memblokir semua password
+const minIdentityPartLength = 3
+
+// Subject berisi identitas pemilik password untuk pengecekan substring
+type Subject struct {
+	Username string
+	Email    string
+	FullName string
+}
+
+// Validate memeriksa password terhadap policy dan mengembalikan daftar kode pelanggaran.
+// Slice kosong berarti password lolos.
+func Validate(referenceID, password string, subject Subject) []string {
+	violations := []string{}
+
+	length := utf8.RuneCountInString(password)
+	if length < configs.GetPasswordMinLength() {
+		violations = append(violations, TooShort)
+	}
+	if length > configs.GetPasswordMaxLength() {
+		violations = append(violations, TooLong)
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, ch := range password {
+		switch {
+		case unicode.IsLower(ch):
+			hasLower = true
+		case unicode.IsUpper(ch):
+			hasUpper = true
+		case unicode.IsDigit(ch):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	if configs.GetPasswordRequireLower() && !hasLower {
+		violations = append(violations, MissingLowercase)
+	}
+	if configs.GetPasswordRequireUpper() && !hasUpper {
+		violations = append(violations, MissingUppercase)
+	}
+	if configs.GetPasswordRequireDigit() && !hasDigit {
+		violations = append(violations, MissingDigit)
+	}
+	if configs.GetPasswordRequireSymbol() && !hasSymbol {
+		violations = append(violations, MissingSymbol)
+	}
+
+	lowered := strings.ToLower(password)
+
+	if containsPart(lowered, subject.Username) {
+		violations = append(violations, ContainsUsername)
+	}
+
+	// cukup cek local-part, domain email biasanya umum (gmail, dll)
+	localPart, _, _ := strings.Cut(subject.Email, "@")
+	if containsPart(lowered, localPart) {
+		violations = append(violations, ContainsEmail)
+	}
+
+	for _, namePart := range strings.Fields(subject.FullName) {
+		if containsPart(lowered, namePart) {
+			violations = append(violations, ContainsFullName)
+			break
+		}
+	}
+
+	breached, err := IsBreached(password)
+	if err != nil {
+		// lookup offline gagal tidak boleh memblokir user, cukup dicatat
+		logger.Warning(referenceID, "WARNING - Policy - Breached password lookup failed: ", err)
+	} else if breached {
+		violations = append(violations, Breached)
+	}
+
+	return violations
+}
+
+func containsPart(loweredPassword, part string) bool {
+	part = strings.ToLower(strings.TrimSpace(part))
+	if utf8.RuneCountInString(part) < minIdentityPartLength {
+		return false
+	}
+	return strings.Contains(loweredPassword, part)
+}
@@ -0,0 +1,61 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// policy default: 8 - 64 karakter, wajib huruf kecil dan angka
+	subject := Subject{Username: "jdoe", Email: "john.doe@example.com", FullName: "Jane Al Smith"}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"valid", "correct horse 42", []string{}},
+		{"valid with symbols and upper", "Tr0ub4dor&3x", []string{}},
+		{"too short", "ab1", []string{TooShort}},
+		{"too short counts runes", "äöü1", []string{TooShort}},
+		{"too long", "a1" + string(make([]byte, 63)), []string{TooLong}},
+		{"missing lowercase", "12345678AB", []string{MissingLowercase}},
+		{"missing digit", "no digits here", []string{MissingDigit}},
+		{"empty", "", []string{TooShort, MissingLowercase, MissingDigit}},
+		{"contains username", "xJDOEx12345", []string{ContainsUsername}},
+		{"contains email local part", "john.doe2024", []string{ContainsEmail}},
+		{"contains full name part", "blacksmith99", []string{ContainsFullName}},
+		// bagian nama lebih pendek dari 3 karakter tidak dicek
+		{"short name part ignored", "always1234", []string{}},
+		{"multiple violations", "JDOE", []string{TooShort, MissingLowercase, MissingDigit, ContainsUsername}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Validate("test", tt.password, subject)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsPart(t *testing.T) {
+	tests := []struct {
+		password string
+		part     string
+		want     bool
+	}{
+		{"xjdoex", "JDoe", true},
+		{"xjdoex", "  jdoe ", true},
+		{"xjdoex", "jd", false},
+		{"xjdoex", "", false},
+		{"xjdoex", "smith", false},
+	}
+
+	for _, tt := range tests {
+		if got := containsPart(tt.password, tt.part); got != tt.want {
+			t.Errorf("containsPart(%q, %q) = %v, want %v", tt.password, tt.part, got, tt.want)
+		}
+	}
+}
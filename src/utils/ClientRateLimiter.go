@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"auth_service/logger"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClientRateLimiter membatasi jumlah request per client dalam satu window (fixed window di Redis).
+// Mengembalikan sisa waktu window bila limit terlampaui.
+func ClientRateLimiter(redisClient *redis.Client, referenceID, clientID string, limit int64, window time.Duration) (time.Duration, error) {
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - ClientRateLimiter - Redis client is not initialized")
+		return 0, fmt.Errorf("internal server error: Redis client is not initialized")
+	}
+
+	redisKey := fmt.Sprintf("client_limit:%s", clientID)
+	ctx := context.Background()
+
+	count, err := redisClient.Incr(ctx, redisKey).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - ClientRateLimiter - Failed to increment counter: ", err)
+		return 0, fmt.Errorf("internal server error")
+	}
+
+	// window dimulai pada request pertama
+	if count == 1 {
+		if err := redisClient.Expire(ctx, redisKey, window).Err(); err != nil {
+			logger.Error(referenceID, "ERROR - ClientRateLimiter - Failed to set window expiry: ", err)
+			return 0, fmt.Errorf("internal server error")
+		}
+	}
+
+	if count > limit {
+		ttl, err := redisClient.TTL(ctx, redisKey).Result()
+		if err != nil || ttl < 0 {
+			// key tanpa expiry (Expire sebelumnya gagal), pasang ulang supaya client tidak terkunci selamanya
+			redisClient.Expire(ctx, redisKey, window)
+			ttl = window
+		}
+		logger.Error(referenceID, fmt.Sprintf("ERROR - ClientRateLimiter - Rate limit exceeded for client: %s (%d/%d), TTL remaining: %v", clientID, count, limit, ttl))
+		return ttl, fmt.Errorf("too many requests")
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"auth_service/logger"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// UnitRateLimiter membatasi jumlah data (bukan jumlah request) per device dalam satu window (fixed window di Redis).
+// amount yang ditolak tidak dihitung, sehingga device masih bisa mengirim batch yang lebih kecil.
+// Mengembalikan sisa waktu window bila limit terlampaui.
+func UnitRateLimiter(redisClient *redis.Client, referenceID string, unitID int64, amount, limit int64, window time.Duration) (time.Duration, error) {
+	if redisClient == nil {
+		logger.Error(referenceID, "ERROR - UnitRateLimiter - Redis client is not initialized")
+		return 0, fmt.Errorf("internal server error: Redis client is not initialized")
+	}
+
+	redisKey := fmt.Sprintf("unit_limit:%d", unitID)
+	ctx := context.Background()
+
+	count, err := redisClient.IncrBy(ctx, redisKey, amount).Result()
+	if err != nil {
+		logger.Error(referenceID, "ERROR - UnitRateLimiter - Failed to increment counter: ", err)
+		return 0, fmt.Errorf("internal server error")
+	}
+
+	// window dimulai pada batch pertama
+	if count == amount {
+		if err := redisClient.Expire(ctx, redisKey, window).Err(); err != nil {
+			logger.Error(referenceID, "ERROR - UnitRateLimiter - Failed to set window expiry: ", err)
+			return 0, fmt.Errorf("internal server error")
+		}
+	}
+
+	if count > limit {
+		redisClient.DecrBy(ctx, redisKey, amount)
+		ttl, err := redisClient.TTL(ctx, redisKey).Result()
+		if err != nil || ttl < 0 {
+			// key tanpa expiry (Expire sebelumnya gagal), pasang ulang supaya device tidak terkunci selamanya
+			redisClient.Expire(ctx, redisKey, window)
+			ttl = window
+		}
+		logger.Error(referenceID, fmt.Sprintf("ERROR - UnitRateLimiter - Rate limit exceeded for unit: %d (%d/%d), TTL remaining: %v", unitID, count, limit, ttl))
+		return ttl, fmt.Errorf("too many readings")
+	}
+
+	return 0, nil
+}
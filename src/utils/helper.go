@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 )
 
 func JSONencode(data any) (string, error) {
@@ -110,3 +111,31 @@ func Request(r *http.Request) (map[string]any, error) {
 
 	return data, nil
 }
+
+// PadResponseTime menahan response sampai minimal minDuration sejak startTime,
+// supaya waktu respon tidak membedakan akun yang ada dan yang tidak ada
+func PadResponseTime(startTime time.Time, minDuration time.Duration) {
+	if remaining := minDuration - time.Since(startTime); remaining > 0 {
+		time.Sleep(remaining)
+	}
+}
+
+// MaskEmail menyamarkan local-part email, contoh: "johndoe@mail.com" -> "j*****e@mail.com"
+func MaskEmail(email string) string {
+	localPart, domain, found := strings.Cut(email, "@")
+	if !found {
+		return strings.Repeat("*", len(email))
+	}
+
+	runes := []rune(localPart)
+	switch {
+	case len(runes) <= 1:
+		localPart = "*"
+	case len(runes) == 2:
+		localPart = string(runes[0]) + "*"
+	default:
+		localPart = string(runes[0]) + strings.Repeat("*", len(runes)-2) + string(runes[len(runes)-1])
+	}
+
+	return localPart + "@" + domain
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/base64"
 	"errors"
 	"math/rand"
 	"strconv"
@@ -45,3 +47,18 @@ func RandoNnumberGenerator(length int) (int, error) {
 
 	return number, nil // Mengembalikan hasil dalam bentuk integer
 }
+
+// SecureTokenGenerator menghasilkan token acak (crypto/rand) sepanjang byteLength byte,
+// di-encode base64url tanpa padding supaya aman dipakai di URL
+func SecureTokenGenerator(byteLength int) (string, error) {
+	if byteLength <= 0 {
+		return "", errors.New("length must be greater than 0")
+	}
+
+	buf := make([]byte, byteLength)
+	if _, err := cryptorand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,145 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+)
+
+// Format attestation yang diterima
+const (
+	AttestationNone   = "none"
+	AttestationPacked = "packed"
+)
+
+var ErrUnsupportedAttestation = errors.New("webauthn: unsupported attestation format")
+
+// id-fido-gen-ce-aaguid
+var oidFIDOAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+type attestationObject struct {
+	Format       string
+	AttStmt      map[any]any
+	AuthDataRaw  []byte
+	AuthData     *AuthenticatorData
+	CredentialPK *PublicKey
+}
+
+func parseAttestationObject(raw []byte) (*attestationObject, error) {
+	value, n, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(raw) {
+		return nil, errors.New("webauthn: trailing data after attestation object")
+	}
+	m, ok := value.(map[any]any)
+	if !ok {
+		return nil, errors.New("webauthn: attestation object is not a map")
+	}
+
+	obj := &attestationObject{}
+	obj.Format, _ = m["fmt"].(string)
+	obj.AttStmt, _ = m["attStmt"].(map[any]any)
+	obj.AuthDataRaw, _ = m["authData"].([]byte)
+	if obj.Format == "" || obj.AttStmt == nil || obj.AuthDataRaw == nil {
+		return nil, errors.New("webauthn: incomplete attestation object")
+	}
+
+	obj.AuthData, err = ParseAuthenticatorData(obj.AuthDataRaw)
+	if err != nil {
+		return nil, err
+	}
+	if obj.AuthData.CredentialPublicKey == nil {
+		return nil, errors.New("webauthn: attestation without credential data")
+	}
+
+	obj.CredentialPK, err = ParsePublicKey(obj.AuthData.CredentialPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// verifyStatement memeriksa attestation statement terhadap authData + hash clientData.
+// Rantai sertifikat packed tidak dicocokkan ke root metadata (FIDO MDS), sehingga
+// attestation hanya menjamin konsistensi, bukan merek/model authenticator.
+func (obj *attestationObject) verifyStatement(clientDataHash []byte) error {
+	switch obj.Format {
+	case AttestationNone:
+		if len(obj.AttStmt) != 0 {
+			return errors.New("webauthn: none attestation with non-empty statement")
+		}
+		return nil
+	case AttestationPacked:
+		return obj.verifyPacked(clientDataHash)
+	}
+	return ErrUnsupportedAttestation
+}
+
+func (obj *attestationObject) verifyPacked(clientDataHash []byte) error {
+	alg, ok := obj.AttStmt["alg"].(int64)
+	if !ok {
+		return errors.New("webauthn: packed attestation without alg")
+	}
+	sig, ok := obj.AttStmt["sig"].([]byte)
+	if !ok {
+		return errors.New("webauthn: packed attestation without sig")
+	}
+	signedData := append(append([]byte(nil), obj.AuthDataRaw...), clientDataHash...)
+
+	x5c, hasX5C := obj.AttStmt["x5c"].([]any)
+	if !hasX5C {
+		// self attestation, ditandatangani credential key itu sendiri
+		if alg != obj.CredentialPK.Algorithm {
+			return errors.New("webauthn: self attestation algorithm mismatch")
+		}
+		return obj.CredentialPK.Verify(signedData, sig)
+	}
+
+	if len(x5c) == 0 {
+		return errors.New("webauthn: empty x5c")
+	}
+	certDER, ok := x5c[0].([]byte)
+	if !ok {
+		return errors.New("webauthn: invalid x5c entry")
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return err
+	}
+
+	if err := verifySignature(alg, cert.PublicKey, signedData, sig); err != nil {
+		return err
+	}
+
+	// persyaratan sertifikat packed (WebAuthn §8.2.1)
+	if cert.Version != 3 {
+		return errors.New("webauthn: attestation certificate must be version 3")
+	}
+	if cert.IsCA {
+		return errors.New("webauthn: attestation certificate must not be a CA")
+	}
+	if len(cert.Subject.OrganizationalUnit) != 1 || cert.Subject.OrganizationalUnit[0] != "Authenticator Attestation" {
+		return errors.New("webauthn: invalid attestation certificate subject")
+	}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidFIDOAAGUID) {
+			continue
+		}
+		if ext.Critical {
+			return errors.New("webauthn: AAGUID extension must not be critical")
+		}
+		var aaguid []byte
+		if _, err := asn1.Unmarshal(ext.Value, &aaguid); err != nil {
+			return err
+		}
+		if !bytes.Equal(aaguid, obj.AuthData.AAGUID) {
+			return errors.New("webauthn: AAGUID mismatch between certificate and authenticator data")
+		}
+	}
+
+	return nil
+}
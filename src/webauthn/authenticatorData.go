@@ -0,0 +1,118 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+// Flag authenticator data (WebAuthn Level 2, §6.1)
+const (
+	FlagUserPresent            byte = 0x01
+	FlagUserVerified           byte = 0x04
+	FlagAttestedCredentialData byte = 0x40
+	FlagExtensionData          byte = 0x80
+)
+
+const (
+	rpIDHashLength  = 32
+	aaguidLength    = 16
+	authDataMinSize = rpIDHashLength + 1 + 4
+	maxCredentialID = 1023
+)
+
+// AuthenticatorData hasil parse authenticatorData
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// hanya terisi bila FlagAttestedCredentialData di-set (saat registrasi)
+	AAGUID              []byte
+	CredentialID        []byte
+	CredentialPublicKey []byte // COSE key mentah
+}
+
+func (a *AuthenticatorData) UserPresent() bool {
+	return a.Flags&FlagUserPresent != 0
+}
+
+func (a *AuthenticatorData) UserVerified() bool {
+	return a.Flags&FlagUserVerified != 0
+}
+
+// ParseAuthenticatorData membaca struktur biner authenticatorData
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < authDataMinSize {
+		return nil, errors.New("webauthn: authenticator data too short")
+	}
+
+	authData := &AuthenticatorData{
+		RPIDHash:  data[:rpIDHashLength],
+		Flags:     data[rpIDHashLength],
+		SignCount: binary.BigEndian.Uint32(data[rpIDHashLength+1 : authDataMinSize]),
+	}
+	rest := data[authDataMinSize:]
+
+	if authData.Flags&FlagAttestedCredentialData != 0 {
+		if len(rest) < aaguidLength+2 {
+			return nil, errors.New("webauthn: attested credential data too short")
+		}
+		authData.AAGUID = rest[:aaguidLength]
+		idLength := int(binary.BigEndian.Uint16(rest[aaguidLength : aaguidLength+2]))
+		rest = rest[aaguidLength+2:]
+		if idLength == 0 || idLength > maxCredentialID || len(rest) < idLength {
+			return nil, errors.New("webauthn: invalid credential ID length")
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		authData.CredentialPublicKey = rest[:n]
+		rest = rest[n:]
+	}
+
+	if authData.Flags&FlagExtensionData != 0 {
+		// isi extension tidak dipakai, cukup pastikan formatnya valid
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		rest = rest[n:]
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New("webauthn: trailing data in authenticator data")
+	}
+
+	return authData, nil
+}
+
+// CollectedClientData adalah isi clientDataJSON yang dikirim browser
+type CollectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+const (
+	ClientDataTypeCreate = "webauthn.create"
+	ClientDataTypeGet    = "webauthn.get"
+)
+
+// ParseClientData membaca clientDataJSON. Challenge bisa diambil dari sini untuk
+// mencari state ceremony sebelum verifikasi lengkap.
+func ParseClientData(clientDataJSON []byte) (*CollectedClientData, error) {
+	var clientData CollectedClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, errors.New("webauthn: invalid client data JSON")
+	}
+	if clientData.Challenge == "" {
+		return nil, errors.New("webauthn: missing challenge in client data")
+	}
+	return &clientData, nil
+}
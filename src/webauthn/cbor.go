@@ -0,0 +1,229 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Decoder CBOR (RFC 8949) minimal, cukup untuk attestation object dan COSE key.
+// Hasil decode:
+//	unsigned / negative int -> int64
+//	byte string             -> []byte
+//	text string             -> string
+//	array                   -> []any
+//	map                     -> map[any]any (key int64 atau string)
+//	true/false              -> bool
+//	null/undefined          -> nil
+//	float                   -> float64
+// Panjang indefinite tidak didukung (tidak dipakai authenticator).
+
+const cborMaxDepth = 16
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decodeCBOR men-decode satu item dan mengembalikan jumlah byte yang terpakai,
+// authenticator data menyimpan COSE key di tengah buffer sehingga panjangnya harus diketahui
+func decodeCBOR(data []byte) (any, int, error) {
+	d := &cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, d.pos, nil
+}
+
+func (d *cborDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errCBORTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *cborDecoder) readBytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errCBORTruncated
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// readArgument membaca argumen (panjang / nilai) sesuai additional info
+func (d *cborDecoder) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := d.readByte()
+		return uint64(b), err
+	case info == 25:
+		b, err := d.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := d.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	}
+	return 0, errors.New("cbor: indefinite length or reserved additional info is not supported")
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > cborMaxDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+
+	initial, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	major := initial >> 5
+	info := initial & 0x1f
+
+	// major type 7 punya arti khusus untuk additional info, ditangani sebelum readArgument
+	if major == 7 {
+		return d.decodeSimple(info)
+	}
+
+	arg, err := d.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), nil
+	case 2:
+		b, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 3:
+		b, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case 4:
+		// setiap elemen minimal 1 byte, batasi alokasi dari panjang yang tidak masuk akal
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errCBORTruncated
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, errCBORTruncated
+		}
+		m := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, errors.New("cbor: unsupported map key type")
+			}
+			if _, exists := m[key]; exists {
+				return nil, errors.New("cbor: duplicate map key")
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case 6:
+		// tag diabaikan, yang dipakai hanya isinya
+		return d.decode(depth + 1)
+	}
+
+	return nil, errors.New("cbor: invalid major type")
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := d.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return float16To64(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+	return nil, errors.New("cbor: unsupported simple value")
+}
+
+func float16To64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1.0
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 31:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(frac+1024, exp-25)
+}
@@ -0,0 +1,207 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// cborPair adalah satu entry map untuk encodeCBOR, urutan dipertahankan supaya hasil encode deterministik
+type cborPair struct {
+	key   any
+	value any
+}
+
+// encodeCBOR adalah encoder minimal untuk menyusun fixture test (bukan bagian dari kode produksi)
+func encodeCBOR(value any) []byte {
+	var buf bytes.Buffer
+	writeHead := func(major byte, arg uint64) {
+		switch {
+		case arg < 24:
+			buf.WriteByte(major<<5 | byte(arg))
+		case arg <= math.MaxUint8:
+			buf.Write([]byte{major<<5 | 24, byte(arg)})
+		case arg <= math.MaxUint16:
+			buf.Write([]byte{major<<5 | 25, byte(arg >> 8), byte(arg)})
+		default:
+			buf.Write([]byte{major<<5 | 26, byte(arg >> 24), byte(arg >> 16), byte(arg >> 8), byte(arg)})
+		}
+	}
+
+	switch v := value.(type) {
+	case int:
+		return encodeCBOR(int64(v))
+	case int64:
+		if v >= 0 {
+			writeHead(0, uint64(v))
+		} else {
+			writeHead(1, uint64(-1-v))
+		}
+	case []byte:
+		writeHead(2, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(3, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		writeHead(4, uint64(len(v)))
+		for _, item := range v {
+			buf.Write(encodeCBOR(item))
+		}
+	case []cborPair:
+		writeHead(5, uint64(len(v)))
+		for _, pair := range v {
+			buf.Write(encodeCBOR(pair.key))
+			buf.Write(encodeCBOR(pair.value))
+		}
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case nil:
+		buf.WriteByte(0xf6)
+	default:
+		panic("encodeCBOR: unsupported type")
+	}
+	return buf.Bytes()
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	// vektor dari RFC 8949 Appendix A
+	tests := []struct {
+		name string
+		hex  string
+		want any
+	}{
+		{"zero", "00", int64(0)},
+		{"small uint", "17", int64(23)},
+		{"uint8", "1818", int64(24)},
+		{"uint16", "190100", int64(256)},
+		{"uint32", "1a000f4240", int64(1000000)},
+		{"uint64", "1b000000e8d4a51000", int64(1000000000000)},
+		{"negative", "20", int64(-1)},
+		{"negative uint16", "3903e7", int64(-1000)},
+		{"byte string", "4401020304", []byte{1, 2, 3, 4}},
+		{"text string", "6449455446", "IETF"},
+		{"utf-8 text", "62c3bc", "ü"},
+		{"array", "83010203", []any{int64(1), int64(2), int64(3)}},
+		{"nested array", "8301820203820405", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"map int keys", "a201020304", map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{"map text keys", "a26161016162820203", map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{"false", "f4", false},
+		{"true", "f5", true},
+		{"null", "f6", nil},
+		{"float16", "f93c00", 1.0},
+		{"float16 negative", "f9c400", -4.0},
+		{"float16 subnormal", "f90001", 5.960464477539063e-8},
+		{"float32", "fa47c35000", 100000.0},
+		{"float64", "fb3ff199999999999a", 1.1},
+		{"tag ignored", "c11a514b67b0", int64(1363896240)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := mustHex(t, tt.hex)
+			got, n, err := decodeCBOR(data)
+			if err != nil {
+				t.Fatalf("decodeCBOR(%s): %v", tt.hex, err)
+			}
+			if n != len(data) {
+				t.Errorf("consumed %d bytes, want %d", n, len(data))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCBOR(%s) = %#v, want %#v", tt.hex, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORInfinity(t *testing.T) {
+	got, _, err := decodeCBOR(mustHex(t, "f97c00"))
+	if err != nil || got != math.Inf(1) {
+		t.Errorf("decodeCBOR(f97c00) = %v (%v), want +Inf", got, err)
+	}
+	got, _, err = decodeCBOR(mustHex(t, "f97e00"))
+	if f, ok := got.(float64); err != nil || !ok || !math.IsNaN(f) {
+		t.Errorf("decodeCBOR(f97e00) = %v (%v), want NaN", got, err)
+	}
+}
+
+func TestDecodeCBORTrailingData(t *testing.T) {
+	// decodeCBOR hanya membaca satu item, sisa buffer dibiarkan untuk pemanggil
+	got, n, err := decodeCBOR(mustHex(t, "0102"))
+	if err != nil || got != int64(1) || n != 1 {
+		t.Errorf("decodeCBOR(0102) = %v, %d (%v), want 1, 1", got, n, err)
+	}
+}
+
+func TestDecodeCBORErrors(t *testing.T) {
+	deep := bytes.Repeat([]byte{0x81}, cborMaxDepth+2)
+	deep = append(deep, 0x00)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated uint16", mustHex(t, "19")},
+		{"truncated byte string", mustHex(t, "4401")},
+		{"truncated text string", mustHex(t, "6449")},
+		{"truncated array", mustHex(t, "8301")},
+		{"truncated map", mustHex(t, "a201")},
+		{"huge array length", mustHex(t, "9bffffffffffffffff")},
+		{"huge map length", mustHex(t, "bb7fffffffffffffff")},
+		{"indefinite byte string", mustHex(t, "5f")},
+		{"indefinite array", mustHex(t, "9f01ff")},
+		{"reserved additional info", mustHex(t, "1c")},
+		{"integer overflow", mustHex(t, "1b8000000000000000")},
+		{"negative overflow", mustHex(t, "3b8000000000000000")},
+		{"array map key", mustHex(t, "a1810101")},
+		{"duplicate map key", mustHex(t, "a201020103")},
+		{"unsupported simple value", mustHex(t, "f0")},
+		{"nesting too deep", deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _, err := decodeCBOR(tt.data); err == nil {
+				t.Errorf("decodeCBOR(%x) = %#v, want error", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestEncodeCBORRoundTrip(t *testing.T) {
+	value := []cborPair{
+		{int64(1), int64(2)},
+		{int64(-1), []byte{0xde, 0xad}},
+		{"fmt", "none"},
+		{"list", []any{int64(300), true, nil}},
+	}
+	got, n, err := decodeCBOR(encodeCBOR(value))
+	if err != nil {
+		t.Fatalf("decodeCBOR: %v", err)
+	}
+	want := map[any]any{
+		int64(1):  int64(2),
+		int64(-1): []byte{0xde, 0xad},
+		"fmt":     "none",
+		"list":    []any{int64(300), true, nil},
+	}
+	if !reflect.DeepEqual(got, want) || n != len(encodeCBOR(value)) {
+		t.Errorf("round trip = %#v, want %#v", got, want)
+	}
+}
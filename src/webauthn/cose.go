@@ -0,0 +1,139 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// Algoritma COSE (RFC 9053) yang didukung
+const (
+	AlgES256 int64 = -7
+	AlgRS256 int64 = -257
+)
+
+// label COSE key
+const (
+	coseKeyType   int64 = 1
+	coseAlgorithm int64 = 3
+	coseEC2Curve  int64 = -1
+	coseEC2X      int64 = -2
+	coseEC2Y      int64 = -3
+	coseRSAN      int64 = -1
+	coseRSAE      int64 = -2
+
+	coseKeyTypeEC2 int64 = 2
+	coseKeyTypeRSA int64 = 3
+	coseCurveP256  int64 = 1
+
+	minRSAKeyBits = 2048
+)
+
+var ErrUnsupportedAlgorithm = errors.New("webauthn: unsupported public key algorithm")
+
+// PublicKey adalah credential public key hasil parse COSE key
+type PublicKey struct {
+	Algorithm int64
+	key       crypto.PublicKey
+}
+
+// ParsePublicKey membaca COSE key (format yang disimpan di tabel credential)
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	value, n, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(coseKey) {
+		return nil, errors.New("webauthn: trailing data after COSE key")
+	}
+	m, ok := value.(map[any]any)
+	if !ok {
+		return nil, errors.New("webauthn: COSE key is not a map")
+	}
+	return publicKeyFromMap(m)
+}
+
+func publicKeyFromMap(m map[any]any) (*PublicKey, error) {
+	kty, _ := m[coseKeyType].(int64)
+	alg, _ := m[coseAlgorithm].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgES256:
+		crv, _ := m[coseEC2Curve].(int64)
+		x, _ := m[coseEC2X].([]byte)
+		y, _ := m[coseEC2Y].([]byte)
+		if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("webauthn: invalid EC2 P-256 key")
+		}
+
+		// validasi titik ada di kurva lewat crypto/ecdh sebelum dipakai ecdsa
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, errors.New("webauthn: EC2 point is not on curve")
+		}
+
+		return &PublicKey{
+			Algorithm: alg,
+			key: &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			},
+		}, nil
+
+	case kty == coseKeyTypeRSA && alg == AlgRS256:
+		n, _ := m[coseRSAN].([]byte)
+		e, _ := m[coseRSAE].([]byte)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("webauthn: invalid RSA key")
+		}
+
+		exponent := int(new(big.Int).SetBytes(e).Int64())
+		modulus := new(big.Int).SetBytes(n)
+		if modulus.BitLen() < minRSAKeyBits || exponent < 3 || exponent%2 == 0 {
+			return nil, errors.New("webauthn: weak RSA key")
+		}
+
+		return &PublicKey{Algorithm: alg, key: &rsa.PublicKey{N: modulus, E: exponent}}, nil
+	}
+
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// Verify memeriksa signature atas data sesuai algoritma key
+func (k *PublicKey) Verify(data, signature []byte) error {
+	return verifySignature(k.Algorithm, k.key, data, signature)
+}
+
+func verifySignature(alg int64, key crypto.PublicKey, data, signature []byte) error {
+	digest := sha256.Sum256(data)
+
+	switch alg {
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("webauthn: key type does not match algorithm")
+		}
+		if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+			return errors.New("webauthn: invalid signature")
+		}
+		return nil
+
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("webauthn: key type does not match algorithm")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("webauthn: invalid signature")
+		}
+		return nil
+	}
+
+	return ErrUnsupportedAlgorithm
+}
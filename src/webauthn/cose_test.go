@@ -0,0 +1,173 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+// coseEC2Key menyusun COSE key ES256 dari public key ecdsa
+func coseEC2Key(pub *ecdsa.PublicKey) []cborPair {
+	return []cborPair{
+		{coseKeyType, coseKeyTypeEC2},
+		{coseAlgorithm, AlgES256},
+		{coseEC2Curve, coseCurveP256},
+		{coseEC2X, pub.X.FillBytes(make([]byte, 32))},
+		{coseEC2Y, pub.Y.FillBytes(make([]byte, 32))},
+	}
+}
+
+// coseRSAKey menyusun COSE key RS256 dari public key rsa
+func coseRSAKey(pub *rsa.PublicKey) []cborPair {
+	return []cborPair{
+		{coseKeyType, coseKeyTypeRSA},
+		{coseAlgorithm, AlgRS256},
+		{coseRSAN, pub.N.Bytes()},
+		{coseRSAE, big.NewInt(int64(pub.E)).Bytes()},
+	}
+}
+
+// withCOSEValue mengganti satu label COSE key, nil berarti label dihapus
+func withCOSEValue(key []cborPair, label int64, value any) []cborPair {
+	out := make([]cborPair, 0, len(key))
+	for _, pair := range key {
+		if pair.key == label {
+			if value == nil {
+				continue
+			}
+			pair.value = value
+		}
+		out = append(out, pair)
+	}
+	return out
+}
+
+func TestParsePublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ec2 := coseEC2Key(&ecKey.PublicKey)
+	offCurveY := new(big.Int).Add(ecKey.PublicKey.Y, big.NewInt(1)).FillBytes(make([]byte, 32))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantAlg int64
+		wantErr bool
+	}{
+		{"ES256", encodeCBOR(ec2), AlgES256, false},
+		{"RS256", encodeCBOR(coseRSAKey(&rsaKey.PublicKey)), AlgRS256, false},
+		{"EC2 wrong curve", encodeCBOR(withCOSEValue(ec2, coseEC2Curve, int64(2))), 0, true},
+		{"EC2 short x", encodeCBOR(withCOSEValue(ec2, coseEC2X, make([]byte, 31))), 0, true},
+		{"EC2 missing y", encodeCBOR(withCOSEValue(ec2, coseEC2Y, nil)), 0, true},
+		{"EC2 point off curve", encodeCBOR(withCOSEValue(ec2, coseEC2Y, offCurveY)), 0, true},
+		{"EC2 P-384 coordinates", encodeCBOR(withCOSEValue(ec2, coseEC2X, p384Key.PublicKey.X.Bytes())), 0, true},
+		{"RSA below 2048 bits", encodeCBOR(coseRSAKey(&weakRSAKey.PublicKey)), 0, true},
+		{"RSA even exponent", encodeCBOR(withCOSEValue(coseRSAKey(&rsaKey.PublicKey), coseRSAE, []byte{0x01, 0x00, 0x00})), 0, true},
+		{"RSA exponent too long", encodeCBOR(withCOSEValue(coseRSAKey(&rsaKey.PublicKey), coseRSAE, make([]byte, 5))), 0, true},
+		{"unsupported algorithm", encodeCBOR(withCOSEValue(ec2, coseAlgorithm, int64(-35))), 0, true},
+		{"key type mismatch", encodeCBOR(withCOSEValue(ec2, coseKeyType, coseKeyTypeRSA)), 0, true},
+		{"trailing data", append(encodeCBOR(ec2), 0x00), 0, true},
+		{"not a map", encodeCBOR([]any{int64(1)}), 0, true},
+		{"invalid cbor", []byte{0xa5, 0x01}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParsePublicKey(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParsePublicKey expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePublicKey: %v", err)
+			}
+			if key.Algorithm != tt.wantAlg {
+				t.Errorf("Algorithm = %d, want %d", key.Algorithm, tt.wantAlg)
+			}
+		})
+	}
+}
+
+func TestPublicKeyVerify(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("authenticator data || client data hash")
+	digest := sha256.Sum256(data)
+	ecSignature, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecPublic, err := ParsePublicKey(encodeCBOR(coseEC2Key(&ecKey.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublic, err := ParsePublicKey(encodeCBOR(coseRSAKey(&rsaKey.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		key       *PublicKey
+		data      []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{"ES256 valid", ecPublic, data, ecSignature, false},
+		{"ES256 tampered data", ecPublic, []byte("other data"), ecSignature, true},
+		{"ES256 RSA signature", ecPublic, data, rsaSignature, true},
+		{"RS256 valid", rsaPublic, data, rsaSignature, false},
+		{"RS256 tampered data", rsaPublic, []byte("other data"), rsaSignature, true},
+		{"RS256 ECDSA signature", rsaPublic, data, ecSignature, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.key.Verify(tt.data, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := verifySignature(AlgES256, &rsaKey.PublicKey, data, ecSignature); err == nil {
+		t.Errorf("verifySignature with mismatched key type expected error")
+	}
+	if err := verifySignature(-8, &ecKey.PublicKey, data, ecSignature); err != ErrUnsupportedAlgorithm {
+		t.Errorf("verifySignature(EdDSA) = %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
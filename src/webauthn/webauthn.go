@@ -0,0 +1,169 @@
+package webauthn
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Verifikasi ceremony WebAuthn (registrasi dan assertion) tanpa dependency eksternal.
+// Penyimpanan challenge dan credential menjadi tanggung jawab pemanggil (handlers).
+
+type Config struct {
+	RPID                    string   // domain, misal "example.com"
+	RPName                  string   // nama yang ditampilkan browser
+	Origins                 []string // origin yang diizinkan, misal "https://app.example.com"
+	RequireUserVerification bool
+}
+
+var config Config
+
+var ErrSignCount = errors.New("webauthn: signature counter did not increase, authenticator may be cloned")
+
+// Init mengatur relying party. Dipanggil sekali dari main.
+func Init(cfg Config) error {
+	if cfg.RPID == "" {
+		return errors.New("webauthn: RP ID must not be empty")
+	}
+	if len(cfg.Origins) == 0 {
+		return errors.New("webauthn: at least one origin is required")
+	}
+	if cfg.RPName == "" {
+		cfg.RPName = cfg.RPID
+	}
+	config = cfg
+	return nil
+}
+
+func GetConfig() Config {
+	return config
+}
+
+// NewChallenge membuat challenge acak 32 byte (base64url, format yang sama dengan clientDataJSON)
+func NewChallenge() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// DecodeBase64URL menerima base64url dengan atau tanpa padding
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Credential adalah hasil registrasi yang perlu disimpan
+type Credential struct {
+	ID                []byte
+	PublicKey         []byte // COSE key mentah
+	Algorithm         int64
+	SignCount         uint32
+	AAGUID            []byte
+	AttestationFormat string
+	UserVerified      bool
+}
+
+// VerifyRegistration memverifikasi hasil navigator.credentials.create()
+func VerifyRegistration(clientDataJSON, attestationObjectRaw []byte, challenge string) (*Credential, error) {
+	if err := verifyClientData(clientDataJSON, ClientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	obj, err := parseAttestationObject(attestationObjectRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyAuthenticatorFlags(obj.AuthData); err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if err := obj.verifyStatement(clientDataHash[:]); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:                append([]byte(nil), obj.AuthData.CredentialID...),
+		PublicKey:         append([]byte(nil), obj.AuthData.CredentialPublicKey...),
+		Algorithm:         obj.CredentialPK.Algorithm,
+		SignCount:         obj.AuthData.SignCount,
+		AAGUID:            append([]byte(nil), obj.AuthData.AAGUID...),
+		AttestationFormat: obj.Format,
+		UserVerified:      obj.AuthData.UserVerified(),
+	}, nil
+}
+
+// VerifyAssertion memverifikasi hasil navigator.credentials.get() terhadap credential tersimpan.
+// Mengembalikan sign count baru untuk disimpan.
+func VerifyAssertion(publicKey []byte, storedSignCount uint32, clientDataJSON, authenticatorData, signature []byte, challenge string) (uint32, error) {
+	if err := verifyClientData(clientDataJSON, ClientDataTypeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := ParseAuthenticatorData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyAuthenticatorFlags(authData); err != nil {
+		return 0, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signedData := append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+	if err := key.Verify(signedData, signature); err != nil {
+		return 0, err
+	}
+
+	// authenticator tanpa counter (passkey yang disinkronkan) selalu mengirim 0
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return 0, ErrSignCount
+	}
+
+	return authData.SignCount, nil
+}
+
+func verifyClientData(clientDataJSON []byte, expectedType, challenge string) error {
+	clientData, err := ParseClientData(clientDataJSON)
+	if err != nil {
+		return err
+	}
+	if clientData.Type != expectedType {
+		return errors.New("webauthn: unexpected client data type")
+	}
+	if subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(challenge)) != 1 {
+		return errors.New("webauthn: challenge mismatch")
+	}
+	if clientData.CrossOrigin {
+		return errors.New("webauthn: cross-origin ceremonies are not allowed")
+	}
+	for _, origin := range config.Origins {
+		if clientData.Origin == origin {
+			return nil
+		}
+	}
+	return errors.New("webauthn: origin not allowed")
+}
+
+func verifyAuthenticatorFlags(authData *AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(config.RPID))
+	if subtle.ConstantTimeCompare(authData.RPIDHash, rpIDHash[:]) != 1 {
+		return errors.New("webauthn: RP ID hash mismatch")
+	}
+	if !authData.UserPresent() {
+		return errors.New("webauthn: user presence flag not set")
+	}
+	if config.RequireUserVerification && !authData.UserVerified() {
+		return errors.New("webauthn: user verification required")
+	}
+	return nil
+}
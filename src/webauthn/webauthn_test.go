@@ -0,0 +1,287 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://app.example.com"
+)
+
+// testAuthenticator adalah authenticator software dengan satu credential ES256
+type testAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAuthenticator{t: t, key: key, credentialID: []byte("credential-0001")}
+}
+
+func setTestConfig(t *testing.T, requireUV bool) {
+	t.Helper()
+	previous := GetConfig()
+	if err := Init(Config{RPID: testRPID, Origins: []string{testOrigin}, RequireUserVerification: requireUV}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config = previous })
+}
+
+func clientDataJSON(typ, challenge, origin string, crossOrigin bool) []byte {
+	data, _ := json.Marshal(CollectedClientData{Type: typ, Challenge: challenge, Origin: origin, CrossOrigin: crossOrigin})
+	return data
+}
+
+// authData menyusun authenticatorData, attested credential data ditambahkan bila withCredential
+func (a *testAuthenticator) authData(rpID string, flags byte, signCount uint32, withCredential bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	if withCredential {
+		flags |= FlagAttestedCredentialData
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+
+	if withCredential {
+		data = append(data, make([]byte, aaguidLength)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, encodeCBOR(coseEC2Key(&a.key.PublicKey))...)
+	}
+	return data
+}
+
+func (a *testAuthenticator) sign(authData, clientData []byte) []byte {
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return signature
+}
+
+func attestationObjectNone(authData []byte) []byte {
+	return encodeCBOR([]cborPair{
+		{"fmt", AttestationNone},
+		{"attStmt", []cborPair{}},
+		{"authData", authData},
+	})
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	setTestConfig(t, false)
+	authenticator := newTestAuthenticator(t)
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createData := clientDataJSON(ClientDataTypeCreate, challenge, testOrigin, false)
+	authData := authenticator.authData(testRPID, FlagUserPresent|FlagUserVerified, 0, true)
+
+	selfAttestation := func(clientData []byte) []byte {
+		return encodeCBOR([]cborPair{
+			{"fmt", AttestationPacked},
+			{"attStmt", []cborPair{{"alg", AlgES256}, {"sig", authenticator.sign(authData, clientData)}}},
+			{"authData", authData},
+		})
+	}
+
+	tests := []struct {
+		name       string
+		clientData []byte
+		attestObj  []byte
+		challenge  string
+		wantFormat string
+		wantErr    bool
+	}{
+		{"none attestation", createData, attestationObjectNone(authData), challenge, AttestationNone, false},
+		{"packed self attestation", createData, selfAttestation(createData), challenge, AttestationPacked, false},
+		{"packed signature over other client data", createData, selfAttestation(clientDataJSON(ClientDataTypeCreate, "other", testOrigin, false)), challenge, "", true},
+		{"challenge mismatch", createData, attestationObjectNone(authData), "other-challenge", "", true},
+		{"wrong ceremony type", clientDataJSON(ClientDataTypeGet, challenge, testOrigin, false), attestationObjectNone(authData), challenge, "", true},
+		{"origin not allowed", clientDataJSON(ClientDataTypeCreate, challenge, "https://evil.example.net", false), attestationObjectNone(authData), challenge, "", true},
+		{"cross origin", clientDataJSON(ClientDataTypeCreate, challenge, testOrigin, true), attestationObjectNone(authData), challenge, "", true},
+		{"RP ID mismatch", createData, attestationObjectNone(authenticator.authData("evil.example.net", FlagUserPresent, 0, true)), challenge, "", true},
+		{"user not present", createData, attestationObjectNone(authenticator.authData(testRPID, 0, 0, true)), challenge, "", true},
+		{"no credential data", createData, attestationObjectNone(authenticator.authData(testRPID, FlagUserPresent, 0, false)), challenge, "", true},
+		{"none with statement", createData, encodeCBOR([]cborPair{{"fmt", AttestationNone}, {"attStmt", []cborPair{{"alg", AlgES256}}}, {"authData", authData}}), challenge, "", true},
+		{"unsupported format", createData, encodeCBOR([]cborPair{{"fmt", "tpm"}, {"attStmt", []cborPair{}}, {"authData", authData}}), challenge, "", true},
+		{"missing authData", createData, encodeCBOR([]cborPair{{"fmt", AttestationNone}, {"attStmt", []cborPair{}}}), challenge, "", true},
+		{"trailing data", createData, append(attestationObjectNone(authData), 0x00), challenge, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credential, err := VerifyRegistration(tt.clientData, tt.attestObj, tt.challenge)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("VerifyRegistration expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+			if string(credential.ID) != string(authenticator.credentialID) || credential.Algorithm != AlgES256 {
+				t.Errorf("credential = %+v", credential)
+			}
+			if credential.AttestationFormat != tt.wantFormat || !credential.UserVerified {
+				t.Errorf("format = %q, user verified = %v", credential.AttestationFormat, credential.UserVerified)
+			}
+			if _, err := ParsePublicKey(credential.PublicKey); err != nil {
+				t.Errorf("stored public key does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationRequiresUserVerification(t *testing.T) {
+	setTestConfig(t, true)
+	authenticator := newTestAuthenticator(t)
+
+	clientData := clientDataJSON(ClientDataTypeCreate, "challenge", testOrigin, false)
+	attestObj := attestationObjectNone(authenticator.authData(testRPID, FlagUserPresent, 0, true))
+	if _, err := VerifyRegistration(clientData, attestObj, "challenge"); err == nil {
+		t.Errorf("VerifyRegistration without UV flag expected error")
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	setTestConfig(t, false)
+	authenticator := newTestAuthenticator(t)
+	publicKey := encodeCBOR(coseEC2Key(&authenticator.key.PublicKey))
+	challenge := "assertion-challenge"
+	getData := clientDataJSON(ClientDataTypeGet, challenge, testOrigin, false)
+
+	tests := []struct {
+		name            string
+		storedSignCount uint32
+		signCount       uint32
+		flags           byte
+		clientData      []byte
+		tamper          bool
+		wantSignCount   uint32
+		wantErr         error
+	}{
+		{"counter increases", 5, 6, FlagUserPresent, getData, false, 6, nil},
+		{"first use", 0, 1, FlagUserPresent, getData, false, 1, nil},
+		{"counterless authenticator", 0, 0, FlagUserPresent, getData, false, 0, nil},
+		{"counter replayed", 6, 6, FlagUserPresent, getData, false, 0, ErrSignCount},
+		{"counter went back", 6, 2, FlagUserPresent, getData, false, 0, ErrSignCount},
+		{"counter reset to zero", 6, 0, FlagUserPresent, getData, false, 0, ErrSignCount},
+		{"user not present", 0, 1, 0, getData, false, 0, errors.New("any")},
+		{"wrong ceremony type", 0, 1, FlagUserPresent, clientDataJSON(ClientDataTypeCreate, challenge, testOrigin, false), false, 0, errors.New("any")},
+		{"tampered signature", 0, 1, FlagUserPresent, getData, true, 0, errors.New("any")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authData := authenticator.authData(testRPID, tt.flags, tt.signCount, false)
+			signature := authenticator.sign(authData, tt.clientData)
+			if tt.tamper {
+				authData[len(authData)-1]++
+			}
+
+			signCount, err := VerifyAssertion(publicKey, tt.storedSignCount, tt.clientData, authData, signature, challenge)
+			if tt.wantErr != nil {
+				if err == nil || (tt.wantErr == ErrSignCount && err != ErrSignCount) {
+					t.Errorf("VerifyAssertion error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyAssertion: %v", err)
+			}
+			if signCount != tt.wantSignCount {
+				t.Errorf("sign count = %d, want %d", signCount, tt.wantSignCount)
+			}
+		})
+	}
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	withCredential := authenticator.authData(testRPID, FlagUserPresent, 7, true)
+	extensions := append(authenticator.authData(testRPID, FlagUserPresent|FlagExtensionData, 7, false), encodeCBOR([]cborPair{{"credProtect", int64(2)}})...)
+
+	badIDLength := authenticator.authData(testRPID, FlagUserPresent, 0, true)
+	binary.BigEndian.PutUint16(badIDLength[authDataMinSize+aaguidLength:], maxCredentialID+1)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"assertion", authenticator.authData(testRPID, FlagUserPresent, 7, false), false},
+		{"with credential", withCredential, false},
+		{"with extensions", extensions, false},
+		{"too short", make([]byte, authDataMinSize-1), true},
+		{"trailing data", append(authenticator.authData(testRPID, FlagUserPresent, 7, false), 0x00), true},
+		{"credential flag without data", authenticator.authData(testRPID, FlagUserPresent|FlagAttestedCredentialData, 7, false), true},
+		{"credential ID too long", badIDLength, true},
+		{"truncated COSE key", withCredential[:len(withCredential)-3], true},
+		{"extension flag without data", authenticator.authData(testRPID, FlagUserPresent|FlagExtensionData, 7, false), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authData, err := ParseAuthenticatorData(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseAuthenticatorData expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAuthenticatorData: %v", err)
+			}
+			if authData.SignCount != 7 || !authData.UserPresent() || authData.UserVerified() {
+				t.Errorf("authData = %+v", authData)
+			}
+		})
+	}
+
+	parsed, _ := ParseAuthenticatorData(withCredential)
+	if string(parsed.CredentialID) != string(authenticator.credentialID) {
+		t.Errorf("CredentialID = %q", parsed.CredentialID)
+	}
+	if _, err := ParsePublicKey(parsed.CredentialPublicKey); err != nil {
+		t.Errorf("CredentialPublicKey does not parse: %v", err)
+	}
+}
+
+func TestDecodeBase64URL(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"aGVsbG8", "hello", false},
+		{"aGVsbG8=", "hello", false},
+		{"-_8", "\xfb\xff", false},
+		{"+/8", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := DecodeBase64URL(tt.input)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && string(got) != tt.want) {
+			t.Errorf("DecodeBase64URL(%q) = %q (%v), want %q", tt.input, got, err, tt.want)
+		}
+	}
+}